@@ -3,12 +3,15 @@ package main
 import (
 	"context"
 	"encoding/csv"
+	"flag"
 	"fmt"
 	"log"
+	neturl "net/url"
 	"os"
 	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"syscall"
 	"time"
@@ -29,8 +32,14 @@ type CSVLogger struct {
 	path   string
 }
 
+// osascript を起動し続けるので、これより短い間隔は受け付けない
+const minInterval = 250 * time.Millisecond
+
 func main() {
-	interval := 5 * time.Second
+	interval, err := loadInterval(5 * time.Second)
+	if err != nil {
+		log.Fatalf("interval: %v", err)
+	}
 
 	// CSVロガー準備（起動のたびに新規ファイル）
 	csvlog, err := newCSVLogger()
@@ -79,6 +88,8 @@ loop:
 					title, url = t, u
 				}
 			}
+			// URLは分類・CSVに使う前に整形する（クエリ・フラグメント・ユーザー情報を落とす）
+			url = sanitizeURL(url)
 
 			category := classify(appName, bundleID, title, url)
 			now := time.Now()
@@ -122,6 +133,24 @@ loop:
 	log.Println("Bye.")
 }
 
+// --interval（Go の duration。例: 2s, 500ms）> SHIRUSIA_INTERVAL > def の順で決める
+func loadInterval(def time.Duration) (time.Duration, error) {
+	if raw := strings.TrimSpace(os.Getenv("SHIRUSIA_INTERVAL")); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			log.Printf("warn: SHIRUSIA_INTERVAL: %v", err)
+		} else {
+			def = d
+		}
+	}
+	d := flag.Duration("interval", def, "poll interval (Go duration, at least 250ms); overrides SHIRUSIA_INTERVAL")
+	flag.Parse()
+	if *d < minInterval {
+		return 0, fmt.Errorf("%s is too short (minimum %s)", *d, minInterval)
+	}
+	return *d, nil
+}
+
 // ===== CSV Logger =====
 
 func newCSVLogger() (*CSVLogger, error) {
@@ -222,6 +251,14 @@ func classify(appName, bundleID, title, url string) string {
 		return "開発プラットフォーム"
 	}
 
+	// ターミナル（git のブランチやビルドのコマンドがタイトルに出ていればプログラミング）
+	if isTerminal(appName, bundleID) {
+		if hasTerminalDevContext(strings.ToLower(title)) {
+			return "プログラムの制作"
+		}
+		return "ターミナル作業"
+	}
+
 	// ドキュメント/資料
 	if strings.Contains(n, "docs.google.com") || strings.Contains(n, "notion.so") || strings.Contains(n, "dropbox.com") {
 		return "ドキュメント作業"
@@ -243,6 +280,98 @@ func classify(appName, bundleID, title, url string) string {
 	return "その他"
 }
 
+// ===== ターミナル =====
+
+var (
+	terminalBundleIDs = []string{
+		"com.apple.terminal", "com.googlecode.iterm2", "dev.warp.warp", "org.alacritty", "io.alacritty",
+		"net.kovidgoyal.kitty", "com.github.wez.wezterm", "com.mitchellh.ghostty", "co.zeit.hyper",
+	}
+	terminalAppNames = []string{"terminal", "iterm", "warp", "alacritty", "kitty", "wezterm", "ghostty", "hyper"}
+
+	// プロンプトのブランチ表示（"~/src/app (main)" "git:(main)"）と "go test" など
+	terminalDevTitleRe = regexp.MustCompile(`\bgit:\([\w./-]+\)|[~/][\w./-]*\s+[(\[][\w./-]+[)\]]|\bgo\s+(?:build|test|run|vet|generate)\b`)
+)
+
+// bundle ID（前方一致）かアプリ名（部分一致）がターミナルか
+func isTerminal(appName, bundleID string) bool {
+	b := strings.ToLower(bundleID)
+	for _, id := range terminalBundleIDs {
+		if b != "" && strings.HasPrefix(b, id) {
+			return true
+		}
+	}
+	a := strings.ToLower(appName)
+	for _, name := range terminalAppNames {
+		if strings.Contains(a, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// ターミナルのタイトル（小文字）にブランチの表示やビルド・バージョン管理のコマンドが出ているか
+func hasTerminalDevContext(t string) bool {
+	if terminalDevTitleRe.MatchString(t) {
+		return true
+	}
+	for _, w := range strings.Fields(t) {
+		switch w {
+		case "git", "lazygit", "tig", "make", "gmake", "cmake", "ninja", "bazel",
+			"cargo", "npm", "npx", "yarn", "pnpm", "gradle", "./gradlew", "mvn":
+			return true
+		}
+	}
+	return false
+}
+
+// ===== URLの整形 =====
+
+// CSVに残すクエリのキー（これ以外のクエリとフラグメントは捨てる）
+var urlKeepParams = []string{"v", "list", "page"}
+
+// true なら scheme://host だけを残す
+var urlHostOnly = false
+
+// 保存してよい形にしたURL。javascript: / data: / blob: や解析できないものは空にする
+func sanitizeURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+	u, err := neturl.Parse(raw)
+	if err != nil || u.Scheme == "" {
+		return ""
+	}
+	scheme := strings.ToLower(u.Scheme)
+	switch scheme {
+	case "javascript", "data", "blob", "vbscript":
+		return ""
+	}
+	if u.Opaque != "" { // about:blank, mailto:... など
+		if scheme == "about" {
+			return "about:" + u.Opaque
+		}
+		return scheme + ":"
+	}
+	if urlHostOnly {
+		if u.Host == "" {
+			return scheme + "://"
+		}
+		return scheme + "://" + u.Host
+	}
+	kept := neturl.Values{}
+	for k, v := range u.Query() {
+		for _, p := range urlKeepParams {
+			if strings.EqualFold(k, p) {
+				kept[k] = v
+			}
+		}
+	}
+	out := neturl.URL{Scheme: scheme, Host: u.Host, Path: u.Path, RawPath: u.RawPath, RawQuery: kept.Encode()}
+	return out.String()
+}
+
 func truncate(s string, max int) string {
 	if len(s) <= max {
 		return s
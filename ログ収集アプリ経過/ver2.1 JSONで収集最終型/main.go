@@ -5,7 +5,9 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
+	"net/url"
 	"os"
 	"os/exec"
 	"os/signal"
@@ -18,14 +20,26 @@ import (
 
 /********** 設定 **********/
 const (
-	pollInterval = 1500 * time.Millisecond
-	logDir       = "/Users/kmg2022-40/Desktop/activitylog/log"
+	logDir = "/Users/kmg2022-40/Desktop/activitylog/log"
+	// osascript を起動し続けるので、これより短い間隔は受け付けない
+	minPollInterval = 250 * time.Millisecond
+)
+
+// 前面ウィンドウを取る間隔（--interval > SHIRUSIA_INTERVAL > 1.5秒。loadPollInterval）
+var pollInterval = 1500 * time.Millisecond
+
+var (
+	// 保存するURLに残すクエリのキー（これ以外のクエリとフラグメントは捨てる。sanitizeURL）
+	urlKeepParams = []string{"v", "list", "page"}
+	// true なら URL は scheme://host だけを残す
+	urlHostOnly = false
 )
 
 /********** データ型 **********/
 type record struct {
 	App       string
 	Title     string
+	URL       string // ブラウザで表示中のページ（Safari / Chromium系のみ）
 	Activity  string
 	Timestamp time.Time
 }
@@ -35,6 +49,7 @@ type session struct {
 	End         string `json:"end"`
 	App         string `json:"app"`
 	Title       string `json:"title"`
+	URL         string `json:"url,omitempty"`
 	Activity    string `json:"activity"`
 	DurationSec int64  `json:"durationSec"`
 }
@@ -102,7 +117,12 @@ func (j *jsonArrayWriter) Close() error {
 
 /********** メイン **********/
 func main() {
+	if err := loadPollInterval(); err != nil {
+		fmt.Fprintf(os.Stderr, "interval: %v\n", err)
+		os.Exit(2)
+	}
 	fmt.Println("Activity logger (JSON sessions, browser tab titles) started. Ctrl+C to stop.")
+	fmt.Printf("Polling every %s\n", pollInterval)
 
 	jw, err := newJSONArrayWriter()
 	if err != nil {
@@ -125,14 +145,14 @@ loop:
 	for {
 		select {
 		case <-ticker.C:
-			app, title, err := frontmostAppAndTitleWithBrowserTabs()
+			app, title, pageURL, err := frontmostAppAndTitleWithBrowserTabs()
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "warn: %v\n", err)
 				continue
 			}
-			activity := classifyActivity(app, title)
+			activity := classifyActivity(app, title, pageURL)
 			now := time.Now()
-			cur := &record{App: app, Title: title, Activity: activity, Timestamp: now}
+			cur := &record{App: app, Title: title, URL: pageURL, Activity: activity, Timestamp: now}
 
 			if last == nil {
 				last = cur
@@ -179,6 +199,26 @@ loop:
 	fmt.Println("Stopped.")
 }
 
+// --interval（Go の duration。例: 1s, 750ms）。未指定なら SHIRUSIA_INTERVAL、それもなければ既定のまま
+func loadPollInterval() error {
+	def := pollInterval
+	if raw := strings.TrimSpace(os.Getenv("SHIRUSIA_INTERVAL")); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warn: SHIRUSIA_INTERVAL: %v\n", err)
+		} else {
+			def = d
+		}
+	}
+	d := flag.Duration("interval", def, "poll interval (Go duration, at least 250ms); overrides SHIRUSIA_INTERVAL")
+	flag.Parse()
+	if *d < minPollInterval {
+		return fmt.Errorf("%s is too short (minimum %s)", *d, minPollInterval)
+	}
+	pollInterval = *d
+	return nil
+}
+
 /********** セッション化ユーティリティ **********/
 func sessionFrom(r *record, start, end time.Time) session {
 	dur := end.Sub(start).Round(time.Second)
@@ -190,13 +230,16 @@ func sessionFrom(r *record, start, end time.Time) session {
 		End:         end.Format(time.RFC3339),
 		App:         clean(r.App),
 		Title:       clean(r.Title),
+		URL:         r.URL,
 		Activity:    clean(r.Activity),
 		DurationSec: int64(dur / time.Second),
 	}
 }
 
-/********** ブラウザのアクティブタブタイトル対応 **********/
-func frontmostAppAndTitleWithBrowserTabs() (string, string, error) {
+/********** ブラウザのアクティブタブタイトル・URL対応 **********/
+// Safari / Chromium系はタブのタイトルとURLを1回の osascript で取る（ver1 の safariFrontTab と同じ形）。
+// それ以外のアプリはURLなし（空）
+func frontmostAppAndTitleWithBrowserTabs() (string, string, string, error) {
 	// まず前面アプリ名
 	appScript := `
 		tell application "System Events"
@@ -206,19 +249,24 @@ func frontmostAppAndTitleWithBrowserTabs() (string, string, error) {
 	`
 	app, err := runOSA(appScript)
 	if err != nil {
-		return "", "", fmt.Errorf("get frontmost app failed: %w", err)
+		return "", "", "", fmt.Errorf("get frontmost app failed: %w", err)
 	}
 	app = strings.TrimSpace(app)
 
 	low := strings.ToLower(app)
 
-	// Safari：現在タブのタイトル
+	// Safari：現在タブのタイトルとURL
 	if low == "safari" {
-		title, e := runOSA(`
+		out, e := runOSA(`
 			tell application "Safari"
 				try
 					if (count of windows) > 0 then
-						return name of current tab of front window
+						set t to current tab of front window
+						set u to ""
+						try
+							set u to URL of t
+						end try
+						return (name of t) & linefeed & u
 					else
 						return ""
 					end if
@@ -228,7 +276,8 @@ func frontmostAppAndTitleWithBrowserTabs() (string, string, error) {
 			end tell
 		`)
 		if e == nil {
-			return app, strings.TrimSpace(title), nil
+			title, u := splitTitleURL(out)
+			return app, title, u, nil
 		}
 	}
 
@@ -238,7 +287,8 @@ func frontmostAppAndTitleWithBrowserTabs() (string, string, error) {
 			tell application "%s"
 				try
 					if (count of windows) > 0 then
-						return title of active tab of front window
+						set t to active tab of front window
+						return (title of t) & linefeed & (URL of t)
 					else
 						return ""
 					end if
@@ -247,9 +297,10 @@ func frontmostAppAndTitleWithBrowserTabs() (string, string, error) {
 				end try
 			end tell
 		`, escapeOSA(app))
-		title, e := runOSA(script)
+		out, e := runOSA(script)
 		if e == nil {
-			return app, strings.TrimSpace(title), nil
+			title, u := splitTitleURL(out)
+			return app, title, u, nil
 		}
 	}
 
@@ -270,7 +321,53 @@ func frontmostAppAndTitleWithBrowserTabs() (string, string, error) {
 		end tell
 	`, escapeOSA(app))
 	title, _ := runOSA(titleScript)
-	return app, strings.TrimSpace(title), nil
+	return app, strings.TrimSpace(title), "", nil
+}
+
+// "タイトル\nURL" を分ける。URLは分類・保存の前に整形する
+func splitTitleURL(out string) (string, string) {
+	title, u, _ := strings.Cut(strings.TrimRight(out, "\n"), "\n")
+	return strings.TrimSpace(title), sanitizeURL(u)
+}
+
+// 保存してよい形にしたURL。クエリ・フラグメント・ユーザー情報を落とし、
+// javascript: / data: / blob: や解析できないものは空にする
+func sanitizeURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" {
+		return ""
+	}
+	scheme := strings.ToLower(u.Scheme)
+	switch scheme {
+	case "javascript", "data", "blob", "vbscript":
+		return ""
+	}
+	if u.Opaque != "" { // about:blank, mailto:... など
+		if scheme == "about" {
+			return "about:" + u.Opaque
+		}
+		return scheme + ":"
+	}
+	if urlHostOnly {
+		if u.Host == "" {
+			return scheme + "://"
+		}
+		return scheme + "://" + u.Host
+	}
+	kept := url.Values{}
+	for k, v := range u.Query() {
+		for _, p := range urlKeepParams {
+			if strings.EqualFold(k, p) {
+				kept[k] = v
+			}
+		}
+	}
+	out := url.URL{Scheme: scheme, Host: u.Host, Path: u.Path, RawPath: u.RawPath, RawQuery: kept.Encode()}
+	return out.String()
 }
 
 func isChromiumBrowser(appLower string) bool {
@@ -295,7 +392,8 @@ func runOSA(script string) (string, error) {
 }
 
 /********** ラベリング・ヘルパ **********/
-func classifyActivity(app, title string) string {
+// pageURL はブラウザのURL（取れなければ空）。調査・ドキュメント閲覧はホストでも判定する
+func classifyActivity(app, title, pageURL string) string {
 	a := strings.ToLower(app)
 	t := strings.ToLower(title)
 
@@ -316,16 +414,30 @@ func classifyActivity(app, title string) string {
 		}
 	}
 
+	// 会議・通話（会議中のウィンドウだけ。チャットは下のコミュニケーション）
+	if isMeetingWindow(a, t, pageURL) {
+		return "会議・通話"
+	}
+
+	// ターミナル（git のブランチやビルドのコマンドがタイトルに出ていればプログラミング）
+	if hasAny(a, terminalApps) {
+		if hasTerminalDevContext(t) {
+			return "プログラムの制作"
+		}
+		return "ターミナル作業"
+	}
+
 	// コミュニケーション
 	if strings.Contains(a, "slack") || strings.Contains(a, "teams") ||
-		strings.Contains(a, "discord") || strings.Contains(a, "zoom") || strings.Contains(a, "meet") {
+		strings.Contains(a, "discord") || strings.Contains(a, "zoom") || strings.Contains(a, "meet") ||
+		strings.Contains(a, "webex") {
 		return "コミュニケーション"
 	}
 
 	// ブラウザ
 	if strings.Contains(a, "safari") || strings.Contains(a, "chrome") ||
 		strings.Contains(a, "arc") || strings.Contains(a, "firefox") || strings.Contains(a, "edge") || strings.Contains(a, "brave") || strings.Contains(a, "opera") || strings.Contains(a, "vivaldi") {
-		if hasAny(t, []string{"arxiv", "qiita", "stackoverflow", "docs", "doc:", "documentation", "mdn"}) {
+		if isResearchHost(pageURL) || hasAny(t, []string{"arxiv", "qiita", "stackoverflow", "docs", "doc:", "documentation", "mdn"}) {
 			return "調査・ドキュメント閲覧"
 		}
 		return "Webブラウジング"
@@ -355,6 +467,81 @@ func classifyActivity(app, title string) string {
 	return "その他"
 }
 
+var terminalApps = []string{"terminal", "iterm", "warp", "alacritty", "kitty", "wezterm", "ghostty", "hyper"}
+
+// プロンプトのブランチ表示（"~/src/app (main)" "git:(main)"）と "go test" など
+var terminalDevTitleRe = regexp.MustCompile(`\bgit:\([\w./-]+\)|[~/][\w./-]*\s+[(\[][\w./-]+[)\]]|\bgo\s+(?:build|test|run|vet|generate)\b`)
+
+// ターミナルのタイトル（小文字）にブランチの表示やビルド・バージョン管理のコマンドが出ているか
+func hasTerminalDevContext(t string) bool {
+	if terminalDevTitleRe.MatchString(t) {
+		return true
+	}
+	for _, w := range strings.Fields(t) {
+		switch w {
+		case "git", "lazygit", "tig", "make", "gmake", "cmake", "ninja", "bazel",
+			"cargo", "npm", "npx", "yarn", "pnpm", "gradle", "./gradlew", "mvn":
+			return true
+		}
+	}
+	return false
+}
+
+// 会議中のウィンドウか（a, t は小文字）。Zoom は "Zoom Meeting" などの会議ウィンドウ、
+// Google Meet はブラウザのタブ "Meet – abc-defg-hij" "Meet — 定例" か meet.google.com/abc-defg-hij、
+// Teams は "Meeting with …" "Call with …" "会議" "通話"、Webex は "Meeting" "Personal Room" を含むウィンドウ
+// （本体の "Webex" などは除く）
+var (
+	meetTitleRe = regexp.MustCompile(`^meet\s+[-–—:]\s+\S`)
+	meetCodeRe  = regexp.MustCompile(`^/[a-z]{3}-[a-z]{4}-[a-z]{3}(?:/|$)`)
+)
+
+func isMeetingWindow(a, t, pageURL string) bool {
+	switch {
+	case strings.Contains(a, "zoom"):
+		return hasAny(t, []string{"zoom meeting", "zoom ミーティング", "zoom webinar", "zoom ウェビナー"})
+	case strings.Contains(a, "teams"):
+		return hasAny(t, []string{"meeting", "call with", "会議", "通話"})
+	case strings.Contains(a, "webex"):
+		return t != "webex" && t != "cisco webex meetings" && t != "webex meetings" &&
+			hasAny(t, []string{"meeting", "personal room", "ミーティング", "パーソナル会議室"})
+	}
+	if meetTitleRe.MatchString(strings.TrimSpace(t)) {
+		return true
+	}
+	u, err := url.Parse(pageURL)
+	return pageURL != "" && err == nil && strings.EqualFold(u.Hostname(), "meet.google.com") && meetCodeRe.MatchString(u.Path)
+}
+
+var researchHosts = []string{
+	"arxiv.org", "qiita.com", "zenn.dev", "stackoverflow.com", "stackexchange.com",
+	"developer.mozilla.org", "pkg.go.dev", "go.dev", "readthedocs.io", "wikipedia.org",
+}
+
+// URLのホストが調査・ドキュメント向けのサイト（上の一覧のドメインか docs.* / developer.*）か
+func isResearchHost(pageURL string) bool {
+	if pageURL == "" {
+		return false
+	}
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "docs.google.com" {
+		return false // Google ドキュメント（編集）
+	}
+	if strings.HasPrefix(host, "docs.") || strings.HasPrefix(host, "developer.") {
+		return true
+	}
+	for _, d := range researchHosts {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
 func hasAny(s string, keys []string) bool {
 	for _, k := range keys {
 		if strings.Contains(s, k) {
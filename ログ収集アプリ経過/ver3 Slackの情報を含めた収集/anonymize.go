@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+/********** anonymize サブコマンド **********/
+// 共有用に、セッションログ（activity_*.json）またはSlackメッセージ（msg_*.json）から
+// タイトル・URL・本文を取り除いたファイルを作る。カテゴリと時間（start/end/durationSec）は残す。
+//   activitylog anonymize [-apps] [-salt S] [-drop-dms] [-o out.json] file.json
+// -apps を付けるとアプリ名も "app-xxxxxxxx" に置き換える（meta の rawApp・apps・glances の中のアプリ名も）。
+// ラベルはアプリ名（と -salt）から決まるので、同じアプリはどのファイルでも同じラベルになる。
+// -drop-dms を付けると DM（meta.conversationType が dm / mpdm）のメッセージは書き出さない。
+// saltなしだと有名アプリは名前からラベルを逆算できるため、外部に出す場合は -salt を推奨。
+func runAnonymize(args []string) int {
+	fs := flag.NewFlagSet("anonymize", flag.ExitOnError)
+	apps := fs.Bool("apps", false, "replace app names with deterministic generic labels")
+	salt := fs.String("salt", "", "secret salt for app labels (recommended when sharing)")
+	out := fs.String("o", "", "output path (default: <input>.anon.json)")
+	dropDMs := fs.Bool("drop-dms", false, "do not export Slack messages from DMs (meta.conversationType dm/mpdm)")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: anonymize [-apps] [-salt S] [-drop-dms] [-o out.json] file.json")
+		return 2
+	}
+	in := fs.Arg(0)
+	if *out == "" {
+		*out = strings.TrimSuffix(in, ".json") + ".anon.json"
+	}
+
+	raw, err := os.ReadFile(in)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "anonymize: %v\n", err)
+		return 1
+	}
+	label := func(app string) string {
+		if !*apps {
+			return app
+		}
+		return anonymousAppLabel(app, *salt)
+	}
+
+	var v any
+	if t := bytes.TrimSpace(raw); len(t) > 0 && t[0] == '{' {
+		// Slackメッセージ1件
+		var m messageEntry
+		if err := json.Unmarshal(raw, &m); err != nil {
+			fmt.Fprintf(os.Stderr, "anonymize: %v\n", err)
+			return 1
+		}
+		if t := m.Meta["conversationType"]; *dropDMs && (t == convDM || t == convMPDM) {
+			fmt.Printf("skipped %s (%s)\n", in, t)
+			return 0
+		}
+		v = anonymizeMessage(m)
+	} else {
+		ss, err := readSessionsFile(in)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "anonymize: %v\n", err)
+			return 1
+		}
+		for i := range ss {
+			ss[i] = anonymizeSession(ss[i], label)
+		}
+		v = ss
+	}
+
+	if err := writeJSONFile(*out, v); err != nil {
+		fmt.Fprintf(os.Stderr, "anonymize: %v\n", err)
+		return 1
+	}
+	fmt.Printf("wrote %s\n", *out)
+	return 0
+}
+
+// タイトルやURLを含む meta（曲名・会議名・ほかのウィンドウのタブ・タイトルトリガーの正規表現）。常に落とす
+var titleMetaKeys = []string{"tracks", "meetingTopic", "otherTabs", "trigger"}
+
+func anonymizeSession(s session, label func(string) string) session {
+	s.Title = ""
+	s.URL = ""
+	s.MatchedBy = matchedByKind(s.MatchedBy) // "app:slack" "url-host:github.com" などの値は落とす
+	s.App = label(s.App)
+	if s.Meta != nil {
+		m := make(map[string]string, len(s.Meta))
+		for k, v := range s.Meta {
+			if containsString(titleMetaKeys, k) {
+				continue
+			}
+			// アプリ名を含む meta は App と同じラベルに置き換える（-apps なしならそのまま）
+			switch k {
+			case "rawApp":
+				v = label(v)
+			case "apps":
+				v = relabelList(v, ", ", label)
+			case "glances":
+				v = relabelGlances(v, label)
+			}
+			m[k] = v
+		}
+		s.Meta = m
+	}
+	return s
+}
+
+// "Safari, Google Chrome" の各アプリ名をラベルにする
+func relabelList(v, sep string, label func(string) string) string {
+	parts := strings.Split(v, sep)
+	for i, p := range parts {
+		parts[i] = label(p)
+	}
+	return strings.Join(parts, sep)
+}
+
+// meta.glances（"Slack 2s, メール 1s"。grace.go）のアプリ名をラベルにする
+func relabelGlances(v string, label func(string) string) string {
+	parts := strings.Split(v, ", ")
+	for i, p := range parts {
+		if j := strings.LastIndex(p, " "); j > 0 {
+			parts[i] = label(p[:j]) + p[j:]
+		} else {
+			parts[i] = label(p)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func anonymizeMessage(m messageEntry) messageEntry {
+	m.Text = ""
+	m.Title = ""
+	// チャンネルID・ユーザーIDも個人に結びつくため落とす（会話の種類だけは残す）
+	t := m.Meta["conversationType"]
+	m.Meta = nil
+	if t != "" {
+		m.Meta = map[string]string{"conversationType": t}
+	}
+	return m
+}
+
+// アプリ名 → "app-xxxxxxxx"（HMAC-SHA256の先頭8桁）
+func anonymousAppLabel(app, salt string) string {
+	if app == "" {
+		return ""
+	}
+	h := hmac.New(sha256.New, []byte(salt))
+	h.Write([]byte(strings.ToLower(app)))
+	return "app-" + hex.EncodeToString(h.Sum(nil))[:8]
+}
+
+func writeJSONFile(path string, v any) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
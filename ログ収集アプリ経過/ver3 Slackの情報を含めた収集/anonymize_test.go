@@ -0,0 +1,66 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAnonymizeSessionMeta(t *testing.T) {
+	s := session{
+		App:       "Visual Studio Code",
+		Title:     "secret.go — project",
+		URL:       "https://example.com/private",
+		Activity:  "プログラムの制作",
+		MatchedBy: "app:visual studio code",
+		Meta: map[string]string{
+			"rawApp":       "Code - Insiders",
+			"apps":         "Visual Studio Code, Terminal",
+			"glances":      "Slack 2s, Mail 1s",
+			"trigger":      "(?i)incident-\\d+",
+			"tracks":       "Song A / Song B",
+			"meetingTopic": "1on1",
+			"otherTabs":    "Docs",
+			"inputMode":    "keyboard",
+			"participants": "3",
+		},
+	}
+	label := func(app string) string { return anonymousAppLabel(app, "salt") }
+	got := anonymizeSession(s, label)
+
+	if got.Title != "" || got.URL != "" || got.MatchedBy != "app" {
+		t.Errorf("title/url/matchedBy kept: %q %q %q", got.Title, got.URL, got.MatchedBy)
+	}
+	for _, k := range []string{"trigger", "tracks", "meetingTopic", "otherTabs"} {
+		if _, ok := got.Meta[k]; ok {
+			t.Errorf("meta.%s kept", k)
+		}
+	}
+	for _, name := range []string{"Visual Studio Code", "Insiders", "Terminal", "Slack", "Mail"} {
+		for k, v := range got.Meta {
+			if strings.Contains(v, name) {
+				t.Errorf("meta.%s = %q still contains %q", k, v, name)
+			}
+		}
+	}
+	if want := label("Visual Studio Code") + ", " + label("Terminal"); got.Meta["apps"] != want {
+		t.Errorf("meta.apps = %q, want %q", got.Meta["apps"], want)
+	}
+	if want := label("Slack") + " 2s, " + label("Mail") + " 1s"; got.Meta["glances"] != want {
+		t.Errorf("meta.glances = %q, want %q", got.Meta["glances"], want)
+	}
+	if got.Meta["inputMode"] != "keyboard" || got.Meta["participants"] != "3" {
+		t.Errorf("non-identifying meta dropped: %v", got.Meta)
+	}
+}
+
+// -apps なしではアプリ名は残すが、タイトル由来の meta は落とす
+func TestAnonymizeSessionKeepsAppsWithoutLabels(t *testing.T) {
+	s := session{App: "Slack", Meta: map[string]string{"apps": "Slack, Discord", "trigger": "standup"}}
+	got := anonymizeSession(s, func(app string) string { return app })
+	if got.Meta["apps"] != "Slack, Discord" {
+		t.Errorf("meta.apps = %q", got.Meta["apps"])
+	}
+	if _, ok := got.Meta["trigger"]; ok {
+		t.Error("meta.trigger kept")
+	}
+}
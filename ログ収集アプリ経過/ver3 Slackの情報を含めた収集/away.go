@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+/********** 手動の離席（POST /away・/back、SIGUSR1・SIGUSR2） **********/
+// 昼食や別室での会議など、離席すると分かっているときにアイドル検知を待たずに記録する。
+// 離席にすると進行中のセッションを閉じ、「離席」の期間を始める。戻るまでは前面アプリを取得しない
+// （画面をロックせずに離れても、その間が作業として記録されない）。
+//   curl -X POST http://127.0.0.1:8765/away   （-http 使用時）
+//   kill -USR1 <pid> で離席、kill -USR2 <pid> で復帰（Unix系）
+const (
+	awayApp      = "away"
+	awayActivity = "離席"
+)
+
+func awayRecord(at time.Time) *record {
+	return &record{App: awayApp, Activity: awayActivity, Timestamp: at}
+}
+
+// 離席/復帰の要求をメインループへ渡す（すぐに処理されなくても取りこぼさないよう少しだけバッファする）
+func (l *liveState) RequestAway(away bool) bool {
+	select {
+	case l.awayReq <- away:
+		return true
+	default:
+		return false
+	}
+}
+
+func (l *liveState) AwayRequests() <-chan bool {
+	return l.awayReq
+}
+
+type awayResponse struct {
+	Away bool `json:"away"`
+}
+
+func awayHandler(st *liveState, away bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !st.RequestAway(away) {
+			http.Error(w, "busy, try again", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusAccepted)
+		writeJSON(w, awayResponse{Away: away})
+	}
+}
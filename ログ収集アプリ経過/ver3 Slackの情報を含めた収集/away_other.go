@@ -0,0 +1,6 @@
+//go:build windows || plan9
+
+package main
+
+// SIGUSR1/SIGUSR2 がない環境では HTTP の /away・/back のみ
+func notifyAwaySignals(st *liveState) {}
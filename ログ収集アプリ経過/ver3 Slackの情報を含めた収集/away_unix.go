@@ -0,0 +1,20 @@
+//go:build !windows && !plan9
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// SIGUSR1 で離席、SIGUSR2 で復帰
+func notifyAwaySignals(st *liveState) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		for sig := range ch {
+			st.RequestAway(sig == syscall.SIGUSR1)
+		}
+	}()
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+/********** 時間バケット集計（-buckets） **********/
+// 可変長のセッションを固定幅（例: 1分）のバケットに切り分け、
+// バケットごとに最も長かったカテゴリを1レコードとして出す。ヒートマップ等の可視化向け。
+// バケットはその日の 0:00（ローカル時刻）から数える。time.Truncate は UTC 基準なので、
+// JST では 1日のバケットが 9:00 始まりになってしまう。
+type bucketRecord struct {
+	Start    string           `json:"start"`    // RFC3339
+	End      string           `json:"end"`      // RFC3339
+	Activity string           `json:"activity"` // バケット内で最も長かったカテゴリ
+	Seconds  map[string]int64 `json:"seconds"`  // カテゴリ別の内訳（秒）
+}
+
+type bucketAccumulator struct {
+	size   time.Duration
+	cur    time.Time // 集計中バケットの開始時刻（ゼロ値なら未開始）
+	totals map[string]time.Duration
+}
+
+func newBucketAccumulator(size time.Duration) *bucketAccumulator {
+	return &bucketAccumulator{size: size, totals: map[string]time.Duration{}}
+}
+
+// [start, end) のactivityを加算し、これによって確定したバケットを返す。
+// セッションは連続して届く前提なので、新しいバケットに入った時点で前のバケットは確定とみなす。
+func (b *bucketAccumulator) Add(activity string, start, end time.Time) []bucketRecord {
+	var done []bucketRecord
+	for start.Before(end) {
+		bs := bucketStart(start, b.size)
+		if b.cur.IsZero() {
+			b.cur = bs
+		} else if bs.After(b.cur) {
+			if rec, ok := b.Flush(); ok {
+				done = append(done, rec)
+			}
+			b.cur = bs
+		}
+		segEnd := bs.Add(b.size)
+		if end.Before(segEnd) {
+			segEnd = end
+		}
+		b.totals[activity] += segEnd.Sub(start)
+		start = segEnd
+	}
+	return done
+}
+
+// 集計中のバケットを確定して返す（空なら ok=false）
+func (b *bucketAccumulator) Flush() (bucketRecord, bool) {
+	if b.cur.IsZero() || len(b.totals) == 0 {
+		return bucketRecord{}, false
+	}
+	keys := make([]string, 0, len(b.totals))
+	for k := range b.totals {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys) // 同時間のときの決定性のため
+
+	rec := bucketRecord{
+		Start:   b.cur.Format(time.RFC3339),
+		End:     b.cur.Add(b.size).Format(time.RFC3339),
+		Seconds: map[string]int64{},
+	}
+	var best time.Duration
+	for _, k := range keys {
+		d := b.totals[k]
+		rec.Seconds[k] = int64(d.Round(time.Second) / time.Second)
+		if d > best {
+			best = d
+			rec.Activity = k
+		}
+	}
+	b.totals = map[string]time.Duration{}
+	return rec, true
+}
+
+// t を含むバケットの開始時刻。その日の 0:00（ローカル）から size ごとに区切る。
+// 0:00 からの経過時間で数えるので、夏時間の切り替え日でも t より後にはならない
+func bucketStart(t time.Time, size time.Duration) time.Time {
+	day := startOfDay(t)
+	off := t.Sub(day)
+	return day.Add(off - off%size)
+}
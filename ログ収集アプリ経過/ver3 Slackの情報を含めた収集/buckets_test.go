@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+var jst = time.FixedZone("JST", 9*60*60)
+
+func TestBucketStartLocalMidnight(t *testing.T) {
+	tests := []struct {
+		at   time.Time
+		size time.Duration
+		want time.Time
+	}{
+		{time.Date(2025, 9, 1, 10, 42, 5, 0, jst), time.Minute, time.Date(2025, 9, 1, 10, 42, 0, 0, jst)},
+		{time.Date(2025, 9, 1, 10, 42, 5, 0, jst), time.Hour, time.Date(2025, 9, 1, 10, 0, 0, 0, jst)},
+		{time.Date(2025, 9, 1, 10, 42, 5, 0, jst), 15 * time.Minute, time.Date(2025, 9, 1, 10, 30, 0, 0, jst)},
+		// UTC 基準の Truncate だと 2025-09-01 09:00 JST になる
+		{time.Date(2025, 9, 1, 8, 30, 0, 0, jst), 24 * time.Hour, time.Date(2025, 9, 1, 0, 0, 0, 0, jst)},
+		{time.Date(2025, 9, 1, 23, 59, 59, 0, jst), 24 * time.Hour, time.Date(2025, 9, 1, 0, 0, 0, 0, jst)},
+	}
+	for _, tt := range tests {
+		if got := bucketStart(tt.at, tt.size); !got.Equal(tt.want) {
+			t.Errorf("bucketStart(%s, %s) = %s, want %s", tt.at, tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestBucketAccumulatorDominantActivity(t *testing.T) {
+	b := newBucketAccumulator(time.Hour)
+	at := func(h, m int) time.Time { return time.Date(2025, 9, 1, h, m, 0, 0, jst) }
+
+	if done := b.Add("プログラムの制作", at(9, 0), at(9, 40)); len(done) != 0 {
+		t.Fatalf("bucket closed early: %+v", done)
+	}
+	done := b.Add("コミュニケーション", at(9, 40), at(10, 10))
+	if len(done) != 1 {
+		t.Fatalf("got %d buckets, want 1", len(done))
+	}
+	got := done[0]
+	if got.Start != "2025-09-01T09:00:00+09:00" || got.End != "2025-09-01T10:00:00+09:00" {
+		t.Errorf("bucket = %s - %s, want 09:00 - 10:00 JST", got.Start, got.End)
+	}
+	if got.Activity != "プログラムの制作" {
+		t.Errorf("activity = %q, want プログラムの制作", got.Activity)
+	}
+	if got.Seconds["プログラムの制作"] != 2400 || got.Seconds["コミュニケーション"] != 1200 {
+		t.Errorf("seconds = %v", got.Seconds)
+	}
+
+	rest, ok := b.Flush()
+	if !ok || rest.Start != "2025-09-01T10:00:00+09:00" || rest.Seconds["コミュニケーション"] != 600 {
+		t.Errorf("flush = %+v, %v", rest, ok)
+	}
+}
@@ -0,0 +1,28 @@
+//go:build darwin
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+/********** bundle ID（アプリ名ごとにキャッシュ） **********/
+var bundleIDCache = map[string]string{}
+
+// アプリ名からbundle IDを引く。毎ポーリングでosascriptを増やさないよう、アプリ名ごとに一度だけ問い合わせる
+func bundleIDOf(app string) string {
+	if app == "" {
+		return ""
+	}
+	if id, ok := bundleIDCache[app]; ok {
+		return id
+	}
+	id, err := runOSA(fmt.Sprintf(`id of application "%s"`, escapeOSA(app)))
+	if err != nil {
+		id = "" // 取れないアプリも再問い合わせしないよう空で覚えておく
+	}
+	id = strings.TrimSpace(id)
+	bundleIDCache[app] = id
+	return id
+}
@@ -0,0 +1,6 @@
+//go:build !darwin
+
+package main
+
+// bundle ID は macOS だけのもの（ほかの OS ではアプリ名だけで分類する）
+func bundleIDOf(app string) string { return "" }
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+/********** カメラ・マイク使用の検出（-detect-calls、任意） **********/
+// アプリ名の一覧に頼らず「通話中か」を判定するため、カメラまたはマイクが使用中かを調べる。
+// ブラウザ上の通話やDiscordのボイスチャンネルなども拾える。判定はベストエフォート:
+//   マイク: ioreg の入力オーディオエンジンが動作中（IOAudioEngineState = 1）
+//   カメラ: lsof でカメラ補助プロセス（VDCAssistant / AppleCameraAssistant）がファイルを開いているか
+// どちらも外部コマンドを起動するため、結果は callCheckTTL の間キャッシュする。
+const callCheckTTL = 10 * time.Second
+
+type callDetector struct {
+	checkedAt time.Time
+	inUse     bool
+}
+
+// カメラかマイクのどちらかが使用中なら true
+func (c *callDetector) OnCall(now time.Time) bool {
+	if !c.checkedAt.IsZero() && now.Sub(c.checkedAt) < callCheckTTL {
+		return c.inUse
+	}
+	c.inUse = micInUse() || cameraInUse()
+	c.checkedAt = now
+	return c.inUse
+}
+
+func micInUse() bool {
+	out, err := runCmd("ioreg", "-r", "-c", "AppleHDAEngineInput")
+	if err != nil {
+		return false
+	}
+	return strings.Contains(out, `"IOAudioEngineState" = 1`)
+}
+
+func cameraInUse() bool {
+	for _, p := range []string{"VDCAssistant", "AppleCameraAssistant"} {
+		// -c でプロセス名を絞る。開いているファイルが出力されればカメラ使用中とみなす
+		out, err := runCmd("lsof", "-n", "-w", "-c", p)
+		if err == nil && strings.TrimSpace(out) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func runCmd(name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
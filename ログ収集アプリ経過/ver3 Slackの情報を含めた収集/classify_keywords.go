@@ -0,0 +1,430 @@
+package main
+
+import (
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+/********** キーワード表によるカテゴリ判定 **********/
+// bundle ID / アプリ名 / URLのホスト / タブタイトル のどれかで判定する種類のカテゴリ用。
+// Extra は環境変数で追加できるアプリ名またはbundle ID（カンマ区切り、完全一致）。
+type keywordCategory struct {
+	Bundles []string // bundle ID（前方一致・小文字）
+	Apps    []string // アプリ名（部分一致・小文字）
+	Hosts   []string // URLのホスト（そのドメインかサブドメイン）
+	Titles  []string // タイトル（部分一致・小文字）
+	Extra   []string
+}
+
+func (k *keywordCategory) match(in activityInput) bool {
+	return k.matchedBy(in) != ""
+}
+
+// 一致したキーワード（"bundle:com.figma.desktop" "app:figma" "url-host:www.figma.com" "title:| canva"）。
+// 一致しなければ空
+func (k *keywordCategory) matchedBy(in activityInput) string {
+	b := strings.ToLower(in.BundleID)
+	a := strings.ToLower(in.App)
+	if b != "" {
+		for _, id := range k.Bundles {
+			if strings.HasPrefix(b, id) {
+				return "bundle:" + id
+			}
+		}
+	}
+	if key := firstContained(a, k.Apps); key != "" {
+		return "app:" + key
+	}
+	for _, x := range k.Extra {
+		if x == a || (b != "" && x == b) {
+			return "extra:" + x
+		}
+	}
+	if in.URL != "" && len(k.Hosts) > 0 {
+		if u, err := url.Parse(in.URL); err == nil && hostMatches(u.Hostname(), k.Hosts) {
+			return "url-host:" + strings.ToLower(u.Hostname())
+		}
+	}
+	if key := firstContained(strings.ToLower(in.Title), k.Titles); key != "" {
+		return "title:" + key
+	}
+	return ""
+}
+
+/********** デザイン作業 **********/
+// 追加はルールファイル（rules.go）の designApps / designHosts / designTitles、
+// または SHIRUSIA_DESIGN_APPS="Affinity Publisher,com.example.draw"
+var designTools = newDesignTools(userRules)
+
+func newDesignTools(rs *ruleSet) keywordCategory {
+	return keywordCategory{
+		Bundles: []string{
+			"com.figma.desktop",
+			"com.bohemiancoding.sketch3",
+			"com.adobe.photoshop",
+			"com.adobe.illustrator",
+			"com.adobe.indesign",
+			"com.adobe.xd",
+			"com.seriflabs.affinitydesigner",
+			"com.seriflabs.affinityphoto",
+			"com.pixelmatorteam.pixelmator",
+		},
+		Apps: []string{
+			"figma", "sketch", "photoshop", "illustrator", "indesign", "adobe xd",
+			"affinity designer", "affinity photo", "pixelmator",
+		},
+		Hosts:  append([]string{"figma.com", "canva.com", "photopea.com", "sketch.com", "miro.com"}, rs.DesignHosts...),
+		Titles: append([]string{"– figma", "- figma", "| canva", "photopea"}, rs.DesignTitles...),
+		Extra:  append(splitList(os.Getenv("SHIRUSIA_DESIGN_APPS")), rs.DesignApps...),
+	}
+}
+
+func isDesignTool(in activityInput) bool {
+	return designTools.match(in)
+}
+
+/********** AI活用 **********/
+// アプリ（bundle ID・アプリ名）と URL のホストで判定する。タイトルの単語（"claude" "gemini" "copilot"）では
+// 判定しない（これらのツールを話題にした記事やニュースまで拾ってしまうため）。
+// 追加はルールファイル（rules.go）の aiApps（アプリ名か bundle ID）と aiHosts（ドメイン）、
+// または SHIRUSIA_AI_APPS="LM Studio,com.example.assistant"
+var aiTools = newAITools(userRules)
+
+func newAITools(rs *ruleSet) keywordCategory {
+	return keywordCategory{
+		Bundles: []string{
+			"com.openai.chat",
+			"com.anthropic.claudefordesktop",
+			"com.microsoft.copilot",
+			"ai.perplexity.mac",
+			"com.google.gemini",
+		},
+		Apps: []string{"chatgpt", "claude", "copilot", "perplexity", "gemini", "lm studio", "ollama"},
+		Hosts: append([]string{
+			"chatgpt.com", "chat.openai.com", "claude.ai", "copilot.microsoft.com",
+			"gemini.google.com", "perplexity.ai", "poe.com", "chat.deepseek.com",
+		}, rs.AIHosts...),
+		Extra: append(splitList(os.Getenv("SHIRUSIA_AI_APPS")), rs.AIApps...),
+	}
+}
+
+func isAITool(in activityInput) bool {
+	return aiTools.match(in)
+}
+
+/********** 読書・資料閲覧 **********/
+// 電子書籍・PDFリーダー。Preview / Books は名前が一般的すぎるので bundle ID で判定する。
+// タイトルが .pdf / .epub のもの（ブラウザでPDFを開いている場合など）も含める。
+// 追加はルールファイル（rules.go）の readingApps / readingHosts / readingTitles、
+// または SHIRUSIA_READING_APPS="Zotero,com.example.reader"
+var readingTools = newReadingTools(userRules)
+
+func newReadingTools(rs *ruleSet) keywordCategory {
+	return keywordCategory{
+		Bundles: []string{
+			"com.amazon.lassen", // Kindle
+			"com.apple.ibooksx", // Books
+			"com.apple.preview",
+			"net.sourceforge.skim-app",
+			"com.readdle.pdfexpert",
+			"com.adobe.reader",
+			"com.adobe.acrobat",
+		},
+		Apps:   []string{"kindle", "pdf expert", "skim", "acrobat reader", "adobe acrobat"},
+		Hosts:  append([]string{"read.amazon.com", "read.amazon.co.jp"}, rs.ReadingHosts...),
+		Titles: append([]string{".pdf", ".epub"}, rs.ReadingTitles...),
+		Extra:  append(splitList(os.Getenv("SHIRUSIA_READING_APPS")), rs.ReadingApps...),
+	}
+}
+
+func isReadingApp(in activityInput) bool {
+	return readingTools.match(in)
+}
+
+/********** 金融・経理 **********/
+// ネットバンキング・証券・暗号資産の取引所・会計ソフト。
+// タイトルには残高や口座番号が出やすいので、このカテゴリに分類したセッションはタイトル中の
+// 数字の並び（4桁以上、または通貨記号付きの金額）を "•••" に置き換えてから記録する（redactFinanceTitle）。
+// SHIRUSIA_FINANCE_REDACT=0 で置き換えない。追加はルールファイル（rules.go）の financeApps / financeHosts / financeTitles、
+// または SHIRUSIA_FINANCE_APPS="MoneyMoney,com.example.bank"
+const financeActivity = "金融・経理"
+
+var financeTools = newFinanceTools(userRules)
+
+func newFinanceTools(rs *ruleSet) keywordCategory {
+	return keywordCategory{
+		Bundles: []string{
+			"com.tradingview.",
+			"com.moneymoney-app",
+			"com.quicken.",
+			"com.intuit.",
+			"com.youneedabudget.",
+		},
+		Apps: []string{"freee", "money forward", "マネーフォワード", "tradingview", "quickbooks", "quicken", "moneymoney", "ynab"},
+		Hosts: append([]string{
+			// 銀行
+			"mufg.jp", "smbc.co.jp", "mizuhobank.co.jp", "resonabank.co.jp", "rakuten-bank.co.jp",
+			"netbk.co.jp", "sonybank.net", "paypay-bank.co.jp", "jp-bank.japanpost.jp",
+			"chase.com", "bankofamerica.com", "wellsfargo.com", "paypal.com", "wise.com",
+			// 証券・取引
+			"sbisec.co.jp", "rakuten-sec.co.jp", "monex.co.jp", "matsui.co.jp", "nomura.co.jp",
+			"robinhood.com", "schwab.com", "fidelity.com", "interactivebrokers.com", "tradingview.com",
+			"coinbase.com", "binance.com", "bitflyer.com",
+			// 会計・経費
+			"freee.co.jp", "moneyforward.com", "yayoi-kk.co.jp", "quickbooks.intuit.com", "xero.com",
+		}, rs.FinanceHosts...),
+		Titles: append([]string{
+			"freee", "マネーフォワード", "money forward", "tradingview", "ネットバンキング", "インターネットバンキング",
+			"online banking", "確定申告", "経費精算", "e-tax",
+		}, rs.FinanceTitles...),
+		Extra: append(splitList(os.Getenv("SHIRUSIA_FINANCE_APPS")), rs.FinanceApps...),
+	}
+}
+
+func isFinanceWork(in activityInput) bool {
+	return financeTools.match(in)
+}
+
+var (
+	financeRedact   = strings.TrimSpace(os.Getenv("SHIRUSIA_FINANCE_REDACT")) != "0"
+	financeNumberRe = regexp.MustCompile(`[¥￥$€£]\s?\d[\d,.]*|\d[\d,.\- ]{2,}\d`)
+)
+
+// 残高・口座番号らしい数字を伏せる
+func redactFinanceTitle(title string) string {
+	if !financeRedact {
+		return title
+	}
+	return financeNumberRe.ReplaceAllStringFunc(title, func(m string) string {
+		if strings.ContainsAny(m, "¥￥$€£") || countDigits(m) >= 4 {
+			return "•••"
+		}
+		return m
+	})
+}
+
+func countDigits(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			n++
+		}
+	}
+	return n
+}
+
+/********** キャリア・求職 **********/
+// LinkedIn・求人サイト・履歴書の作成。ブラウザやWordの中でも一般的な閲覧・文書作成と分けて数える。
+// 追加はルールファイル（rules.go）の careerApps / careerHosts / careerTitles、
+// または SHIRUSIA_CAREER_APPS="Offers,com.example.jobs"
+var careerTools = newCareerTools(userRules)
+
+func newCareerTools(rs *ruleSet) keywordCategory {
+	return keywordCategory{
+		Hosts: append([]string{
+			"linkedin.com", "indeed.com", "glassdoor.com", "wellfound.com", "wantedly.com",
+			"bizreach.jp", "doda.jp", "rikunabi.com", "mynavi.jp", "en-japan.com",
+			"green-japan.com", "levtech.jp", "findy-code.io",
+		}, rs.CareerHosts...),
+		Titles: append([]string{
+			"linkedin", "indeed", "glassdoor", "wantedly", "bizreach", "ビズリーチ", "doda",
+			"リクナビ", "マイナビ転職", "求人", "転職", "履歴書", "職務経歴書", "resume", "résumé", "curriculum vitae",
+		}, rs.CareerTitles...),
+		Extra: append(splitList(os.Getenv("SHIRUSIA_CAREER_APPS")), rs.CareerApps...),
+	}
+}
+
+func isCareerWork(in activityInput) bool {
+	return careerTools.match(in)
+}
+
+/********** ノート・PKM **********/
+// Obsidian / Notion / Logseq などのノート・ナレッジ管理ツール。既定では従来どおり「ドキュメント編集」に含め、
+// SHIRUSIA_PKM_CATEGORY="ノート・PKM" のようにカテゴリ名を指定すると、Word / Pages などの文書作成と分けて記録する。
+// Apple のメモは名前が一般的すぎるので bundle ID で判定する。
+// 追加は SHIRUSIA_PKM_APPS="Craft,com.example.notes"
+var noteTools = keywordCategory{
+	Bundles: []string{
+		"md.obsidian",
+		"notion.id",
+		"com.logseq.logseq",
+		"net.shinyfrog.bear",
+		"com.apple.notes",
+		"com.lukilabs.lukiapp", // Craft
+	},
+	Apps:  []string{"obsidian", "notion", "logseq", "roam research", "bear"},
+	Hosts: []string{"roamresearch.com"},
+	Extra: splitList(os.Getenv("SHIRUSIA_PKM_APPS")),
+}
+
+const pkmSplitActivity = "ノート・PKM" // SHIRUSIA_PKM_CATEGORY の例・-focus の既定に含める名前
+
+var pkmActivity = loadPKMActivity()
+
+func loadPKMActivity() string {
+	return pkmActivityFrom(os.Getenv("SHIRUSIA_PKM_CATEGORY"))
+}
+
+// SHIRUSIA_PKM_CATEGORY の値からノートツールのカテゴリを決める（空なら「ドキュメント編集」）
+func pkmActivityFrom(env string) string {
+	if c := strings.TrimSpace(env); c != "" {
+		return c
+	}
+	return "ドキュメント編集"
+}
+
+// ノートツールを category に分類するルール（builtinRules。category は pkmActivity）
+func noteRule(category string) activityRule {
+	return activityRule{category, "notes", func(in activityInput, a, t string) bool {
+		return isNoteApp(in)
+	}}
+}
+
+func isNoteApp(in activityInput) bool {
+	return noteTools.match(in)
+}
+
+/********** ゲーム **********/
+// 全画面のゲームはアクセシビリティからウィンドウタイトルを取れないことが多く、
+// そのままだとどのゲームも空タイトルになる。既知のゲームはアプリ名を仮のタイトルにする（gameTitle）。
+// Unity 製のゲームは bundle ID が "unity.<会社>.<ゲーム>" になる。
+// 追加は SHIRUSIA_GAME_APPS="Factorio,com.example.game"
+var gameApps = keywordCategory{
+	Bundles: []string{
+		"com.valvesoftware.",
+		"com.blizzard.",
+		"com.riotgames.",
+		"com.epicgames.",
+		"com.mojang.",
+		"com.feralinteractive.",
+		"com.aspyr.",
+		"com.larian.",
+		"com.hoyoverse.",
+		"unity.",
+	},
+	Apps: []string{
+		"steam", "minecraft", "league of legends", "world of warcraft", "hearthstone",
+		"diablo", "overwatch", "baldur's gate", "civilization", "stardew valley",
+		"genshin impact", "dota 2", "counter-strike",
+	},
+	Extra: splitList(os.Getenv("SHIRUSIA_GAME_APPS")),
+}
+
+func isGameApp(in activityInput) bool {
+	return gameApps.match(in)
+}
+
+// タイトルが空の既知のゲームはアプリ名をタイトルにする（ゲームごとに別のセッションになるように）
+func gameTitle(in activityInput) string {
+	if strings.TrimSpace(in.Title) == "" && isGameApp(in) {
+		return in.App
+	}
+	return in.Title
+}
+
+/********** 調査・ドキュメント閲覧（URLのホスト） **********/
+// タイトルだけでは記事名しか分からないので、URL が取れるときはホストで判定する。
+// docs.* / developer.* のサブドメインもドキュメントとして扱う（docs.python.org, developer.apple.com など）
+var researchSites = keywordCategory{
+	Hosts: []string{
+		"arxiv.org", "qiita.com", "zenn.dev", "stackoverflow.com", "stackexchange.com", "superuser.com",
+		"developer.mozilla.org", "pkg.go.dev", "go.dev", "readthedocs.io", "docs.rs", "man7.org",
+		"scholar.google.com", "wikipedia.org",
+	},
+}
+
+var researchSubdomains = []string{"docs.", "developer.", "developers.", "devdocs."}
+
+// サブドメインは docs.* でも編集ツールのもの（Google ドキュメント）
+var notResearchHosts = []string{"docs.google.com"}
+
+func isResearchSite(in activityInput) bool {
+	if in.URL == "" {
+		return false
+	}
+	if researchSites.match(activityInput{URL: in.URL}) {
+		return true
+	}
+	u, err := url.Parse(in.URL)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	if hostMatches(host, notResearchHosts) {
+		return false
+	}
+	for _, p := range researchSubdomains {
+		if strings.HasPrefix(host, p) {
+			return true
+		}
+	}
+	return false
+}
+
+/********** インフラ・運用 **********/
+// クラウドのコンソール、Kubernetes のダッシュボード、ターミナルでの kubectl / terraform など。
+// 追加はルールファイル（rules.go）の infraApps / infraHosts / infraTitles、
+// または SHIRUSIA_INFRA_APPS="Cyberduck,com.example.infra"
+var infraTools = newInfraTools(userRules)
+
+func newInfraTools(rs *ruleSet) keywordCategory {
+	return keywordCategory{
+		Apps: []string{"docker desktop", "podman desktop", "openlens", "aptakube"},
+		Hosts: append([]string{
+			"console.aws.amazon.com", "console.cloud.google.com", "portal.azure.com",
+			"app.terraform.io", "cloud.digitalocean.com", "dash.cloudflare.com",
+		}, rs.InfraHosts...),
+		// "Management Console" "Microsoft Azure" は記事やブログのタイトルにも出るので、コンソールはホストで判定する
+		Titles: append([]string{"google cloud console", "kubernetes dashboard", "terraform cloud"}, rs.InfraTitles...),
+		Extra:  append(splitList(os.Getenv("SHIRUSIA_INFRA_APPS")), rs.InfraApps...),
+	}
+}
+
+// ターミナルのタイトルに出るインフラ系コマンド（シェルが実行中のコマンドをタイトルに出す設定のとき）
+var infraCommands = []string{"kubectl", "terraform", "k9s", "helm", "kubectx", "gcloud", "az", "aws", "pulumi", "ansible", "ansible-playbook"}
+
+func isInfraWork(in activityInput) bool {
+	if infraTools.match(in) {
+		return true
+	}
+	if !isTerminal(in) { // terminal.go
+		return false
+	}
+	// "aws" や "az" が単語の一部に当たらないよう、単語単位で比べる
+	for _, w := range strings.FieldsFunc(strings.ToLower(in.Title), isTitleSeparator) {
+		for _, c := range infraCommands {
+			if w == c {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isTitleSeparator(r rune) bool {
+	return r == ' ' || r == '\t' || r == '—' || r == '–' || r == ':' || r == '|' || r == '/' || r == '(' || r == ')'
+}
+
+// host が domains のいずれか（またはそのサブドメイン）か
+func hostMatches(host string, domains []string) bool {
+	host = strings.ToLower(host)
+	for _, d := range domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// カンマ区切りの設定値を小文字・空白除去のリストにする
+func splitList(raw string) []string {
+	var out []string
+	for _, p := range strings.Split(raw, ",") {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestClassifyDesign(t *testing.T) {
+	checkClassify(t, []classifyCase{
+		{"figma desktop bundle", activityInput{App: "Figma", BundleID: "com.figma.Desktop", Title: "Onboarding flow"}, "デザイン作業"},
+		{"photoshop by app name", activityInput{App: "Adobe Photoshop 2025", Title: "banner.psd @ 100%"}, "デザイン作業"},
+		{"sketch", activityInput{App: "Sketch", BundleID: "com.bohemiancoding.sketch3", Title: "Icons"}, "デザイン作業"},
+		{"figma in browser by url", activityInput{App: "Google Chrome", Title: "Onboarding flow", URL: "https://www.figma.com/design/abc/Onboarding"}, "デザイン作業"},
+		{"figma in browser by title", activityInput{App: "Safari", Title: "Onboarding flow – Figma"}, "デザイン作業"},
+		{"canva title", activityInput{App: "Arc", Title: "Poster | Canva"}, "デザイン作業"},
+		{"plain browsing", activityInput{App: "Google Chrome", Title: "News", URL: "https://example.com/"}, "Webブラウジング"},
+	})
+}
+
+func TestClassifyAI(t *testing.T) {
+	checkClassify(t, []classifyCase{
+		{"chatgpt desktop", activityInput{App: "ChatGPT", BundleID: "com.openai.chat", Title: "New chat"}, "AI活用"},
+		{"claude desktop", activityInput{App: "Claude", BundleID: "com.anthropic.claudefordesktop", Title: "Refactor plan"}, "AI活用"},
+		{"chatgpt in browser", activityInput{App: "Google Chrome", Title: "ChatGPT", URL: "https://chatgpt.com/c/123"}, "AI活用"},
+		{"claude in browser", activityInput{App: "Safari", Title: "Claude", URL: "https://claude.ai/chat/abc"}, "AI活用"},
+		{"gemini in browser", activityInput{App: "Arc", Title: "Gemini", URL: "https://gemini.google.com/app"}, "AI活用"},
+		// ツールの名前が出てくるだけの記事はAI活用にしない
+		{"article about claude", activityInput{App: "Google Chrome", Title: "Claude vs Gemini: which is better? - The Verge", URL: "https://www.theverge.com/ai"}, "Webブラウジング"},
+		{"article without url", activityInput{App: "Safari", Title: "GitHub Copilot pricing changes"}, "Webブラウジング"},
+		// エディタ内の Copilot はコーディングのまま
+		{"copilot chat in editor", activityInput{App: "Visual Studio Code", Title: "Copilot Chat — main.go"}, "プログラムの制作"},
+	})
+}
+
+func TestAIToolsFromRulesFile(t *testing.T) {
+	path := writeTestFile(t, "rules.yaml", "aiApps: [Msty, ' com.Example.Assistant ']\naiHosts: [Chat.Mistral.AI]\n")
+	rs, err := readRulesFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ai := newAITools(rs)
+	for _, in := range []activityInput{
+		{App: "Msty", Title: "New chat"},
+		{App: "Assistant", BundleID: "com.example.assistant"},
+		{App: "Google Chrome", Title: "Le Chat", URL: "https://chat.mistral.ai/chat"},
+		{App: "Google Chrome", Title: "ChatGPT", URL: "https://chatgpt.com/"},
+	} {
+		if !ai.match(in) {
+			t.Errorf("%+v did not match aiApps/aiHosts from the rules file", in)
+		}
+	}
+	if ai.match(activityInput{App: "Google Chrome", Title: "Mistral AI news", URL: "https://mistral.ai/news"}) {
+		t.Error("mistral.ai matched although only chat.mistral.ai is listed")
+	}
+}
+
+// aiApps / aiHosts と同じように、ほかのカテゴリもルールファイルでアプリ・ホスト・タイトルを足せる
+func TestKeywordToolsFromRulesFile(t *testing.T) {
+	path := writeTestFile(t, "rules.yaml", `
+careerApps: [Offers]
+careerHosts: [Jobs.Example.com]
+careerTitles: [選考]
+designApps: [com.example.draw]
+designHosts: [excalidraw.com]
+designTitles: ["- Penpot"]
+readingApps: [Zotero]
+readingHosts: [oreilly.com]
+readingTitles: [.mobi]
+infraApps: [Cyberduck]
+infraHosts: [console.hetzner.cloud]
+infraTitles: [grafana]
+financeApps: [MoneyMoney Lite]
+financeHosts: [mybank.example]
+financeTitles: [家計簿]
+`)
+	rs, err := readRulesFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		tools keywordCategory
+		in    []activityInput
+	}{
+		{"career", newCareerTools(rs), []activityInput{
+			{App: "Offers"},
+			{App: "Google Chrome", Title: "Openings", URL: "https://jobs.example.com/backend"},
+			{App: "Microsoft Word", Title: "二次選考メモ.docx"},
+		}},
+		{"design", newDesignTools(rs), []activityInput{
+			{App: "Draw", BundleID: "com.example.draw"},
+			{App: "Safari", Title: "Untitled", URL: "https://excalidraw.com/"},
+			{App: "Firefox", Title: "Wireframe - Penpot"},
+		}},
+		{"reading", newReadingTools(rs), []activityInput{
+			{App: "Zotero"},
+			{App: "Safari", Title: "Learning Go", URL: "https://learning.oreilly.com/library/view/x"},
+			{App: "Calibre", Title: "book.mobi"},
+		}},
+		{"infra", newInfraTools(rs), []activityInput{
+			{App: "Cyberduck"},
+			{App: "Google Chrome", Title: "Servers", URL: "https://console.hetzner.cloud/projects"},
+			{App: "Arc", Title: "Home - Grafana"},
+		}},
+		{"finance", newFinanceTools(rs), []activityInput{
+			{App: "MoneyMoney Lite"},
+			{App: "Safari", Title: "Balance", URL: "https://www.mybank.example/"},
+			{App: "Numbers", Title: "家計簿2025.numbers"},
+		}},
+	}
+	for _, tt := range tests {
+		tools := tt.tools
+		for _, in := range tt.in {
+			if !tools.match(in) {
+				t.Errorf("%s: %+v did not match the keys from the rules file", tt.name, in)
+			}
+		}
+		// 組み込みのキーワードも残っている
+		if tt.name == "career" && !tools.match(activityInput{App: "Safari", URL: "https://www.linkedin.com/feed/"}) {
+			t.Error("career: builtin hosts lost after merging the rules file")
+		}
+	}
+	// ルールファイルがなければ組み込みのまま
+	if career := newCareerTools(&ruleSet{}); career.match(activityInput{App: "Offers"}) {
+		t.Error("career: matched Offers without a rules file")
+	}
+}
+
+func TestClassifyInfra(t *testing.T) {
+	checkClassify(t, []classifyCase{
+		{"aws console", activityInput{App: "Google Chrome", Title: "EC2 | us-east-1", URL: "https://us-east-1.console.aws.amazon.com/ec2/home"}, "インフラ・運用"},
+		{"gcp console title", activityInput{App: "Safari", Title: "Kubernetes Engine – Google Cloud console"}, "インフラ・運用"},
+		{"azure portal", activityInput{App: "Microsoft Edge", Title: "Home - Microsoft Azure", URL: "https://portal.azure.com/#home"}, "インフラ・運用"},
+		{"console news", activityInput{App: "Google Chrome", Title: "AWS Management Console gets a new look - Tech News"}, "Webブラウジング"},
+		{"azure blog", activityInput{App: "Safari", Title: "What's new in Microsoft Azure this month"}, "Webブラウジング"},
+		{"docker desktop", activityInput{App: "Docker Desktop", Title: "Containers"}, "インフラ・運用"},
+		{"kubectl in terminal", activityInput{App: "iTerm2", Title: "kubectl get pods -n prod"}, "インフラ・運用"},
+		{"terraform in terminal", activityInput{App: "Terminal", Title: "infra — terraform plan — 120×40"}, "インフラ・運用"},
+		{"aws cli in terminal", activityInput{App: "Warp", Title: "aws s3 ls"}, "インフラ・運用"},
+		// コマンド名は単語単位で比べる（"awsome" や "lazy" に当たらない）
+		{"aws inside a word", activityInput{App: "Terminal", Title: "awsome-project — zsh"}, terminalActivity},
+		{"plain shell", activityInput{App: "Terminal", Title: "~ — -zsh — 80×24"}, terminalActivity},
+		// ブラウザのタイトルにコマンド名が出るだけならインフラにしない
+		{"kubectl article", activityInput{App: "Google Chrome", Title: "kubectl cheat sheet", URL: "https://example.com/kubectl"}, "Webブラウジング"},
+		// エディタで .tf を開いているのはコーディング
+		{"terraform file in editor", activityInput{App: "Visual Studio Code", Title: "main.tf — infra"}, "プログラムの制作"},
+	})
+}
+
+func TestClassifyReading(t *testing.T) {
+	checkClassify(t, []classifyCase{
+		{"kindle app", activityInput{App: "Kindle", BundleID: "com.amazon.Lassen", Title: "リーダブルコード"}, "読書・資料閲覧"},
+		{"preview by bundle", activityInput{App: "Preview", BundleID: "com.apple.Preview", Title: "paper.pdf"}, "読書・資料閲覧"},
+		{"books by bundle", activityInput{App: "Books", BundleID: "com.apple.iBooksX", Title: "Go言語による並行処理"}, "読書・資料閲覧"},
+		{"pdf in browser", activityInput{App: "Google Chrome", Title: "spec-v2.pdf", URL: "https://example.com/spec-v2.pdf"}, "読書・資料閲覧"},
+		{"kindle cloud reader", activityInput{App: "Safari", Title: "Kindle", URL: "https://read.amazon.co.jp/?asin=B0"}, "読書・資料閲覧"},
+		// 名前が一般的な "Preview" "Books" は bundle ID なしでは読書にしない
+		{"books without bundle", activityInput{App: "Books", Title: "Library"}, defaultActivity},
+	})
+}
+
+func TestGameTitle(t *testing.T) {
+	tests := []struct {
+		name string
+		in   activityInput
+		want string
+	}{
+		// 全画面でタイトルが空のゲームは、アプリ名をタイトルにする
+		{"blank title", activityInput{App: "Minecraft", Title: ""}, "Minecraft"},
+		{"whitespace title", activityInput{App: "Stardew Valley", Title: "  "}, "Stardew Valley"},
+		{"by bundle", activityInput{App: "Hades", BundleID: "com.valvesoftware.steam.hades"}, "Hades"},
+		{"title kept", activityInput{App: "Steam", Title: "Library"}, "Library"},
+		// ゲーム以外の空タイトルは空のまま
+		{"not a game", activityInput{App: "Finder", Title: ""}, ""},
+	}
+	for _, tt := range tests {
+		if got := gameTitle(tt.in); got != tt.want {
+			t.Errorf("%s: gameTitle = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// タイトルが空でもゲームごとに別のセッションになり、どちらも「ゲーム」に分類される
+func TestBlankTitleGamesSplitSessions(t *testing.T) {
+	var recs []*record
+	for _, app := range []string{"Minecraft", "Stardew Valley"} {
+		in := activityInput{App: app}
+		in.Title = gameTitle(in)
+		recs = append(recs, &record{App: app, Title: in.Title, Activity: classify(in)})
+	}
+	for _, r := range recs {
+		if r.Activity != "ゲーム" {
+			t.Errorf("%s: activity = %q, want ゲーム", r.App, r.Activity)
+		}
+	}
+	if recs[0].Title == recs[1].Title || !changed(recs[0], recs[1]) {
+		t.Errorf("two blank-title games were merged: %q / %q", recs[0].Title, recs[1].Title)
+	}
+}
+
+func TestClassifyNotesDefault(t *testing.T) {
+	checkClassify(t, []classifyCase{
+		{"obsidian", activityInput{App: "Obsidian", BundleID: "md.obsidian", Title: "Daily note - vault"}, "ドキュメント編集"},
+		{"notion", activityInput{App: "Notion", Title: "Roadmap"}, "ドキュメント編集"},
+		{"apple notes by bundle", activityInput{App: "Notes", BundleID: "com.apple.Notes", Title: "買い物"}, "ドキュメント編集"},
+		{"word", activityInput{App: "Microsoft Word", Title: "report.docx"}, "ドキュメント編集"},
+		// Apple のメモは bundle ID がないと判定しない
+		{"notes without bundle", activityInput{App: "Notes", Title: "買い物"}, defaultActivity},
+		{"notion in browser", activityInput{App: "Safari", Title: "Roadmap", URL: "https://www.notion.so/roadmap"}, "Webブラウジング"},
+	})
+}
+
+func TestPKMActivityFrom(t *testing.T) {
+	tests := []struct{ env, want string }{
+		{"", "ドキュメント編集"},
+		{"  ", "ドキュメント編集"},
+		{" " + pkmSplitActivity + " ", pkmSplitActivity},
+		{"Notes", "Notes"},
+	}
+	for _, tt := range tests {
+		if got := pkmActivityFrom(tt.env); got != tt.want {
+			t.Errorf("pkmActivityFrom(%q) = %q, want %q", tt.env, got, tt.want)
+		}
+	}
+}
+
+// SHIRUSIA_PKM_CATEGORY を指定すると、ノートツールだけが別カテゴリになり、Word などの文書作成は変わらない
+func TestClassifyNotesSplit(t *testing.T) {
+	t.Setenv("SHIRUSIA_PKM_CATEGORY", " "+pkmSplitActivity+" ")
+
+	// builtinRules は起動時に作られるので、ノートのルールだけを作り直した表で分類する（グローバルは変えない）
+	rules := slices.Clone(builtinRules)
+	found := false
+	for i := range rules {
+		if rules[i].By == "notes" {
+			rules[i], found = noteRule(loadPKMActivity()), true
+		}
+	}
+	if !found {
+		t.Fatal("no notes rule in builtinRules")
+	}
+	tests := []classifyCase{
+		{"obsidian", activityInput{App: "Obsidian", BundleID: "md.obsidian", Title: "Daily note - vault"}, pkmSplitActivity},
+		{"logseq", activityInput{App: "Logseq", BundleID: "com.logseq.logseq", Title: "Journals"}, pkmSplitActivity},
+		{"roam in browser", activityInput{App: "Google Chrome", Title: "Daily Notes", URL: "https://roamresearch.com/#/app/db"}, pkmSplitActivity},
+		{"word", activityInput{App: "Microsoft Word", Title: "report.docx"}, "ドキュメント編集"},
+		{"pages", activityInput{App: "Pages", Title: "Letter"}, "ドキュメント編集"},
+	}
+	for _, tt := range tests {
+		if got := classifyWithRules(tt.in, rules).Category; got != tt.want {
+			t.Errorf("%s: classifyWithRules(%+v) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestClassifyCareer(t *testing.T) {
+	checkClassify(t, []classifyCase{
+		{"linkedin by url", activityInput{App: "Google Chrome", Title: "(3) Feed", URL: "https://www.linkedin.com/feed/"}, "キャリア・求職"},
+		{"job board by url", activityInput{App: "Safari", Title: "Go エンジニアの求人", URL: "https://doda.jp/DodaFront/View/JobSearchList.action"}, "キャリア・求職"},
+		{"job title without url", activityInput{App: "Arc", Title: "バックエンドエンジニアの求人 - Wantedly"}, "キャリア・求職"},
+		{"resume in word", activityInput{App: "Microsoft Word", Title: "職務経歴書_2025.docx"}, "キャリア・求職"},
+		{"resume in pages", activityInput{App: "Pages", Title: "Resume - English"}, "キャリア・求職"},
+		// エディタで開いているのはコーディングのまま
+		{"resume parser in editor", activityInput{App: "Visual Studio Code", Title: "resume.go — parser"}, "プログラムの制作"},
+		{"plain word document", activityInput{App: "Microsoft Word", Title: "議事録.docx"}, "ドキュメント編集"},
+		{"plain browsing", activityInput{App: "Google Chrome", Title: "News", URL: "https://example.com/"}, "Webブラウジング"},
+	})
+}
+
+func TestClassifyFinance(t *testing.T) {
+	checkClassify(t, []classifyCase{
+		{"bank by url", activityInput{App: "Google Chrome", Title: "口座一覧", URL: "https://direct.smbc.co.jp/aib/aibgsjsw5001.jsp"}, financeActivity},
+		{"broker by url", activityInput{App: "Safari", Title: "ポートフォリオ", URL: "https://site1.sbisec.co.jp/ETGate/"}, financeActivity},
+		{"accounting app", activityInput{App: "freee会計", Title: "取引一覧"}, financeActivity},
+		{"tradingview bundle", activityInput{App: "TradingView", BundleID: "com.tradingview.tradingviewapp.desktop", Title: "USDJPY 147.25"}, financeActivity},
+		{"tax return title", activityInput{App: "Arc", Title: "確定申告書等作成コーナー"}, financeActivity},
+		// 決済の仕組みを書いているコードはコーディングのまま
+		{"paypal sdk in editor", activityInput{App: "Visual Studio Code", Title: "paypal.go — checkout"}, "プログラムの制作"},
+		{"plain browsing", activityInput{App: "Google Chrome", Title: "News", URL: "https://example.com/"}, "Webブラウジング"},
+	})
+}
+
+func TestRedactFinanceTitle(t *testing.T) {
+	defer func(v bool) { financeRedact = v }(financeRedact)
+	financeRedact = true
+	tests := []struct{ in, want string }{
+		{"残高 1,234,567円 - 三井住友銀行", "残高 •••円 - 三井住友銀行"},
+		{"普通 1234567 お取引", "普通 ••• お取引"},
+		{"Balance $12,345.67 | Chase", "Balance ••• | Chase"},
+		{"¥980 のお支払い", "••• のお支払い"},
+		{"Card ending 4242 4242 4242 4242", "Card ending •••"},
+		// 3桁以下の数字は残す（件数・ページなど）
+		{"明細 12件 (3/10)", "明細 12件 (3/10)"},
+		{"取引一覧", "取引一覧"},
+	}
+	for _, tt := range tests {
+		if got := redactFinanceTitle(tt.in); got != tt.want {
+			t.Errorf("redactFinanceTitle(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	financeRedact = false // SHIRUSIA_FINANCE_REDACT=0
+	if got := redactFinanceTitle("残高 1,234,567円"); got != "残高 1,234,567円" {
+		t.Errorf("redaction disabled: got %q", got)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+/********** 正規表現を使う判定 **********/
+// hasAny の部分一致だけだと、文中に "docs" と書いてあるだけのページや "docs.google.com" の ".go" まで拾ってしまう。
+// 位置や語の区切りが大事な判定は、起動時にコンパイルした正規表現で行う（ポーリングごとにはコンパイルしない）。
+//
+// ウィンドウタイトルは "main.go — activitylog — Visual Studio Code" や "Introduction - Go Docs" のように
+// 区切り（" — " " – " " - " " | "）で項目が並ぶので、項目ごとに照合する（titleSegments）。
+type textMatcher struct {
+	subs []string         // 小文字の部分一致
+	res  []*regexp.Regexp // タイトル全体に当てる正規表現
+	segs []*regexp.Regexp // タイトルの項目ごとに当てる正規表現（^ $ は項目の先頭・末尾）
+}
+
+func (m *textMatcher) match(title string) bool {
+	if hasAny(strings.ToLower(title), m.subs) {
+		return true
+	}
+	for _, re := range m.res {
+		if re.MatchString(title) {
+			return true
+		}
+	}
+	if len(m.segs) == 0 {
+		return false
+	}
+	for _, p := range titleSegments(title) {
+		for _, re := range m.segs {
+			if re.MatchString(p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var (
+	titleSepRe = regexp.MustCompile(`\s+[—–|-]\s+`)
+	// 項目の末尾がソースファイルの拡張子（"main.go" は当たり、"docs.google.com" は当たらない）
+	sourceFiles = textMatcher{segs: []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\S(\.(?:` + extAlternation(sourceExts) + `))$`),
+	}}
+
+	// 調査・ドキュメント閲覧（ブラウザのタイトル）。"docs" は文中に出てくるだけでは当てない
+	researchTitles = textMatcher{
+		subs: []string{"arxiv", "qiita", "stackoverflow", "stack overflow", "doc:", "documentation", "mdn"},
+		res: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)(?:^|[\s(/])docs\.[a-z0-9-]+\.[a-z]{2,}`), // docs.python.org などのホスト名
+		},
+		segs: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)^(?:[\w.]+\s)?docs$`), // "Docs" "Go Docs" だけの項目
+		},
+	}
+)
+
+// タイトルの項目（エディタの未保存マーク "●" は除く）
+func titleSegments(title string) []string {
+	parts := titleSepRe.Split(title, -1)
+	for i, p := range parts {
+		parts[i] = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(p), "●"))
+	}
+	return parts
+}
+
+// タイトルのどこかの項目がソースファイル名か
+func hasSourceFileTitle(title string) bool {
+	return sourceFiles.match(title)
+}
+
+// タイトルの項目の末尾にあるソースファイルの拡張子（".go" など。なければ空）
+func sourceFileExt(title string) string {
+	for _, p := range titleSegments(title) {
+		if m := sourceFiles.segs[0].FindStringSubmatch(p); m != nil {
+			return strings.ToLower(m[1])
+		}
+	}
+	return ""
+}
+
+/********** 分類の根拠（matchedBy） **********/
+// builtinRules の By を session.matchedBy の表記にする。
+//   "app"        → "app:visual studio code"（小文字のアプリ名）
+//   "title-ext"  → "title-ext:.go"
+//   "url-host"   → "url-host:github.com"
+//   キーワード表のカテゴリ（"design" など）→ 一致したキーワード（"app:figma" "url-host:www.canva.com"）
+// それ以外（"dev-url" "title:webmail" など）はそのまま
+var keywordRuleSets = map[string]*keywordCategory{
+	"game": &gameApps, "design": &designTools, "ai": &aiTools, "finance": &financeTools,
+	"infra": &infraTools, "reading": &readingTools, "career": &careerTools, "notes": &noteTools,
+	"terminal": &terminalTools,
+}
+
+func matchedBy(by string, in activityInput, a string) string {
+	switch by {
+	case "app":
+		return "app:" + a
+	case "title-ext":
+		if ext := sourceFileExt(in.Title); ext != "" {
+			return "title-ext:" + ext
+		}
+	case "url-host":
+		if u, err := url.Parse(in.URL); err == nil && u.Hostname() != "" {
+			return "url-host:" + strings.ToLower(u.Hostname())
+		}
+	}
+	if k, ok := keywordRuleSets[by]; ok {
+		if m := k.matchedBy(in); m != "" {
+			return m
+		}
+	}
+	return by
+}
+
+// matchedBy から値（アプリ名・ホスト・キーワード）を落とし、種類だけにする（"url-host:github.com" → "url-host"）。
+// タイトルやURLを残さないセッション用
+func matchedByKind(by string) string {
+	kind, _, _ := strings.Cut(by, ":")
+	return kind
+}
+
+// [".go", ".py"] → "go|py"
+func extAlternation(exts []string) string {
+	out := make([]string, len(exts))
+	for i, e := range exts {
+		out[i] = regexp.QuoteMeta(strings.TrimPrefix(e, "."))
+	}
+	return strings.Join(out, "|")
+}
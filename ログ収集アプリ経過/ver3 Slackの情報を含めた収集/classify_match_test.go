@@ -0,0 +1,77 @@
+package main
+
+import "testing"
+
+func TestHasSourceFileTitle(t *testing.T) {
+	tests := []struct {
+		title string
+		want  bool
+	}{
+		{"main.go — activitylog — Visual Studio Code", true},
+		{"● main.go — activitylog", true}, // 未保存マーク
+		{"app.py - project - PyCharm", true},
+		{"App.java | backend", true},
+		{"MAIN.GO", true},
+		// ".go" を含むだけのものは当てない
+		{"golang.google.com", false},
+		{"docs.google.com - Google Docs", false},
+		{"Let's go.go team", false},
+		{".go", false}, // 拡張子だけ
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := hasSourceFileTitle(tt.title); got != tt.want {
+			t.Errorf("hasSourceFileTitle(%q) = %v, want %v", tt.title, got, tt.want)
+		}
+	}
+	if ext := sourceFileExt("● Main.GO — activitylog"); ext != ".go" {
+		t.Errorf("sourceFileExt = %q, want .go", ext)
+	}
+}
+
+func TestResearchTitles(t *testing.T) {
+	tests := []struct {
+		title string
+		want  bool
+	}{
+		{"Introduction - Go Docs", true},
+		{"Docs", true},
+		{"docs.python.org/3/library", true},
+		{"(docs.djangoproject.com) Models", true},
+		{"Stack Overflow - Where Developers Learn", true},
+		{"Array.prototype.map() - JavaScript | MDN", true},
+		// 文中の "docs" や Google ドキュメントは調査にしない
+		{"How we write our docs at Acme - Blog", false},
+		{"mydocs.example.com", false},
+	}
+	for _, tt := range tests {
+		if got := researchTitles.match(tt.title); got != tt.want {
+			t.Errorf("researchTitles.match(%q) = %v, want %v", tt.title, got, tt.want)
+		}
+	}
+}
+
+// ドキュメントのタイトルとソースファイル名の両方があるときの優先順位
+func TestClassifyDocsPrecedence(t *testing.T) {
+	checkClassify(t, []classifyCase{
+		{"docs page", activityInput{App: "Google Chrome", Title: "Introduction - Go Docs"}, "調査・ドキュメント閲覧"},
+		{"docs host", activityInput{App: "Safari", Title: "net/http", URL: "https://pkg.go.dev/net/http"}, "調査・ドキュメント閲覧"},
+		// ブラウザで見ているソースファイル（GitHub など）はコーディング
+		{"source file in browser", activityInput{App: "Google Chrome", Title: "main.go - Go Docs"}, "プログラムの制作"},
+		{"golang blog", activityInput{App: "Google Chrome", Title: "golang.google.com"}, "Webブラウジング"},
+	})
+}
+
+func BenchmarkClassify(b *testing.B) {
+	inputs := []activityInput{
+		{App: "Visual Studio Code", Title: "● main.go — activitylog — Visual Studio Code"},
+		{App: "Google Chrome", Title: "Introduction - Go Docs", URL: "https://go.dev/doc/"},
+		{App: "Slack", Title: "#dev | Acme"},
+		{App: "Terminal", Title: "git:(main) go test ./... — zsh"},
+		{App: "Unknown App", BundleID: "com.example.unknown", Title: "Window"},
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		classify(inputs[i%len(inputs)])
+	}
+}
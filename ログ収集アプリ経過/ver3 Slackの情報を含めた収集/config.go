@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+/********** 保存先ディレクトリ **********/
+// SHIRUSIA_LOG_DIR      セッションJSONの保存先
+// SHIRUSIA_MESSAGE_DIR  Slack本文の保存先
+//   未設定なら OS ごとの既定の場所の下の {log,Message}:
+//     macOS   ~/Library/Application Support/Shirusia
+//     Windows %AppData%\Shirusia
+//     その他  $XDG_DATA_HOME/shirusia（未設定なら ~/.local/share/shirusia）
+//   "~/..." はホームディレクトリに、相対パスは起動時のカレントディレクトリ基準の絶対パスに直す。
+//   ディレクトリがなければ最初に書き込むときに作る（newJSONArrayFile / saveMessageJSON）。
+//   ホームディレクトリが分からず既定の場所を決められないときは、カレントディレクトリに書き散らさないよう
+//   エラーで終了する（環境変数で場所を指定すれば動く）。
+func loadDataDir(env, sub string) string {
+	if raw := strings.TrimSpace(os.Getenv(env)); raw != "" {
+		dir, err := expandPath(raw)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", env, err)
+			os.Exit(1)
+		}
+		return dir
+	}
+	root, err := defaultDataRoot()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cannot determine the default data directory: %v; set %s\n", err, env)
+		os.Exit(1)
+	}
+	return filepath.Join(root, sub)
+}
+
+// OS ごとの既定の保存先（上の表）
+func defaultDataRoot() (string, error) {
+	switch runtime.GOOS {
+	case "darwin", "windows":
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, "Shirusia"), nil
+	}
+	if x := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(x) { // 相対パスは XDG の仕様どおり無視する
+		return filepath.Join(x, "shirusia"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share", "shirusia"), nil
+}
+
+// "~" / "~/..." を展開し、絶対パスにする
+func expandPath(p string) (string, error) {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		p = filepath.Join(home, p[1:])
+	}
+	return filepath.Abs(p)
+}
+
+/********** ポーリング間隔 **********/
+// -interval 1s（Go の duration）で前面ウィンドウを取る間隔を変えられる。未指定なら SHIRUSIA_INTERVAL、
+// それもなければ 1.5 秒。毎回 osascript を起動するので minPollInterval より短くはできない。
+// -debounce を指定していなければ、その既定（ポーリング1回分）もこの間隔に合わせる。
+const (
+	defaultPollInterval = 1500 * time.Millisecond
+	minPollInterval     = 250 * time.Millisecond
+)
+
+func envPollInterval() time.Duration {
+	raw := strings.TrimSpace(os.Getenv("SHIRUSIA_INTERVAL"))
+	if raw == "" {
+		return defaultPollInterval
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d < minPollInterval {
+		fmt.Fprintf(os.Stderr, "warn: SHIRUSIA_INTERVAL: invalid interval %q (at least %s); using %s\n", raw, minPollInterval, defaultPollInterval)
+		return defaultPollInterval
+	}
+	return d
+}
+
+/********** アプリ別ポーリング間隔 **********/
+// SHIRUSIA_APP_INTERVALS="Spotify=30s,Visual Studio Code=1.5s"
+//   前面アプリ名（大文字小文字は無視・完全一致）ごとに、次のポーリングまでの間隔を上書きする。
+//   指定のないアプリは全体のデフォルト（pollInterval）で取得する。
+//   上書きは「そのアプリが前面にある間の次回取得までの待ち時間」にだけ効くため、
+//   長い間隔（例: Spotify=30s）を指定すると、そこから別アプリへ切り替えた検知も最大でその間隔だけ遅れる。
+func loadAppIntervals() map[string]time.Duration {
+	raw := strings.TrimSpace(os.Getenv("SHIRUSIA_APP_INTERVALS"))
+	if raw == "" {
+		return nil
+	}
+	out := map[string]time.Duration{}
+	for _, part := range strings.Split(raw, ",") {
+		name, val, ok := strings.Cut(part, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !ok || name == "" {
+			fmt.Fprintf(os.Stderr, "warn: SHIRUSIA_APP_INTERVALS: invalid entry %q\n", part)
+			continue
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(val))
+		if err != nil || d <= 0 {
+			fmt.Fprintf(os.Stderr, "warn: SHIRUSIA_APP_INTERVALS: invalid duration for %q: %q\n", name, val)
+			continue
+		}
+		out[name] = d
+	}
+	return out
+}
+
+// 現在の前面アプリに応じた次回ポーリングまでの間隔
+func nextPollDelay(app string, overrides map[string]time.Duration) time.Duration {
+	if d, ok := overrides[strings.ToLower(strings.TrimSpace(app))]; ok {
+		return d
+	}
+	return pollInterval
+}
+
+// /healthz でメインループが止まっているとみなすまでの時間。
+// いちばん長いポーリング間隔の3倍に、osascript の応答待ちぶんの余裕を足す
+func healthStaleAfter(overrides map[string]time.Duration) time.Duration {
+	longest := pollInterval
+	for _, d := range overrides {
+		if d > longest {
+			longest = d
+		}
+	}
+	return 3*longest + 30*time.Second
+}
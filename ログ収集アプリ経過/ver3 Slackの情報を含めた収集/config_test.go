@@ -0,0 +1,48 @@
+package main
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestDefaultDataRoot(t *testing.T) {
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		t.Skip("uses os.UserConfigDir")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	t.Setenv("XDG_DATA_HOME", "")
+	if got, err := defaultDataRoot(); err != nil || got != filepath.Join(home, ".local", "share", "shirusia") {
+		t.Errorf("defaultDataRoot() = %q, %v", got, err)
+	}
+	xdg := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", xdg)
+	if got, err := defaultDataRoot(); err != nil || got != filepath.Join(xdg, "shirusia") {
+		t.Errorf("with XDG_DATA_HOME: defaultDataRoot() = %q, %v", got, err)
+	}
+	// 相対パスの XDG_DATA_HOME は使わない
+	t.Setenv("XDG_DATA_HOME", "data")
+	if got, _ := defaultDataRoot(); got != filepath.Join(home, ".local", "share", "shirusia") {
+		t.Errorf("relative XDG_DATA_HOME was used: %q", got)
+	}
+	// ホームディレクトリが分からなければエラー（カレントディレクトリの相対パスにはしない）
+	t.Setenv("HOME", "")
+	if got, err := defaultDataRoot(); err == nil {
+		t.Errorf("without HOME: defaultDataRoot() = %q, want an error", got)
+	}
+}
+
+func TestLoadDataDirFromEnv(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SHIRUSIA_TEST_DIR", "~/logs")
+	if got := loadDataDir("SHIRUSIA_TEST_DIR", "log"); got != filepath.Join(home, "logs") {
+		t.Errorf("~/logs = %q", got)
+	}
+	t.Setenv("SHIRUSIA_TEST_DIR", "rel/logs")
+	if got := loadDataDir("SHIRUSIA_TEST_DIR", "log"); !filepath.IsAbs(got) {
+		t.Errorf("relative path was not made absolute: %q", got)
+	}
+}
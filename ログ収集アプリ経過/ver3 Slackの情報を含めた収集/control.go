@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+/********** 標準入力からの制御（-control-stdin） **********/
+// 親プロセスからシグナルやHTTPを使わずにロガーを操作するための、1行1JSONの簡単なプロトコル。
+// 標準入力にコマンドを1行ずつ書くと、処理した結果（ack）を標準出力に1行のJSONで返す。
+//   {"cmd":"rotate"}    今のセッションファイルを閉じて新しいファイルに切り替える
+//   {"cmd":"away"}      離席にする（POST /away と同じ）
+//   {"cmd":"back"}      離席から戻る（POST /back と同じ）
+//   {"cmd":"snapshot"}  進行中のセッションと当日のカテゴリ別合計（秒）を返す
+// "id" を付けると ack にそのまま返すので、応答の対応付けに使える。
+//   → {"id":"1","cmd":"rotate","ok":true,"file":"/…/activity_20250825_120000.json"}
+//   → {"cmd":"foo","ok":false,"error":"unknown command \"foo\""}
+// 標準出力には起動メッセージなども出るので、親プロセスは "ack":true を持つ行だけを読めばよい
+// （watch サブコマンドと併用すると、ack はセッションの JSONL と同じ出力に混ざる）。
+// 標準入力が閉じられたら制御の受け付けだけをやめ、記録は続ける。
+type controlCommand struct {
+	Cmd string `json:"cmd"`
+	ID  string `json:"id,omitempty"`
+
+	err string // 読み取り時に分かったエラー（メインループでそのまま ack にする）
+}
+
+type controlAck struct {
+	Ack     bool             `json:"ack"` // 常に true（ほかの出力と見分けるため）
+	ID      string           `json:"id,omitempty"`
+	Cmd     string           `json:"cmd"`
+	OK      bool             `json:"ok"`
+	Error   string           `json:"error,omitempty"`
+	File    string           `json:"file,omitempty"`    // rotate: 新しいセッションファイル
+	Current *session         `json:"current,omitempty"` // snapshot: 進行中のセッション（now までで切ったもの）
+	Today   map[string]int64 `json:"today,omitempty"`   // snapshot: 当日のカテゴリ別合計（秒）
+}
+
+var controlCommands = map[string]bool{"rotate": true, "away": true, "back": true, "snapshot": true}
+
+// in を1行ずつ読み、コマンドをチャネルに流す（in が閉じたらチャネルを閉じる）
+func startControlReader(in io.Reader) <-chan controlCommand {
+	ch := make(chan controlCommand, 4)
+	go runGuarded("stdin control", func() {
+		defer close(ch)
+		sc := bufio.NewScanner(in)
+		for sc.Scan() {
+			line := strings.TrimSpace(sc.Text())
+			if line == "" {
+				continue
+			}
+			var c controlCommand
+			if err := json.Unmarshal([]byte(line), &c); err != nil {
+				c = controlCommand{err: fmt.Sprintf("invalid JSON: %v", err)}
+			} else if !controlCommands[c.Cmd] {
+				c.err = fmt.Sprintf("unknown command %q (rotate, away, back, snapshot)", c.Cmd)
+			}
+			ch <- c
+		}
+	})
+	return ch
+}
+
+// ack を1行のJSONで書く（メインループからだけ呼ぶ）
+func writeControlAck(w io.Writer, a controlAck) error {
+	a.Ack = true
+	b, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// snapshot の中身を埋める
+func (a *controlAck) fillSnapshot(live *liveState, now time.Time) {
+	if s, _, ok := live.Current(now); ok {
+		a.Current = &s
+	}
+	a.Today = map[string]int64{}
+	for _, s := range live.Today(now) {
+		a.Today[s.Activity] += s.DurationSec
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+/********** 現在の状態ファイル（current.json） **********/
+// メニューバーアプリやスクリプト、Alfred/Raycast のワークフローが HTTP サーバなしで
+// 「いま何をしているか」を読めるよう、セッションが切り替わるたび（アイドル・終了を含む）に書き直す。
+// 一時ファイルに書いてから rename するので、読む側が書きかけの内容を見ることはない。
+//   -current-file で場所を変更（"" で無効）。既定はログディレクトリと同じ階層の current.json
+type currentStatus struct {
+	Running  bool   `json:"running"`
+	App      string `json:"app,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Activity string `json:"activity,omitempty"`
+	Start    string `json:"start,omitempty"` // 進行中セッションの開始（RFC3339）
+	Idle     bool   `json:"idle,omitempty"`
+	OnCall   bool   `json:"onCall,omitempty"`
+	Sharing  bool   `json:"sharing,omitempty"`
+	Updated  string `json:"updated"`
+	PID      int    `json:"pid"`
+}
+
+func defaultCurrentFile() string {
+	return filepath.Join(filepath.Dir(logDir), "current.json")
+}
+
+// r が nil なら停止中として書く
+func writeCurrentStatus(path string, r *record, start, now time.Time) error {
+	st := currentStatus{Running: r != nil, Updated: now.Format(time.RFC3339), PID: os.Getpid()}
+	if r != nil {
+		st.App = clean(r.App)
+		st.Title = clean(r.Title)
+		st.Activity = clean(r.Activity)
+		st.Start = start.Format(time.RFC3339)
+		st.Idle = r.Idle
+		st.OnCall = r.OnCall
+		st.Sharing = r.Sharing
+	}
+	b, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(b, '\n'))
+}
+
+// 同じディレクトリの一時ファイルに書いて rename する（同一ファイルシステム内なので置き換えは不可分）
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // rename 済みなら何もしない
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
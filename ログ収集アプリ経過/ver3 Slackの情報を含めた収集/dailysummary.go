@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+/********** 日次サマリファイル（summary_YYYYMMDD.json） **********/
+// 生のセッションを見返さなくても1日の内訳が分かるよう、カテゴリ別・アプリ別の合計と、
+// 長く見ていたウィンドウタイトルの上位を logDir/summary_YYYYMMDD.json に書く。
+// ロガーは日付が変わったのを見つけたら前日分を書く（-daily-summary、再生中は書かない）。
+// 過去の日はサブコマンドで作り直せる。
+//   activitylog summarize [-date 2025-09-01] [-top 10] [-o out.json] [file.json|glob ...]
+// -date の既定は昨日。ファイルを省略すると logDir（SHIRUSIA_OUTPUT=sqlite なら activity.db）から読む。
+// 日付をまたぐセッションはその日の分（0:00〜24:00 に重なる部分）だけを数える。
+const defaultSummaryTop = 10
+
+type daySummary struct {
+	Date      string           `json:"date"` // YYYY-MM-DD（ローカル）
+	TotalSec  int64            `json:"totalSec"`
+	Sessions  int              `json:"sessions"`
+	Activity  map[string]int64 `json:"activity"` // カテゴリ → 秒
+	App       map[string]int64 `json:"app"`      // アプリ → 秒
+	TopTitles []titleTime      `json:"topTitles"`
+}
+
+type titleTime struct {
+	Title       string `json:"title"`
+	App         string `json:"app"`
+	DurationSec int64  `json:"durationSec"`
+}
+
+func runSummarize(args []string) int {
+	fs := flag.NewFlagSet("summarize", flag.ExitOnError)
+	dateStr := fs.String("date", "", "day to summarize (YYYY-MM-DD, local; default: yesterday)")
+	top := fs.Int("top", defaultSummaryTop, "number of window titles to keep")
+	out := fs.String("o", "", "output path (default: summary_YYYYMMDD.json in the log directory)")
+	fs.Parse(args)
+
+	day := startOfDay(time.Now()).AddDate(0, 0, -1)
+	if *dateStr != "" {
+		d, err := time.ParseInLocation("2006-01-02", *dateStr, time.Local)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "summarize: invalid -date %q (want YYYY-MM-DD)\n", *dateStr)
+			return 2
+		}
+		day = d
+	}
+	var ss []session
+	var err error
+	if fs.NArg() > 0 {
+		ss, err = readSessionsGlobs(fs.Args())
+	} else {
+		ss, err = sessionsForDay(logDir, day)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "summarize: %v\n", err)
+		return 1
+	}
+	sum := summarizeDay(ss, day, *top)
+	if *out == "" {
+		*out = dailySummaryPath(logDir, day)
+	}
+	if err := writeDailySummary(*out, sum); err != nil {
+		fmt.Fprintf(os.Stderr, "summarize: %v\n", err)
+		return 1
+	}
+	fmt.Printf("%s: %d sessions, %s → %s\n", sum.Date, sum.Sessions, fmtDur(sum.TotalSec), *out)
+	return 0
+}
+
+// day（その日の 0:00）と重なるセッションを集計する
+func summarizeDay(ss []session, day time.Time, top int) daySummary {
+	from, to := day, day.AddDate(0, 0, 1)
+	sum := daySummary{Date: from.Format("2006-01-02"), Activity: map[string]int64{}, App: map[string]int64{}, TopTitles: []titleTime{}}
+	titles := map[[2]string]int64{} // {app, title} → 秒
+	for _, s := range ss {
+		sec := overlapSec(s, from, to)
+		if sec <= 0 {
+			continue
+		}
+		sum.Sessions++
+		sum.TotalSec += sec
+		sum.Activity[s.Activity] += sec
+		sum.App[s.App] += sec
+		if s.Title != "" {
+			titles[[2]string{s.App, s.Title}] += sec
+		}
+	}
+	for k, sec := range titles {
+		sum.TopTitles = append(sum.TopTitles, titleTime{App: k[0], Title: k[1], DurationSec: sec})
+	}
+	sort.Slice(sum.TopTitles, func(i, j int) bool {
+		a, b := sum.TopTitles[i], sum.TopTitles[j]
+		if a.DurationSec != b.DurationSec {
+			return a.DurationSec > b.DurationSec
+		}
+		return a.Title < b.Title
+	})
+	if top >= 0 && len(sum.TopTitles) > top {
+		sum.TopTitles = sum.TopTitles[:top]
+	}
+	return sum
+}
+
+// セッションのうち [from, to) に重なる秒数。end が読めなければ start に durationSec を足す
+func overlapSec(s session, from, to time.Time) int64 {
+	start, err := time.Parse(time.RFC3339, s.Start)
+	if err != nil {
+		return 0
+	}
+	end, err := time.Parse(time.RFC3339, s.End)
+	if err != nil {
+		end = start.Add(time.Duration(s.DurationSec) * time.Second)
+	}
+	if start.Before(from) {
+		start = from
+	}
+	if end.After(to) {
+		end = to
+	}
+	if !end.After(start) {
+		return 0
+	}
+	return int64(end.Sub(start).Round(time.Second) / time.Second)
+}
+
+// day に重なりうるセッション
+func sessionsForDay(dir string, day time.Time) ([]session, error) {
+	return readLoggedSessions(dir, day, day.AddDate(0, 0, 1))
+}
+
+func readSessionsGlobs(globs []string) ([]session, error) {
+	files, err := reportInputFiles(globs, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	return readSessionsPaths(files), nil
+}
+
+func dailySummaryPath(dir string, day time.Time) string {
+	return filepath.Join(dir, "summary_"+day.Format("20060102")+".json")
+}
+
+func writeDailySummary(path string, sum daySummary) error {
+	b, err := json.MarshalIndent(sum, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(b, '\n'))
+}
+
+// 日付が変わったときにメインループから呼ぶ
+func writeDailySummaryOnRollover(dir string, day time.Time, top int) {
+	ss, err := sessionsForDay(dir, day)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "daily summary: %v\n", err)
+		return
+	}
+	path := dailySummaryPath(dir, day)
+	if err := writeDailySummary(path, summarizeDay(ss, day, top)); err != nil {
+		fmt.Fprintf(os.Stderr, "daily summary: %v\n", err)
+		return
+	}
+	fmt.Printf("Wrote daily summary: %s\n", path)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSummarizeDayAcrossCategorySwitch(t *testing.T) {
+	ss := []session{
+		// 前日から続いていたセッションは 0:00 以降の分だけ
+		{Start: "2025-08-31T23:50:00+09:00", End: "2025-09-01T00:20:00+09:00", App: "Visual Studio Code", Title: "main.go", Activity: "プログラムの制作", DurationSec: 1800},
+		{Start: "2025-09-01T09:00:00+09:00", End: "2025-09-01T09:45:00+09:00", App: "Visual Studio Code", Title: "main.go", Activity: "プログラムの制作", DurationSec: 2700},
+		// カテゴリが切り替わり、また戻る
+		{Start: "2025-09-01T09:45:00+09:00", End: "2025-09-01T10:00:00+09:00", App: "Slack", Title: "#dev", Activity: "コミュニケーション", DurationSec: 900},
+		{Start: "2025-09-01T10:00:00+09:00", End: "2025-09-01T10:30:00+09:00", App: "Visual Studio Code", Title: "main.go", Activity: "プログラムの制作", DurationSec: 1800},
+		// 翌日にまたがるセッションは 24:00 までの分だけ
+		{Start: "2025-09-01T23:50:00+09:00", End: "2025-09-02T00:10:00+09:00", App: "Google Chrome", Title: "News", Activity: "Webブラウジング", DurationSec: 1200},
+		// 別の日
+		{Start: "2025-09-02T09:00:00+09:00", End: "2025-09-02T09:30:00+09:00", App: "Slack", Title: "#dev", Activity: "コミュニケーション", DurationSec: 1800},
+	}
+	day := time.Date(2025, 9, 1, 0, 0, 0, 0, jst)
+	sum := summarizeDay(ss, day, 2)
+
+	if sum.Date != "2025-09-01" || sum.Sessions != 5 || sum.TotalSec != 7200 {
+		t.Errorf("date/sessions/total = %s/%d/%d, want 2025-09-01/5/7200", sum.Date, sum.Sessions, sum.TotalSec)
+	}
+	wantActivity := map[string]int64{"プログラムの制作": 5700, "コミュニケーション": 900, "Webブラウジング": 600}
+	for k, v := range wantActivity {
+		if sum.Activity[k] != v {
+			t.Errorf("activity[%s] = %d, want %d", k, sum.Activity[k], v)
+		}
+	}
+	if len(sum.Activity) != len(wantActivity) {
+		t.Errorf("activity = %v", sum.Activity)
+	}
+	if sum.App["Visual Studio Code"] != 5700 || sum.App["Slack"] != 900 {
+		t.Errorf("app = %v", sum.App)
+	}
+	if len(sum.TopTitles) != 2 || sum.TopTitles[0] != (titleTime{Title: "main.go", App: "Visual Studio Code", DurationSec: 5700}) ||
+		sum.TopTitles[1].Title != "#dev" {
+		t.Errorf("topTitles = %+v", sum.TopTitles)
+	}
+
+	path := filepath.Join(t.TempDir(), "summary_20250901.json")
+	if err := writeDailySummary(path, sum); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back daySummary
+	if err := json.Unmarshal(b, &back); err != nil || back.TotalSec != 7200 || back.Activity["コミュニケーション"] != 900 {
+		t.Errorf("written summary = %+v, %v", back, err)
+	}
+}
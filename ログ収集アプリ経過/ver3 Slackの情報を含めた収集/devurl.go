@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/********** ローカル開発URLの判定 **********/
+// ブラウザで localhost:3000 などを見ているのはほぼ開発中なので「プログラムの制作」とみなす。
+// URLが取れるときはホスト/ポートを net/url で解析し、取れないときはタブタイトルが
+// "localhost:8080" のようにホスト名そのものの場合だけ判定する。
+//   SHIRUSIA_DEV_HOSTS="myapp.internal,192.168.0.10"  追加の開発用ホスト
+//   SHIRUSIA_DEV_PORTS="3000,8080"                     開発用ポート（既定 defaultDevPorts を置き換え）
+var defaultDevPorts = []int{3000, 3001, 4000, 4200, 5000, 5173, 5174, 8000, 8080, 8081, 8888, 1313, 9000}
+
+var (
+	devHosts = append([]string{"localhost", "127.0.0.1", "::1", "0.0.0.0"}, splitList(os.Getenv("SHIRUSIA_DEV_HOSTS"))...)
+	devPorts = loadDevPorts()
+)
+
+func loadDevPorts() map[string]bool {
+	ports := defaultDevPorts
+	if raw := splitList(os.Getenv("SHIRUSIA_DEV_PORTS")); len(raw) > 0 {
+		ports = nil
+		for _, p := range raw {
+			n, err := strconv.Atoi(p)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "warn: SHIRUSIA_DEV_PORTS: invalid port %q\n", p)
+				continue
+			}
+			ports = append(ports, n)
+		}
+	}
+	m := map[string]bool{}
+	for _, p := range ports {
+		m[strconv.Itoa(p)] = true
+	}
+	return m
+}
+
+func isDevURL(in activityInput) bool {
+	raw := strings.TrimSpace(in.URL)
+	if raw == "" {
+		// URLが取れないとき: タイトルが "localhost:3000" / "127.0.0.1:8080/..." の形なら同じ扱い
+		t := strings.ToLower(strings.TrimSpace(in.Title))
+		if t == "" || strings.ContainsAny(t, " \t") {
+			return false
+		}
+		raw = "http://" + t
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	return isDevHost(u.Hostname(), u.Port())
+}
+
+func isDevHost(host, port string) bool {
+	host = strings.ToLower(host)
+	for _, h := range devHosts {
+		if host == h {
+			return true
+		}
+	}
+	if strings.HasSuffix(host, ".localhost") || strings.HasSuffix(host, ".test") {
+		return true
+	}
+	// プライベートIPの開発用ポート（同一LAN内の検証機など）
+	if ip := net.ParseIP(host); ip != nil && (ip.IsPrivate() || ip.IsLoopback()) && devPorts[port] {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func TestClassifyDevURL(t *testing.T) {
+	checkClassify(t, []classifyCase{
+		{"localhost:8080", activityInput{App: "Google Chrome", Title: "My App", URL: "http://localhost:8080/"}, "プログラムの制作"},
+		{"127.0.0.1:3000", activityInput{App: "Safari", Title: "Dashboard", URL: "http://127.0.0.1:3000/admin"}, "プログラムの制作"},
+		{"ipv6 loopback", activityInput{App: "Firefox", Title: "API", URL: "http://[::1]:5173/"}, "プログラムの制作"},
+		{"*.localhost", activityInput{App: "Arc", Title: "Store", URL: "https://shop.localhost/"}, "プログラムの制作"},
+		{"lan dev port", activityInput{App: "Google Chrome", Title: "Preview", URL: "http://192.168.0.20:8080/"}, "プログラムの制作"},
+		{"title only", activityInput{App: "Google Chrome", Title: "localhost:8080"}, "プログラムの制作"},
+		// 開発用でないもの
+		{"router admin", activityInput{App: "Google Chrome", Title: "Router", URL: "http://192.168.0.1/"}, "Webブラウジング"},
+		{"public site on 8080", activityInput{App: "Google Chrome", Title: "Example", URL: "http://example.com:8080/"}, "Webブラウジング"},
+		{"title mentioning localhost", activityInput{App: "Safari", Title: "How to open localhost:8080 on a phone"}, "Webブラウジング"},
+	})
+}
+
+func TestIsDevURLPorts(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"http://localhost:8080/", true},
+		{"http://localhost/", true},
+		{"http://10.0.0.5:3000/", true},
+		{"http://10.0.0.5:443/", false},
+		{"http://8.8.8.8:8080/", false},
+		{"not a url", false},
+	}
+	for _, tt := range tests {
+		if got := isDevURL(activityInput{URL: tt.url}); got != tt.want {
+			t.Errorf("isDevURL(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+/********** セッションが終わった理由（-end-reason） **********/
+// 意図的な作業の切り替えと、アイドルや終了による中断を区別できるよう、session.endReason に入れる。
+//   app      : 前面アプリが変わった
+//   title    : 同じアプリでタイトル（タブ・ファイル）が変わった
+//   category : アプリ・タイトルは同じで分類だけが変わった（時間帯による上書きなど）
+//   call     : 通話の開始/終了（-detect-calls）
+//   sharing  : 画面収録・画面共有の開始/終了（-detect-sharing）
+//   trigger  : タイトルトリガーの一致/解除（SHIRUSIA_TITLE_TRIGGERS）
+//   idle     : 無操作になった
+//   resume   : アイドル期間が入力の再開で終わった
+//   lock     : 画面がロックされた（macOS のみ。sleep.go）
+//   sleep    : スリープなどでポーリングが長く止まっていた（sleep.go）
+//   away     : 手動で離席にした（POST /away など）
+//   back     : 離席から戻った
+//   self     : ロガー自身の画面に切り替わった（SHIRUSIA_SELF_WINDOWS=exclude）
+//   private  : シークレットウィンドウに切り替わった（SHIRUSIA_PRIVATE_WINDOWS=skip）
+//   shutdown : ロガーの終了
+//   panic    : パニックによる異常終了（後始末で書き出せた場合）
+//   replay-end : 再生の終わり（-replay）
+//   midnight : 0:00 で日付ごとに分けた（同じ内容のセッションが 0:00 から続く。midnight.go）
+const (
+	endApp       = "app"
+	endTitle     = "title"
+	endCategory  = "category"
+	endCall      = "call"
+	endSharing   = "sharing"
+	endTrigger   = "trigger"
+	endIdle      = "idle"
+	endResume    = "resume"
+	endLock      = "lock"
+	endSleep     = "sleep"
+	endSelf      = "self"
+	endPrivate   = "private"
+	endAway      = "away"
+	endBack      = "back"
+	endShutdown  = "shutdown"
+	endPanic     = "panic"
+	endReplayEnd = "replay-end"
+	endMidnight  = "midnight"
+)
+
+// true なら endReason を書く（-end-reason）
+var recordEndReason bool
+
+// switchTo の note（表示用）と前後のレコードから終了理由を決める
+func endReasonFor(prev, next *record, note string) string {
+	switch note {
+	case "idle":
+		return endIdle
+	case "on exit":
+		return endShutdown
+	case "replay end":
+		return endReplayEnd
+	case "lock":
+		return endLock
+	case "sleep":
+		return endSleep
+	case "self":
+		return endSelf
+	case "private":
+		return endPrivate
+	case "away":
+		return endAway
+	case "back":
+		return endBack
+	case "panic":
+		return endPanic
+	case "midnight":
+		return endMidnight
+	}
+	switch {
+	case prev == nil || next == nil:
+		return ""
+	case prev.Idle:
+		return endResume
+	case prev.Trigger != next.Trigger:
+		return endTrigger
+	case prev.OnCall != next.OnCall:
+		return endCall
+	case prev.Sharing != next.Sharing:
+		return endSharing
+	case prev.App != next.App:
+		return endApp
+	case prev.Title != next.Title:
+		return endTitle
+	}
+	return endCategory
+}
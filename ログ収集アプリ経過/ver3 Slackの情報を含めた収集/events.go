@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+/********** セッションの開始・終了イベント（GET /events、SSE） **********/
+// メインループがセッションを始めた・閉じたときに、標準出力の start / end 行と同じ内容を
+// Server-Sent Events で流す。ポーリングせずに今の作業を追いたいウィジェットなど用。
+//   event: start
+//   data: {"type":"start","time":"2025-09-01T10:00:00+09:00","activity":"プログラムの制作","app":"Visual Studio Code","title":"main.go"}
+// 接続ごとにバッファ付きのチャネルを持ち、送れないほど遅れた接続は切る（ポーリングは待たせない）。
+// 切られた側は EventSource が自動でつなぎ直す。プロキシに切られないよう eventsHeartbeat ごとにコメント行を送る。
+// -private のときはアプリ名・タイトルを入れない。
+const (
+	eventsHeartbeat = 15 * time.Second
+	eventsBuffer    = 16 // 接続ごとに溜められるイベント数。あふれたら切る
+)
+
+type sessionEvent struct {
+	Type        string `json:"type"` // "start" / "end"
+	Time        string `json:"time"` // RFC3339
+	Activity    string `json:"activity"`
+	App         string `json:"app,omitempty"`
+	Title       string `json:"title,omitempty"`
+	DurationSec int64  `json:"durationSec,omitempty"` // end のみ
+	Note        string `json:"note,omitempty"`        // end の注記（"on exit" "idle" など）
+}
+
+type eventHub struct {
+	mu      sync.Mutex
+	clients map[chan []byte]struct{}
+	closed  bool
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{clients: map[chan []byte]struct{}{}}
+}
+
+// 接続を登録する。チャネルは遅れて切られたときと Close のときに閉じる
+func (h *eventHub) Subscribe() (<-chan []byte, func()) {
+	ch := make(chan []byte, eventsBuffer)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	h.clients[ch] = struct{}{}
+	return ch, func() { h.drop(ch) }
+}
+
+func (h *eventHub) drop(ch chan []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.clients[ch]; ok {
+		delete(h.clients, ch)
+		close(ch)
+	}
+}
+
+// 全接続へ送る。待たずに送れない接続は切る
+func (h *eventHub) Publish(ev sessionEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.clients) == 0 {
+		return
+	}
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	frame := []byte(fmt.Sprintf("event: %s\ndata: %s\n\n", ev.Type, b))
+	for ch := range h.clients {
+		select {
+		case ch <- frame:
+		default:
+			delete(h.clients, ch)
+			close(ch)
+		}
+	}
+}
+
+// 全接続を終わらせる（HTTPサーバの Shutdown が流し続けている接続を待たないように）
+func (h *eventHub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for ch := range h.clients {
+		delete(h.clients, ch)
+		close(ch)
+	}
+}
+
+func eventsHandler(h *eventHub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fl, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+		ch, cancel := h.Subscribe()
+		defer cancel()
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no") // nginx にバッファさせない
+		fmt.Fprint(w, ": connected\n\n")
+		fl.Flush()
+
+		hb := time.NewTicker(eventsHeartbeat)
+		defer hb.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case frame, ok := <-ch:
+				if !ok {
+					return
+				}
+				if _, err := w.Write(frame); err != nil {
+					return
+				}
+				fl.Flush()
+			case <-hb.C:
+				if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+					return
+				}
+				fl.Flush()
+			}
+		}
+	}
+}
+
+// 標準出力の start 行と同じ内容のイベント
+func startEvent(r *record, at time.Time, private bool) sessionEvent {
+	ev := sessionEvent{Type: "start", Time: at.Format(time.RFC3339), Activity: r.Activity}
+	if !private {
+		ev.App, ev.Title = r.App, clean(r.Title)
+	}
+	return ev
+}
+
+// 標準出力の end 行と同じ内容のイベント
+func endEvent(s session, end time.Time, note string) sessionEvent {
+	return sessionEvent{Type: "end", Time: end.Format(time.RFC3339), Activity: s.Activity, DurationSec: s.DurationSec, Note: note}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// GET /events につないだクライアントに、発行したイベントが SSE の1件として届く
+func TestEventsStream(t *testing.T) {
+	st := newLiveState(time.Minute)
+	srv := httptest.NewServer(newHTTPServer("", st).Handler)
+	defer srv.Close()
+	defer st.events.Close() // 流し続けている接続を先に終わらせる
+
+	resp, err := http.Get(srv.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	lines := make(chan string)
+	go func() {
+		sc := bufio.NewScanner(resp.Body)
+		for sc.Scan() {
+			lines <- sc.Text()
+		}
+		close(lines)
+	}()
+	next := func() string {
+		t.Helper()
+		select {
+		case l, ok := <-lines:
+			if !ok {
+				t.Fatal("stream closed")
+			}
+			return l
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the stream")
+		}
+		return ""
+	}
+
+	// ": connected" が届いた時点で購読済み
+	if l := next(); l != ": connected" {
+		t.Fatalf("first line = %q, want \": connected\"", l)
+	}
+	next()
+
+	at := time.Date(2025, 9, 1, 10, 0, 0, 0, jst)
+	st.events.Publish(startEvent(&record{App: "Visual Studio Code", Title: "main.go", Activity: "プログラムの制作"}, at, false))
+
+	if l := next(); l != "event: start" {
+		t.Fatalf("event line = %q, want \"event: start\"", l)
+	}
+	data, ok := strings.CutPrefix(next(), "data: ")
+	if !ok {
+		t.Fatalf("missing data line")
+	}
+	var ev sessionEvent
+	if err := json.Unmarshal([]byte(data), &ev); err != nil {
+		t.Fatalf("data %q: %v", data, err)
+	}
+	want := sessionEvent{Type: "start", Time: "2025-09-01T10:00:00+09:00", Activity: "プログラムの制作", App: "Visual Studio Code", Title: "main.go"}
+	if ev != want {
+		t.Errorf("event = %+v, want %+v", ev, want)
+	}
+
+	// Close でストリームが終わる（Shutdown が待たされない）
+	st.events.Close()
+	for {
+		select {
+		case _, ok := <-lines:
+			if !ok {
+				return
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("stream still open after Close")
+		}
+	}
+}
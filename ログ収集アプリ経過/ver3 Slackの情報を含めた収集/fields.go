@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+/********** セッション出力フィールドの絞り込み（-fields） **********/
+// 例: -fields start,durationSec,app,activity
+//   プライバシーのために title を落とす、次の start と重複する end を落とす、など。
+//   start と durationSec は集計に必須なので省略できない。未指定なら全フィールドを出力する。
+var requiredSessionFields = []string{"start", "durationSec"}
+
+// nil のときは絞り込みなし（全フィールド）
+var sessionFieldSet map[string]bool
+
+// true なら durationHuman も書く（-human-durations）
+var humanDurations bool
+
+// session のJSONフィールド名（定義順）
+func sessionFieldNames() []string {
+	t := reflect.TypeOf(session{})
+	names := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		names = append(names, name)
+	}
+	return names
+}
+
+// カンマ区切りの指定を検証して出力フィールド集合にする
+func parseSessionFields(spec string) (map[string]bool, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	known := map[string]bool{}
+	for _, n := range sessionFieldNames() {
+		known[n] = true
+	}
+	set := map[string]bool{}
+	for _, f := range strings.Split(spec, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		if !known[f] {
+			return nil, fmt.Errorf("unknown session field %q (available: %s)", f, strings.Join(sessionFieldNames(), ", "))
+		}
+		set[f] = true
+	}
+	for _, r := range requiredSessionFields {
+		if !set[r] {
+			return nil, fmt.Errorf("session field %q is required and cannot be omitted", r)
+		}
+	}
+	return set, nil
+}
+
+// sessionFieldSet に含まれるフィールドだけを定義順に書き出す
+func (s session) MarshalJSON() ([]byte, error) {
+	type plain session // MarshalJSON を持たない別名（再帰防止）
+	if sessionFieldSet == nil {
+		return json.Marshal(plain(s))
+	}
+	v := reflect.ValueOf(s)
+	t := v.Type()
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	first := true
+	for i := 0; i < t.NumField(); i++ {
+		name, opts, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if !sessionFieldSet[name] {
+			continue
+		}
+		fv := v.Field(i)
+		if strings.Contains(opts, "omitempty") && fv.IsZero() {
+			continue
+		}
+		b, err := json.Marshal(fv.Interface())
+		if err != nil {
+			return nil, err
+		}
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		fmt.Fprintf(&buf, "%q:", name)
+		buf.Write(b)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+/********** Firefox のタブタイトルとURL **********/
+// Firefox は Safari / Chromium 系のように AppleScript で active tab を返さないので、
+// System Events の GUI スクリプティングでツールバーのアドレスバー（AXTextField / AXComboBox）の
+// 値と、前面ウィンドウのタイトルを読む。補助アクセス（アクセシビリティ）の許可が必要。
+// URL は Safari / Chromium 系と同じく sanitizeURL（urlscrub.go）で整形してから分類とセッションの url に使う。
+//
+// アドレスバーを編集中（フォーカスがある）・空・URLとして読めない値のときは errFirefoxNoURL を返し、
+// 呼び出し側は従来のウインドウタイトルに戻る。
+var errFirefoxNoURL = errors.New("firefox: address bar is empty or being edited")
+
+func isFirefox(appLower string) bool {
+	return strings.Contains(appLower, "firefox")
+}
+
+// 1行目: アドレスバーにフォーカスがあれば "focused"、2行目: ウィンドウタイトル、3行目: アドレスバーの値
+func firefoxFrontTab(app string) (title, pageURL string, err error) {
+	script := fmt.Sprintf(`
+		tell application "System Events"
+			tell process "%s"
+				if (count of windows) = 0 then return ""
+				set w to front window
+				set t to name of w
+				set tbs to {}
+				try
+					set tbs to tbs & (every toolbar of w)
+				end try
+				try
+					set tbs to tbs & (every toolbar of group 1 of w)
+				end try
+				repeat with tb in tbs
+					repeat with e in (entire contents of tb)
+						try
+							set r to role of e
+							if r is "AXTextField" or r is "AXComboBox" then
+								set f to ""
+								try
+									if focused of e then set f to "focused"
+								end try
+								return f & linefeed & t & linefeed & (value of e)
+							end if
+						end try
+					end repeat
+				end repeat
+				return linefeed & t & linefeed
+			end tell
+		end tell
+	`, escapeOSA(app))
+	out, err := runOSA(script)
+	if err != nil {
+		return "", "", err
+	}
+	parts := strings.SplitN(strings.TrimRight(out, "\n"), "\n", 3)
+	if len(parts) < 3 || strings.TrimSpace(parts[0]) == "focused" {
+		return "", "", errFirefoxNoURL
+	}
+	u, ok := firefoxAddressURL(parts[2])
+	if !ok {
+		return "", "", errFirefoxNoURL
+	}
+	return strings.TrimSpace(parts[1]), u, nil
+}
+
+// アドレスバーの表示値をURLにする。Firefox は既定で "https://" を省いて表示するので、
+// スキームがなければ補う。検索語など URL として読めないものは ok=false
+func firefoxAddressURL(v string) (string, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" || strings.ContainsAny(v, " \t") {
+		return "", false
+	}
+	if !strings.Contains(v, "://") && !strings.HasPrefix(v, "about:") {
+		v = "https://" + v // "example.com/path" や "localhost:3000"
+	}
+	u, err := url.Parse(v)
+	if err != nil || u.Scheme == "" {
+		return "", false
+	}
+	if u.Scheme == "about" || u.Scheme == "file" {
+		return v, true
+	}
+	// 検索語を残す設定では1語の検索語がそのまま表示されるので、ホストらしいものだけを受け付ける
+	if h := u.Hostname(); !strings.ContainsAny(h, ".:") && h != "localhost" {
+		return "", false
+	}
+	return v, true
+}
@@ -0,0 +1,38 @@
+package main
+
+import "time"
+
+/********** タイトルのちらつきのデバウンス（-debounce） **********/
+// フォーカスが移る瞬間などに、一瞬だけ空や途中のタイトルを返すアプリがある。そのたびに区切ると、
+// 直前とほとんど同じセッションが1ティックおきにできてしまう。変化を見つけても -debounce の間は
+// 区切らずに保留し、次のポーリングでも同じ状態が続いていたら初めて区切る（開始時刻はその状態を
+// 最初に見た時刻）。その間に元のアプリ・タイトル・カテゴリに戻れば変化はなかったことにして、
+// 今のセッションをそのまま延ばす。既定はポーリング1回分（pollInterval）。
+// 保留中に別の状態に変わったら、その状態を最初から見直す（通り過ぎた状態の時間は今のセッションに含める）。
+// -switch-grace・-tab-dwell より先に効き、それらの保留はこの確認が済んだ状態に対して始まる。
+type flickerDebouncer struct {
+	wait    time.Duration
+	pending *record   // 確定待ちの状態
+	since   time.Time // pending を最初に見た時刻
+}
+
+// cur を区切りとしてまだ認めないなら hold=true。認めるなら、その状態を最初に見た時刻を返す。
+// 区切りが確定するまで（switchTo の Reset まで）は同じ状態が続く限り毎回 hold=false を返す
+func (d *flickerDebouncer) Observe(mode trackMode, prev, cur *record, now time.Time) (since time.Time, hold bool) {
+	if d.wait <= 0 || prev == nil || prev.Idle {
+		d.Reset()
+		return now, false
+	}
+	if d.pending == nil || sessionChanged(mode, d.pending, cur) {
+		d.pending, d.since = cur, now
+	}
+	if now.Sub(d.since) < d.wait {
+		return time.Time{}, true
+	}
+	return d.since, false
+}
+
+// 元の状態に戻った、またはセッションが切り替わったので保留を捨てる
+func (d *flickerDebouncer) Reset() {
+	d.pending = nil
+}
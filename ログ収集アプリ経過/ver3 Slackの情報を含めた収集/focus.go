@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+/********** report -focus（集中/気晴らしの比率） **********/
+// カテゴリを「集中」「気晴らし」に分け、日ごとに
+//   - 集中時間 / 気晴らし時間 とその比率
+//   - 最長の集中の連続（集中カテゴリのセッションが途切れずに続いた時間）
+//   - カテゴリの切り替え回数（前のセッションとカテゴリが違った回数）
+// を出す。どちらにも入らないカテゴリ（コミュニケーション等）は比率に含めない。
+// アイドル・休憩や focusStreakGap より長い空白は連続を途切れさせる。
+// アプリ・タイトル単位の指定（SHIRUSIA_FOCUS_RULES）があればカテゴリより優先する（focusrules.go）。
+var (
+	defaultFocusCategories = []string{
+		"プログラムの制作", "デザイン作業", "ドキュメント編集", "表計算・データ整理",
+		"プレゼン資料作成", "調査・ドキュメント閲覧", "インフラ・運用", "読書・資料閲覧", pkmSplitActivity,
+		terminalActivity,
+	}
+	defaultDistractionCategories = []string{"Webブラウジング", "メディア視聴・再生", "ゲーム"}
+)
+
+const focusStreakGap = 2 * time.Minute
+
+type focusDay struct {
+	Date        string
+	FocusSec    int64
+	DistractSec int64
+	StreakSec   int64 // 最長の集中の連続
+	StreakStart time.Time
+	Switches    int
+}
+
+func aggregateFocus(ss []session, focus, distraction map[string]bool) []focusDay {
+	type timed struct {
+		s          session
+		kind       focusKind
+		start, end time.Time
+	}
+	var ts []timed
+	for _, s := range ss {
+		start, err1 := time.Parse(time.RFC3339, s.Start)
+		end, err2 := time.Parse(time.RFC3339, s.End)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		ts = append(ts, timed{s: s, kind: classifyFocus(s, focus, distraction), start: start.Local(), end: end.Local()})
+	}
+	sort.SliceStable(ts, func(i, j int) bool { return ts[i].start.Before(ts[j].start) })
+
+	days := map[string]*focusDay{}
+	var order []string
+	var prev *timed
+	var streak int64
+	var streakStart time.Time
+	for i := range ts {
+		t := &ts[i]
+		date := t.start.Format("2006-01-02")
+		d := days[date]
+		if d == nil {
+			d = &focusDay{Date: date}
+			days[date] = d
+			order = append(order, date)
+		}
+		sameDay := prev != nil && prev.start.Format("2006-01-02") == date
+		contiguous := sameDay && t.start.Sub(prev.end) <= focusStreakGap
+		if sameDay && prev.s.Activity != t.s.Activity && t.s.App != idleApp && prev.s.App != idleApp {
+			d.Switches++
+		}
+
+		switch t.kind {
+		case focusProductive:
+			d.FocusSec += t.s.DurationSec
+			if !contiguous || prev.kind != focusProductive {
+				streak, streakStart = 0, t.start
+			}
+			streak += t.s.DurationSec
+			if streak > d.StreakSec {
+				d.StreakSec, d.StreakStart = streak, streakStart
+			}
+		case focusDistracting:
+			d.DistractSec += t.s.DurationSec
+			streak = 0
+		default:
+			streak = 0
+		}
+		prev = t
+	}
+
+	out := make([]focusDay, 0, len(order))
+	for _, date := range order {
+		out = append(out, *days[date])
+	}
+	return out
+}
+
+func printFocus(w io.Writer, days []focusDay) {
+	fmt.Fprintf(w, "  %s %s %s %s %s %s\n", padRight("日付", 10), padLeft("集中", 10), padLeft("気晴らし", 10),
+		padLeft("比率", 7), padLeft("最長連続", 10), padLeft("切替", 6))
+	var focusSum, distractSum int64
+	for _, d := range days {
+		focusSum += d.FocusSec
+		distractSum += d.DistractSec
+		streakAt := ""
+		if d.StreakSec > 0 {
+			streakAt = "（" + d.StreakStart.Format("15:04") + "〜）"
+		}
+		fmt.Fprintf(w, "  %-10s %10s %10s %7s %10s %6d  %s\n",
+			d.Date, fmtDur(d.FocusSec), fmtDur(d.DistractSec), focusRatio(d.FocusSec, d.DistractSec),
+			fmtDur(d.StreakSec), d.Switches, streakAt)
+	}
+	if len(days) > 1 {
+		fmt.Fprintf(w, "\n  合計: 集中 %s / 気晴らし %s（比率 %s）\n",
+			fmtDur(focusSum), fmtDur(distractSum), focusRatio(focusSum, distractSum))
+	}
+}
+
+// 集中 / 気晴らし（気晴らしが0なら "∞"、どちらも0なら "-"）
+func focusRatio(focus, distract int64) string {
+	switch {
+	case distract == 0 && focus == 0:
+		return "-"
+	case distract == 0:
+		return "∞"
+	}
+	return fmt.Sprintf("%.2f", float64(focus)/float64(distract))
+}
+
+func categorySet(list []string) map[string]bool {
+	m := map[string]bool{}
+	for _, c := range list {
+		m[c] = true
+	}
+	return m
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+/********** 前面アプリ切り替えイベント（-focus-events、macOS） **********/
+// 固定間隔のポーリングだけだと、間隔より短い切り替えを取りこぼし、何も変わらない間も osascript を起動し続ける。
+// -focus-events を付けると、常駐させた osascript（JXA）で NSWorkspace の
+// didActivateApplicationNotification を購読し、アプリが切り替わった瞬間に取得し直す。
+// タイトルの変化は通知されないので、ポーリングは focusEventFallback 間隔の粗い補助として残す。
+// 監視プロセスが終了したら警告を出し、通常のポーリング間隔に戻す。
+const focusEventFallback = 5 * time.Second
+
+const focusObserverJXA = `
+ObjC.import('AppKit');
+ObjC.registerSubclass({
+	name: 'ShirusiaFocusObserver',
+	methods: {
+		'appActivated:': {
+			types: ['void', ['id']],
+			implementation: function (n) {
+				var app = n.userInfo.objectForKey('NSWorkspaceApplicationKey');
+				var line = ObjC.unwrap(app.localizedName) + '\n';
+				$.NSFileHandle.fileHandleWithStandardOutput.writeData($(line).dataUsingEncoding($.NSUTF8StringEncoding));
+			}
+		}
+	}
+});
+var observer = $.ShirusiaFocusObserver.alloc.init;
+$.NSWorkspace.sharedWorkspace.notificationCenter.addObserverSelectorNameObject(
+	observer, 'appActivated:', 'NSWorkspaceDidActivateApplicationNotification', $());
+$.NSRunLoop.currentRunLoop.run;
+`
+
+type focusObserver struct {
+	cmd    *exec.Cmd
+	Events chan string // 切り替わった先のアプリ名。監視が終わると close される
+}
+
+func startFocusObserver() (*focusObserver, error) {
+	cmd := exec.Command("osascript", "-l", "JavaScript", "-e", focusObserverJXA)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start focus observer: %w", err)
+	}
+	osaSpawns.Add(1)
+	o := &focusObserver{cmd: cmd, Events: make(chan string, 16)}
+	go runGuarded("focus observer", func() {
+		defer close(o.Events)
+		sc := bufio.NewScanner(out)
+		for sc.Scan() {
+			select {
+			case o.Events <- strings.TrimSpace(sc.Text()):
+			default: // メインループが取得中なら、次のティックで拾えるので捨ててよい
+			}
+		}
+		if err := cmd.Wait(); err != nil {
+			fmt.Fprintf(os.Stderr, "warn: focus observer exited: %v (falling back to polling)\n", err)
+		}
+	})
+	return o, nil
+}
+
+func (o *focusObserver) Stop() {
+	if o.cmd.Process != nil {
+		o.cmd.Process.Kill()
+	}
+}
+
+// イベントで切り替えを拾える間は、ポーリングはタイトル変化用の粗い間隔でよい
+func pollDelayWithEvents(d time.Duration, events bool) time.Duration {
+	if events && d < focusEventFallback {
+		return focusEventFallback
+	}
+	return d
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+/********** フォーカス中のUI要素の役割（-focused-role、任意） **********/
+// 同じアプリ・タイトルの中でも「テキスト欄に入力している（AXTextArea/AXTextField）」のか
+// 「Webページを読んでいる（AXWebArea）」のかを区別するため、フォーカス中要素の AXRole を取る。
+// 毎ティック追加のAX問い合わせが走るので既定はオフ。
+// セッション中に観測した中で最も多かった役割を meta.focusedRole に入れる。
+func focusedRole() string {
+	out, err := runOSA(`
+		tell application "System Events"
+			try
+				set p to first process whose frontmost is true
+				set el to value of attribute "AXFocusedUIElement" of p
+				return value of attribute "AXRole" of el
+			on error
+				return ""
+			end try
+		end tell
+	`)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(out)
+}
+
+// 観測した役割を1回分数える
+func (r *record) noteRole(role string) {
+	if role == "" {
+		return
+	}
+	if r.Roles == nil {
+		r.Roles = map[string]int{}
+	}
+	r.Roles[role]++
+}
+
+// 最も多く観測された役割（同数なら名前順で先のもの）
+func dominantRole(roles map[string]int) string {
+	keys := make([]string, 0, len(roles))
+	for k := range roles {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	best, n := "", 0
+	for _, k := range keys {
+		if roles[k] > n {
+			best, n = k, roles[k]
+		}
+	}
+	return best
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+/********** アプリ・タイトル単位の集中/気晴らし指定（report -focus） **********/
+// カテゴリ単位の分け方では足りないとき（同じ YouTube でも技術チャンネルは勉強、など）に、
+// アプリとタイトルで直接「集中」「気晴らし」を指定する。
+//
+// SHIRUSIA_FOCUS_RULES="<アプリ名>[~<タイトルの正規表現>]=><productive|distracting|neutral>;..."
+//   アプリ名は大文字小文字を無視した完全一致で、"*" はすべてのアプリ。
+//   上から順に見て最初に一致したルールを使う（例外は先に書く）。
+//   例: SHIRUSIA_FOCUS_RULES="*~YouTube.*(Go|Rust|GopherCon)=>productive;*~YouTube=>distracting;Slack=>neutral"
+//
+// 優先順位: ルールに一致したセッションはカテゴリに関係なくルールの指定に従い、
+// どのルールにも一致しなければ従来どおり -focus-categories / -distraction-categories で分ける。
+// neutral はどちらにも数えない（カテゴリが集中でも比率から外したいとき用）。
+// アイドル・休憩・離席のセッションにはルールを当てない。
+type focusKind int
+
+const (
+	focusNone focusKind = iota
+	focusProductive
+	focusDistracting
+)
+
+type focusRule struct {
+	app   string         // 小文字。"*" はすべて
+	title *regexp.Regexp // nil ならタイトルを問わない
+	kind  focusKind
+}
+
+var focusRules = loadFocusRules()
+
+func loadFocusRules() []focusRule {
+	var out []focusRule
+	for _, part := range strings.Split(os.Getenv("SHIRUSIA_FOCUS_RULES"), ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		r, err := parseFocusRule(part)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warn: SHIRUSIA_FOCUS_RULES: %v\n", err)
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+func parseFocusRule(s string) (focusRule, error) {
+	match, kind, ok := strings.Cut(s, "=>")
+	if !ok {
+		return focusRule{}, fmt.Errorf("invalid entry %q (want \"app[~title]=>productive|distracting|neutral\")", s)
+	}
+	var r focusRule
+	switch strings.ToLower(strings.TrimSpace(kind)) {
+	case "productive":
+		r.kind = focusProductive
+	case "distracting":
+		r.kind = focusDistracting
+	case "neutral":
+		r.kind = focusNone
+	default:
+		return focusRule{}, fmt.Errorf("invalid kind in %q (productive, distracting, neutral)", s)
+	}
+	app, title, hasTitle := strings.Cut(match, "~")
+	r.app = strings.ToLower(strings.TrimSpace(app))
+	if r.app == "" {
+		return focusRule{}, fmt.Errorf("missing app in %q (use \"*\" for any app)", s)
+	}
+	if hasTitle {
+		re, err := regexp.Compile(strings.TrimSpace(title))
+		if err != nil {
+			return focusRule{}, fmt.Errorf("invalid title pattern in %q: %v", s, err)
+		}
+		r.title = re
+	}
+	return r, nil
+}
+
+// セッションを集中/気晴らし/どちらでもないに分ける（ルール → カテゴリの順）
+func classifyFocus(s session, focus, distraction map[string]bool) focusKind {
+	if s.App != idleApp && s.App != awayApp {
+		app := strings.ToLower(s.App)
+		for _, r := range focusRules {
+			if (r.app == "*" || r.app == app) && (r.title == nil || r.title.MatchString(s.Title)) {
+				return r.kind
+			}
+		}
+	}
+	switch {
+	case focus[s.Activity]:
+		return focusProductive
+	case distraction[s.Activity]:
+		return focusDistracting
+	}
+	return focusNone
+}
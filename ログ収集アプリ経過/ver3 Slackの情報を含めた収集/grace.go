@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+/********** アプリ切り替えの猶予（-switch-grace） **********/
+// 通知を2秒だけ見て戻る、のような「ちら見」でセッションを切らないための猶予。
+// 前面アプリが変わっても -switch-grace の間は今のセッションを続け、
+//   - その間に元のアプリ・タイトルに戻れば、寄り道は元のセッションに含め meta.glances に残す
+//     （例: "Slack 2s, メール 1s"）
+//   - 猶予を過ぎても戻らなければ、元のアプリを離れた時刻までさかのぼって新しいセッションを始める
+// 猶予中にさらに別のアプリへ移った場合は、最後に見ていたアプリのセッションになる（離れた時刻は最初のまま）。
+// 通話・画面共有・タイトルトリガーの変化、アイドル、同じアプリ内の変化（-tab-dwell）には関係しない。
+const maxGlances = 20
+
+type switchGrace struct {
+	grace   time.Duration
+	pending *record   // 猶予中に前面にあるアプリ
+	left    time.Time // 元のセッションのアプリを離れた時刻
+}
+
+// cur を今すぐ新しいセッションにせず保留するなら hold=true。
+// 保留しない場合は新しいセッションの開始時刻を返す（猶予切れなら元のアプリを離れた時刻、それ以外は now）。
+func (g *switchGrace) Observe(prev, cur *record, now time.Time) (at time.Time, hold bool) {
+	if g.grace <= 0 || prev == nil || prev.Idle || prev.App == cur.App ||
+		prev.OnCall != cur.OnCall || prev.Sharing != cur.Sharing || prev.Trigger != cur.Trigger {
+		g.Reset()
+		return now, false
+	}
+	if g.pending == nil {
+		g.left = now
+	}
+	g.pending = cur
+	if now.Sub(g.left) < g.grace {
+		return time.Time{}, true
+	}
+	at = g.left
+	g.Reset()
+	return at, false
+}
+
+// 元のセッションに戻った。寄り道していたなら r（元のセッション）に記録する
+func (g *switchGrace) Return(r *record, now time.Time) {
+	if g.pending != nil {
+		r.noteGlance(g.pending.App, now.Sub(g.left))
+	}
+	g.Reset()
+}
+
+// セッションが切り替わったので保留を捨てる
+func (g *switchGrace) Reset() {
+	g.pending = nil
+}
+
+func (r *record) noteGlance(app string, d time.Duration) {
+	if len(r.Glances) >= maxGlances {
+		return
+	}
+	r.Glances = append(r.Glances, fmt.Sprintf("%s %s", clean(app), d.Round(time.Second)))
+}
+
+func glancesMeta(glances []string) string {
+	return strings.Join(glances, ", ")
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSwitchGraceReturnWithinGrace(t *testing.T) {
+	g := &switchGrace{grace: 5 * time.Second}
+	t0 := testTime(10, 0)
+	code := &record{App: "Visual Studio Code", Title: "main.go"}
+
+	if _, hold := g.Observe(code, &record{App: "Slack"}, t0.Add(time.Second)); !hold {
+		t.Fatal("switch to Slack was not held within the grace period")
+	}
+	if _, hold := g.Observe(code, &record{App: "Mail"}, t0.Add(2*time.Second)); !hold {
+		t.Fatal("hop to Mail was not held within the grace period")
+	}
+	// 元のアプリに戻った: 最後に見ていたアプリと、最初に離れてからの時間が寄り道として残る
+	g.Return(code, t0.Add(3*time.Second))
+	if len(code.Glances) != 1 || code.Glances[0] != "Mail 2s" {
+		t.Errorf("glances = %q, want [Mail 2s]", code.Glances)
+	}
+	if g.pending != nil {
+		t.Error("pending switch kept after returning")
+	}
+	s := sessionFrom(code, t0, t0.Add(10*time.Second))
+	if s.Meta["glances"] != "Mail 2s" || s.DurationSec != 10 {
+		t.Errorf("session = %+v, want one 10s session with meta.glances", s)
+	}
+
+	// 寄り道していなければ何も残さない
+	g.Return(code, t0.Add(20*time.Second))
+	if len(code.Glances) != 1 {
+		t.Errorf("glances = %q after returning without a pending switch", code.Glances)
+	}
+}
+
+func TestSwitchGraceExpiry(t *testing.T) {
+	g := &switchGrace{grace: 5 * time.Second}
+	t0 := testTime(10, 0)
+	code := &record{App: "Visual Studio Code"}
+
+	g.Observe(code, &record{App: "Slack"}, t0.Add(time.Second))
+	if _, hold := g.Observe(code, &record{App: "Slack"}, t0.Add(5*time.Second)); !hold {
+		t.Fatal("released before the grace period ended")
+	}
+	// 猶予を過ぎた: 元のアプリを離れた時刻から新しいセッションになる
+	at, hold := g.Observe(code, &record{App: "Slack"}, t0.Add(6*time.Second))
+	if hold || !at.Equal(t0.Add(time.Second)) {
+		t.Errorf("Observe after grace = %s, %v; want %s, false", at, hold, t0.Add(time.Second))
+	}
+	if g.pending != nil {
+		t.Error("pending switch kept after the grace period ended")
+	}
+}
+
+func TestSwitchGraceNotHeld(t *testing.T) {
+	t0 := testTime(10, 0)
+	code := &record{App: "Visual Studio Code"}
+	tests := []struct {
+		name      string
+		g         *switchGrace
+		prev, cur *record
+	}{
+		{"disabled", &switchGrace{}, code, &record{App: "Slack"}},
+		{"same app", &switchGrace{grace: 5 * time.Second}, code, &record{App: "Visual Studio Code", Title: "other.go"}},
+		{"from idle", &switchGrace{grace: 5 * time.Second}, idleRecord(t0), &record{App: "Slack"}},
+		{"call started", &switchGrace{grace: 5 * time.Second}, code, &record{App: "zoom.us", OnCall: true}},
+		{"no session", &switchGrace{grace: 5 * time.Second}, nil, &record{App: "Slack"}},
+	}
+	for _, tt := range tests {
+		at, hold := tt.g.Observe(tt.prev, tt.cur, t0)
+		if hold || !at.Equal(t0) {
+			t.Errorf("%s: Observe = %s, %v; want now, false", tt.name, at, hold)
+		}
+	}
+}
+
+func TestNoteGlanceLimit(t *testing.T) {
+	r := &record{App: "Visual Studio Code"}
+	for i := 0; i < maxGlances+5; i++ {
+		r.noteGlance(fmt.Sprintf("App%d", i), 1500*time.Millisecond)
+	}
+	if len(r.Glances) != maxGlances {
+		t.Errorf("kept %d glances, want %d", len(r.Glances), maxGlances)
+	}
+	if r.Glances[0] != "App0 2s" {
+		t.Errorf("first glance = %q, want rounded to the second", r.Glances[0])
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+/********** ライブ状態（HTTP用） **********/
+// メインループが更新し、HTTPハンドラが読む。ロックは短時間だけ持つのでポーリングを止めない。
+type liveState struct {
+	mu       sync.Mutex
+	day      string    // sessions の日付（YYYY-MM-DD、ローカル）
+	sessions []session // 当日に確定したセッション
+	cur      *record   // 進行中セッション（なければ nil）
+	curStart time.Time
+	private  bool // -private: 進行中セッションも書き出すときと同じように丸めて出す（privatemode.go）
+
+	lastTick   time.Time               // メインループが最後に回った時刻（実時間）
+	staleAfter time.Duration           // これより古ければ止まっているとみなす
+	sinkErrs   map[string]error        // 出力先ごとの直近の書き込みエラー
+	checks     map[string]func() error // 出力先が自分で状態を持つもの（-post-url など）
+
+	awayReq chan bool // 離席(true)/復帰(false)の要求（away.go）
+
+	events *eventHub // セッションの開始・終了の通知（GET /events。events.go）
+}
+
+func newLiveState(staleAfter time.Duration) *liveState {
+	return &liveState{
+		lastTick:   time.Now(), // 起動直後は最初のティック前でも正常とする
+		staleAfter: staleAfter,
+		sinkErrs:   map[string]error{},
+		checks:     map[string]func() error{},
+		awayReq:    make(chan bool, 4),
+		events:     newEventHub(),
+	}
+}
+
+// 確定したセッションを当日分として追加（日付が変わっていれば当日分をリセット）
+func (l *liveState) AddSession(s session, end time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rollDay(end)
+	l.sessions = append(l.sessions, s)
+}
+
+// 進行中セッションを設定（nil で解除）
+func (l *liveState) SetCurrent(r *record, start time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.cur = r
+	l.curStart = start
+}
+
+// メインループが1回回るたびに呼ぶ
+func (l *liveState) Tick(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lastTick = now
+}
+
+// 出力先 name への書き込み結果を記録する（成功なら nil で回復扱い）
+func (l *liveState) SinkResult(name string, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err == nil {
+		delete(l.sinkErrs, name)
+		return
+	}
+	l.sinkErrs[name] = err
+}
+
+func (l *liveState) AddHealthCheck(name string, check func() error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.checks[name] = check
+}
+
+// 異常があればその理由の一覧（正常なら空）
+func (l *liveState) Health(now time.Time) (lastTick time.Time, problems []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if age := now.Sub(l.lastTick); age > l.staleAfter {
+		problems = append(problems, fmt.Sprintf("main loop stalled: last tick %s ago", age.Round(time.Second)))
+	}
+	for _, name := range sortedNames(l.sinkErrs) {
+		problems = append(problems, fmt.Sprintf("%s: %v", name, l.sinkErrs[name]))
+	}
+	for _, name := range sortedNames(l.checks) {
+		if err := l.checks[name](); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+	return l.lastTick, problems
+}
+
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for k := range m {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (l *liveState) rollDay(now time.Time) {
+	day := now.Format("2006-01-02")
+	if l.day != day {
+		l.day = day
+		l.sessions = nil
+	}
+}
+
+// 当日分のセッション（進行中のものは now までで切った仮のセッションとして末尾に含める）
+func (l *liveState) Today(now time.Time) []session {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rollDay(now)
+	out := make([]session, len(l.sessions), len(l.sessions)+1)
+	copy(out, l.sessions)
+	if l.cur != nil {
+		start := l.curStart
+		if midnight := startOfDay(now); start.Before(midnight) {
+			start = midnight
+		}
+		if s := l.inProgress(start, now); s.DurationSec > 0 || !l.private {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// 進行中セッション（now までで切ったもの）と、その開始からの経過。なければ ok=false
+func (l *liveState) Current(now time.Time) (s session, elapsed time.Duration, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.cur == nil {
+		return session{}, 0, false
+	}
+	elapsed = now.Sub(l.curStart)
+	if l.private {
+		elapsed = roundPrivate(elapsed)
+	}
+	return l.inProgress(l.curStart, now), elapsed, true
+}
+
+// 進行中セッションを now までで切ったもの（-private なら丸めてアプリ名をハッシュにしたもの）
+func (l *liveState) inProgress(start, now time.Time) session {
+	s := sessionFrom(l.cur, start, now)
+	if l.private {
+		return privatizeSession(s, start)
+	}
+	return s
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+/********** HTTPサーバ（-http） **********/
+//   GET /          日次タイムラインのWeb UI（web/ を埋め込み）
+//   GET /current   進行中のセッションと経過秒数（メニューバーのウィジェットなど用）
+//   GET /today     当日のカテゴリ別合計（秒）
+//   GET /sessions  当日のセッション一覧（進行中を含む）
+//   GET /events    セッションの開始・終了を Server-Sent Events で流す（events.go）
+//   POST /away     離席にする / POST /back  離席から戻る（away.go）
+//   GET /healthz   死活監視用（launchd / systemd / Docker など）。メインループが最近回っていて
+//                  出力先に異常がなければ 200、そうでなければ 503 と理由を返す
+//
+//go:embed web
+var webFiles embed.FS
+
+type healthResponse struct {
+	Status   string   `json:"status"` // "ok" / "unhealthy"
+	LastTick string   `json:"lastTick"`
+	Problems []string `json:"problems,omitempty"`
+}
+
+type currentResponse struct {
+	Active     bool     `json:"active"` // 進行中のセッションがあるか（起動直後・離席の切り替え中などは false）
+	ElapsedSec int64    `json:"elapsedSec"`
+	Session    *session `json:"session,omitempty"` // end・durationSec は今の時点まで
+}
+
+type todayResponse struct {
+	Date     string           `json:"date"`
+	TotalSec int64            `json:"totalSec"`
+	Totals   map[string]int64 `json:"totals"` // カテゴリ → 秒
+}
+
+func newHTTPServer(addr string, st *liveState) *http.Server {
+	mux := http.NewServeMux()
+
+	ui, _ := fs.Sub(webFiles, "web")
+	mux.Handle("GET /", http.FileServer(http.FS(ui)))
+
+	mux.HandleFunc("GET /current", func(w http.ResponseWriter, r *http.Request) {
+		var resp currentResponse
+		if s, elapsed, ok := st.Current(time.Now()); ok {
+			resp = currentResponse{Active: true, ElapsedSec: int64(elapsed / time.Second), Session: &s}
+		}
+		writeJSON(w, resp)
+	})
+	mux.HandleFunc("GET /today", func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now()
+		resp := todayResponse{Date: now.Format("2006-01-02"), Totals: map[string]int64{}}
+		for _, s := range st.Today(now) {
+			resp.Totals[s.Activity] += s.DurationSec
+			resp.TotalSec += s.DurationSec
+		}
+		writeJSON(w, resp)
+	})
+	mux.HandleFunc("GET /sessions", func(w http.ResponseWriter, r *http.Request) {
+		ss := st.Today(time.Now())
+		if ss == nil {
+			ss = []session{}
+		}
+		writeJSON(w, ss)
+	})
+	mux.HandleFunc("GET /events", eventsHandler(st.events))
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		last, problems := st.Health(time.Now())
+		resp := healthResponse{Status: "ok", LastTick: last.Format(time.RFC3339), Problems: problems}
+		if len(problems) > 0 {
+			resp.Status = "unhealthy"
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		writeJSON(w, resp)
+	})
+	mux.HandleFunc("POST /away", awayHandler(st, true))
+	mux.HandleFunc("POST /back", awayHandler(st, false))
+
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	srv.RegisterOnShutdown(st.events.Close) // 流し続けている /events の接続を Shutdown が待たないように
+	return srv
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(v)
+}
+
+// 終了シグナル時に呼ぶ
+func shutdownHTTPServer(srv *http.Server) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	return srv.Shutdown(ctx)
+}
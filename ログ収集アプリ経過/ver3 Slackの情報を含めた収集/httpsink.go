@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+/********** リモート集約先への送信（-post-url） **********/
+// 確定したセッションを、複数台のマシンから1か所に集める収集サーバへPOSTする。
+// 1件ずつ送ると通信が多いので、-post-batch 件たまるか -post-window 経過でまとめて
+// JSON配列として送る。失敗したら指数バックオフで再送し、終了時には残りを送ってから閉じる。
+//   SHIRUSIA_POST_TOKEN があれば Authorization: Bearer <token> を付ける
+type httpSink struct {
+	url      string
+	token    string
+	client   *http.Client
+	maxBatch int
+	window   time.Duration
+
+	ch   chan session
+	done chan struct{}
+
+	mu      sync.Mutex
+	lastErr error // 直近の送信が失敗していればその理由（/healthz 用）
+}
+
+const (
+	httpSinkQueue      = 1000 // 送信待ちの上限（超えたら捨てて警告）
+	httpSinkMaxPending = 5000 // 送れないまま溜めておく上限
+	httpSinkRetries    = 5
+)
+
+func newHTTPSink(url string, maxBatch int, window time.Duration) *httpSink {
+	if maxBatch < 1 {
+		maxBatch = 1
+	}
+	h := &httpSink{
+		url:      url,
+		token:    strings.TrimSpace(os.Getenv("SHIRUSIA_POST_TOKEN")),
+		client:   &http.Client{Timeout: 10 * time.Second},
+		maxBatch: maxBatch,
+		window:   window,
+		ch:       make(chan session, httpSinkQueue),
+		done:     make(chan struct{}),
+	}
+	go runGuarded("http sink", h.run)
+	return h
+}
+
+// メインループを止めないよう、キューに積むだけ
+func (h *httpSink) AppendSession(s *session) error {
+	select {
+	case h.ch <- *s:
+		return nil
+	default:
+		return fmt.Errorf("http sink queue full, dropping session")
+	}
+}
+
+// 残りを送ってから終了する
+func (h *httpSink) Close() error {
+	close(h.ch)
+	<-h.done
+	return nil
+}
+
+func (h *httpSink) run() {
+	defer close(h.done)
+	var pending []session
+	timer := time.NewTimer(h.window)
+	defer timer.Stop()
+	failing := false // 送信に失敗している間は件数では送らず、タイマーでだけ再試行する
+
+	flush := func(final bool) {
+		if len(pending) == 0 {
+			return
+		}
+		retries := httpSinkRetries
+		if final {
+			retries = 2 // 終了時は長く待たせない
+		}
+		err := h.postWithRetry(pending, retries)
+		h.setErr(err)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "http sink: %v (keeping %d sessions for the next attempt)\n", err, len(pending))
+			if len(pending) > httpSinkMaxPending {
+				drop := len(pending) - httpSinkMaxPending
+				fmt.Fprintf(os.Stderr, "http sink: dropping %d oldest sessions\n", drop)
+				pending = pending[drop:]
+			}
+			failing = true
+			return
+		}
+		pending = nil
+		failing = false
+	}
+
+	for {
+		select {
+		case s, ok := <-h.ch:
+			if !ok {
+				flush(true)
+				return
+			}
+			pending = append(pending, s)
+			if !failing && len(pending) >= h.maxBatch {
+				flush(false)
+			}
+		case <-timer.C:
+			flush(false)
+			timer.Reset(h.window)
+		}
+	}
+}
+
+// 直近の送信が失敗していればそのエラー（成功すれば nil に戻る）
+func (h *httpSink) Err() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.lastErr
+}
+
+func (h *httpSink) setErr(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastErr = err
+}
+
+// 1s, 2s, 4s... と間隔を倍にしながら retries 回まで試す
+func (h *httpSink) postWithRetry(batch []session, retries int) error {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	backoff := time.Second
+	for attempt := 1; ; attempt++ {
+		err = h.post(body)
+		if err == nil {
+			return nil
+		}
+		if attempt >= retries {
+			return fmt.Errorf("post %d sessions failed after %d attempts: %w", len(batch), attempt, err)
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (h *httpSink) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.token != "" {
+		req.Header.Set("Authorization", "Bearer "+h.token)
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("collector returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+/********** export-ics サブコマンド **********/
+// セッションログをカレンダー（iCalendar, RFC 5545）に変換する。実際の予定と重ねて、
+// 予定と実績の差を見るためのもの。
+//   activitylog export-ics [-merge 2m] [-min 1m] [-o out.ics] file.json
+// 1セッション = 1 VEVENT。-merge を付けると、同じカテゴリが gap 以内の間隔で続くものを
+// 1つの集中ブロックにまとめる。アイドル期間は書き出さない。
+type icsEvent struct {
+	Start, End time.Time
+	Summary    string
+	Desc       string
+}
+
+func runExportICS(args []string) int {
+	fs := flag.NewFlagSet("export-ics", flag.ExitOnError)
+	merge := fs.Duration("merge", 0, "merge consecutive sessions of the same category separated by at most this gap (0 = one event per session)")
+	minDur := fs.Duration("min", 0, "skip events shorter than this")
+	out := fs.String("o", "", "output path (default: <input>.ics)")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: export-ics [-merge 2m] [-min 1m] [-o out.ics] file.json")
+		return 2
+	}
+	in := fs.Arg(0)
+	if *out == "" {
+		*out = strings.TrimSuffix(in, ".json") + ".ics"
+	}
+
+	ss, err := readSessionsFile(in)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export-ics: %v\n", err)
+		return 1
+	}
+	evs := sessionEvents(ss, *merge, *minDur)
+
+	f, err := os.Create(*out)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export-ics: %v\n", err)
+		return 1
+	}
+	w := bufio.NewWriter(f)
+	writeICS(w, evs, time.Now())
+	if err := w.Flush(); err != nil {
+		f.Close()
+		fmt.Fprintf(os.Stderr, "export-ics: %v\n", err)
+		return 1
+	}
+	if err := f.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "export-ics: %v\n", err)
+		return 1
+	}
+	fmt.Printf("wrote %s (%d events)\n", *out, len(evs))
+	return 0
+}
+
+// セッション → イベント。merge > 0 なら同じカテゴリの連続をまとめる
+func sessionEvents(ss []session, merge, minDur time.Duration) []icsEvent {
+	var evs []icsEvent
+	var apps []string // まとめたイベントに含まれるアプリ（出現順）
+	for _, s := range ss {
+		if s.App == idleApp {
+			continue
+		}
+		start, err1 := time.Parse(time.RFC3339, s.Start)
+		end, err2 := time.Parse(time.RFC3339, s.End)
+		if err1 != nil || err2 != nil || !end.After(start) {
+			continue
+		}
+		if n := len(evs); merge > 0 && n > 0 && evs[n-1].Summary == s.Activity && start.Sub(evs[n-1].End) <= merge {
+			last := &evs[n-1]
+			if end.After(last.End) {
+				last.End = end
+			}
+			if !containsString(apps, s.App) {
+				apps = append(apps, s.App)
+				last.Desc = strings.Join(apps, ", ")
+			}
+			continue
+		}
+		summary := s.Activity
+		if merge == 0 && s.App != "" {
+			summary = s.Activity + "（" + s.App + "）"
+		}
+		desc := s.App
+		if merge == 0 && s.Title != "" {
+			desc = s.App + "\n" + s.Title
+		}
+		evs = append(evs, icsEvent{Start: start, End: end, Summary: summary, Desc: desc})
+		apps = []string{s.App}
+	}
+	if minDur <= 0 {
+		return evs
+	}
+	kept := evs[:0]
+	for _, e := range evs {
+		if e.End.Sub(e.Start) >= minDur {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func writeICS(w io.Writer, evs []icsEvent, now time.Time) {
+	line := func(s string) { io.WriteString(w, foldICSLine(s)+"\r\n") }
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//Shirusia//activitylog//JA")
+	line("CALSCALE:GREGORIAN")
+	line("X-WR-CALNAME:" + escapeICSText("Shirusia 実績"))
+	stamp := icsTime(now)
+	for _, e := range evs {
+		line("BEGIN:VEVENT")
+		line("UID:" + icsUID(e))
+		line("DTSTAMP:" + stamp)
+		line("DTSTART:" + icsTime(e.Start))
+		line("DTEND:" + icsTime(e.End))
+		line("SUMMARY:" + escapeICSText(e.Summary))
+		if e.Desc != "" {
+			line("DESCRIPTION:" + escapeICSText(e.Desc))
+		}
+		line("TRANSP:TRANSPARENT") // 空き時間の計算を邪魔しない
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+}
+
+// UTC の DATE-TIME 形式
+func icsTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// 同じセッションを再エクスポートしても同じUIDになる（カレンダー側で重複しない）
+func icsUID(e icsEvent) string {
+	sum := sha256.Sum256([]byte(e.Start.UTC().Format(time.RFC3339) + "|" + e.Summary))
+	return hex.EncodeToString(sum[:8]) + "@shirusia"
+}
+
+// TEXT 値のエスケープ（RFC 5545 3.3.11）: \ ; , と改行
+func escapeICSText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case ';':
+			b.WriteString(`\;`)
+		case ',':
+			b.WriteString(`\,`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			// \r\n は \n 側で処理する
+		default:
+			if r < 0x20 && r != '\t' {
+				continue // その他の制御文字は使えない
+			}
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// 75オクテットごとに CRLF + 空白 で折り返す（RFC 5545 3.1）。UTF-8 の文字の途中では切らない
+func foldICSLine(s string) string {
+	const limit = 75
+	if len(s) <= limit {
+		return s
+	}
+	var b strings.Builder
+	n := 0
+	for i, r := range s {
+		size := utf8.RuneLen(r)
+		if n+size > limit {
+			b.WriteString("\r\n ")
+			n = 1 // 先頭の空白も数える
+		}
+		b.WriteString(s[i : i+size])
+		n += size
+	}
+	return b.String()
+}
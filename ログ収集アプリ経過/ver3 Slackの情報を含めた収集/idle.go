@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+/********** 無操作（アイドル）検知 **********/
+// 最後のキーボード/マウス入力からの経過時間を OS ごとに読む（inputIdleTime）。
+//   macOS    IOHIDSystem の HIDIdleTime を ioreg から（idle_darwin.go）
+//   Windows  GetLastInputInfo（idle_windows.go）
+//   その他   取り方がないので -idle は無効になる（idle_other.go）
+var errIdleUnsupported = errors.New("reading keyboard/mouse idle time is not supported on this OS")
+
+const (
+	idleApp      = "idle"
+	idleActivity = "アイドル"
+	breakLabel   = "休憩"
+)
+
+// アイドル期間を表すレコード（入力が止まった時刻から始まる）
+func idleRecord(at time.Time) *record {
+	return &record{App: idleApp, Activity: idleActivity, Idle: true, Timestamp: at}
+}
+
+/********** 休憩時間帯 **********/
+// SHIRUSIA_BREAK_WINDOWS="12:00-13:00,15:00-15:15"（既定 "12:00-13:00"）
+//   アイドル期間がこの時間帯（ローカル時刻）と重なれば、「アイドル」ではなく「休憩」として記録する。
+//   時間帯の外のアイドル（会議室への移動など）は「アイドル」のまま残るので、休憩と区別できる。
+//   カメラ/マイクで通話中と分かっている間（-detect-calls）はそもそもアイドル扱いにしない。
+type clockWindow struct {
+	from, to time.Duration // 0:00 からの経過
+}
+
+var breakWindows = loadBreakWindows()
+
+func loadBreakWindows() []clockWindow {
+	raw := strings.TrimSpace(os.Getenv("SHIRUSIA_BREAK_WINDOWS"))
+	if raw == "" {
+		raw = "12:00-13:00"
+	}
+	ws, err := parseClockWindows(raw)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warn: SHIRUSIA_BREAK_WINDOWS: %v\n", err)
+	}
+	return ws
+}
+
+// "HH:MM-HH:MM,..." を解釈する（日をまたぐ "22:00-02:00" も可）
+func parseClockWindows(raw string) ([]clockWindow, error) {
+	var out []clockWindow
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		a, b, ok := strings.Cut(part, "-")
+		if !ok {
+			return out, fmt.Errorf("invalid window %q (want HH:MM-HH:MM)", part)
+		}
+		from, err1 := parseClock(a)
+		to, err2 := parseClock(b)
+		if err1 != nil || err2 != nil {
+			return out, fmt.Errorf("invalid window %q (want HH:MM-HH:MM)", part)
+		}
+		out = append(out, clockWindow{from: from, to: to})
+	}
+	return out, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// t（ローカル時刻）がこの時間帯に入っているか
+func (w clockWindow) contains(t time.Time) bool {
+	tod := t.Sub(startOfDay(t))
+	if w.from <= w.to {
+		return tod >= w.from && tod < w.to
+	}
+	return tod >= w.from || tod < w.to // 日をまたぐ
+}
+
+// [start, end) がいずれかの休憩時間帯と重なるか（1分刻みで確認）
+func overlapsBreak(start, end time.Time) bool {
+	for t := start; t.Before(end); t = t.Add(time.Minute) {
+		for _, w := range breakWindows {
+			if w.contains(t) {
+				return true
+			}
+		}
+	}
+	for _, w := range breakWindows {
+		if w.contains(end) {
+			return true
+		}
+	}
+	return false
+}
+
+// アイドル期間の確定時のラベル
+func idleLabel(start, end time.Time) string {
+	if overlapsBreak(start, end) {
+		return breakLabel
+	}
+	return idleActivity
+}
@@ -0,0 +1,30 @@
+//go:build darwin
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// HIDIdleTime は最後のキーボード/マウス入力からの経過ナノ秒
+var hidIdleRe = regexp.MustCompile(`"HIDIdleTime"\s*=\s*(\d+)`)
+
+func inputIdleTime() (time.Duration, error) {
+	out, err := runCmd("ioreg", "-c", "IOHIDSystem", "-d", "4")
+	if err != nil {
+		return 0, fmt.Errorf("ioreg: %w", err)
+	}
+	m := hidIdleRe.FindStringSubmatch(out)
+	if m == nil {
+		return 0, errors.New("HIDIdleTime not found")
+	}
+	ns, err := strconv.ParseInt(m[1], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(ns), nil
+}
@@ -0,0 +1,8 @@
+//go:build !darwin && !windows
+
+package main
+
+import "time"
+
+// 入力からの経過時間を読む方法がない OS（起動時に -idle を無効にする）
+func inputIdleTime() (time.Duration, error) { return 0, errIdleUnsupported }
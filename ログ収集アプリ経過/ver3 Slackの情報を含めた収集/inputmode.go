@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/********** 入力の種類（-input-mode、任意） **********/
+// 同じエディタでも「書いている（キーボード中心）」のか「読んでいる・レビューしている（ポインタ中心）」のかを
+// 分けるため、セッション中にどちらの入力が多かったかを meta.inputMode に入れる。
+// CoreGraphics の入力イベントカウンタ（CGEventSourceCounterForEventType、起動からの累計）を
+// 毎ティック読み、前回との差をその間前面だったセッションに足す。トラックパッドとマウスは区別できない。
+//   keyboard : キー入力が 70% 以上
+//   pointer  : クリック・スクロールが 70% 以上
+//   mixed    : その間
+// スクロールはトラックパッドだと1回の操作で大量のイベントが出るので、scrollEventsPerAction 件で1回と数える。
+// 合計が minInputActions 回に満たないセッション（眺めていただけ等）にはラベルを付けない。
+// 毎ティック osascript を1回余分に起動するので既定はオフ。
+const (
+	scrollEventsPerAction = 10
+	minInputActions       = 10
+)
+
+// 起動からの累計（HIDシステム状態）を "キー クリック スクロール" の順で返す
+const inputCountersJXA = `
+ObjC.import('CoreGraphics');
+function c(t) { return $.CGEventSourceCounterForEventType(1, t); }
+[c(10), c(1) + c(3) + c(25), c(22)].join(' ');
+`
+
+type inputCounts struct {
+	Keys, Clicks, Scrolls int64
+}
+
+func (c *inputCounts) add(d inputCounts) {
+	c.Keys += d.Keys
+	c.Clicks += d.Clicks
+	c.Scrolls += d.Scrolls
+}
+
+// セッション中に多かった入力の種類（少なすぎれば ""）
+func (c inputCounts) label() string {
+	keys := float64(c.Keys)
+	pointer := float64(c.Clicks) + float64(c.Scrolls)/scrollEventsPerAction
+	total := keys + pointer
+	switch {
+	case total < minInputActions:
+		return ""
+	case keys >= total*0.7:
+		return "keyboard"
+	case pointer >= total*0.7:
+		return "pointer"
+	}
+	return "mixed"
+}
+
+type inputSampler struct {
+	prev    inputCounts
+	started bool
+}
+
+// 前回の呼び出しからの増分（初回と読み取りに失敗したときはゼロ）
+func (s *inputSampler) Sample() inputCounts {
+	cur, err := readInputCounters()
+	if err != nil {
+		return inputCounts{}
+	}
+	d := inputCounts{Keys: cur.Keys - s.prev.Keys, Clicks: cur.Clicks - s.prev.Clicks, Scrolls: cur.Scrolls - s.prev.Scrolls}
+	first := !s.started
+	s.prev, s.started = cur, true
+	if first || d.Keys < 0 || d.Clicks < 0 || d.Scrolls < 0 {
+		return inputCounts{} // 初回・カウンタのリセット（ログアウト等）は数えない
+	}
+	return d
+}
+
+func readInputCounters() (inputCounts, error) {
+	osaSpawns.Add(1)
+	out, err := runCmd("osascript", "-l", "JavaScript", "-e", inputCountersJXA)
+	if err != nil {
+		return inputCounts{}, err
+	}
+	f := strings.Fields(out)
+	if len(f) != 3 {
+		return inputCounts{}, fmt.Errorf("unexpected input counters %q", strings.TrimSpace(out))
+	}
+	var v [3]int64
+	for i := range f {
+		if v[i], err = strconv.ParseInt(f[i], 10, 64); err != nil {
+			return inputCounts{}, err
+		}
+	}
+	return inputCounts{Keys: v[0], Clicks: v[1], Scrolls: v[2]}, nil
+}
@@ -0,0 +1,12 @@
+//go:build darwin
+
+package main
+
+// ログイン画面・スクリーンセーバーのロック中か（sleep.go）
+func screenLocked() bool {
+	out, err := runCmd("ioreg", "-n", "Root", "-d1")
+	if err != nil {
+		return false
+	}
+	return screenLockedIn(out)
+}
@@ -0,0 +1,6 @@
+//go:build !darwin
+
+package main
+
+// 画面ロックを判定する方法がない OS（ロック中は -idle の閾値を過ぎてからアイドルになる）
+func screenLocked() bool { return false }
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// 1日分より多い 10k セッションを追記する。追記のたびに fsync する（-sync-interval 0）のと、
+// 既定の間隔で同期するのとの差を見る
+func BenchmarkAppendSessions(b *testing.B) {
+	const sessions = 10000
+	s := &session{
+		Start: "2025-09-01T10:00:00+09:00", End: "2025-09-01T10:05:00+09:00",
+		App: "Visual Studio Code", Title: "main.go — activitylog — Visual Studio Code",
+		Activity: "プログラムの制作", MatchedBy: "title-ext:.go", DurationSec: 300,
+	}
+	for _, every := range []time.Duration{0, 5 * time.Second} {
+		for _, format := range []string{"array", "ndjson"} {
+			b.Run(fmt.Sprintf("%s/sync=%s", format, every), func(b *testing.B) {
+				defer func(d time.Duration, f string) { syncInterval, outputFormat = d, f }(syncInterval, outputFormat)
+				syncInterval, outputFormat = every, format
+				for i := 0; i < b.N; i++ {
+					w, err := newSessionFile(b.TempDir())
+					if err != nil {
+						b.Fatal(err)
+					}
+					for n := 0; n < sessions; n++ {
+						if err := w.AppendSession(s); err != nil {
+							b.Fatal(err)
+						}
+					}
+					if err := w.Close(); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
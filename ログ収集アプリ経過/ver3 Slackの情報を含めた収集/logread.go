@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+/********** セッションログの読み込み（サブコマンド共通） **********/
+// activity_*.json（JSON配列）と activity_*.ndjson（1行1セッション）を読む。中身の先頭が '[' かどうかで
+// 見分けるので拡張子は問わない。クラッシュ等で閉じ括弧のない・最後の行が途中までのファイルでも、
+// 読めたところまでの要素を返す。
+func readSessionsFile(path string) ([]session, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	dec := json.NewDecoder(br)
+	if isJSONArray(br) {
+		if _, err := dec.Token(); err != nil { // 先頭の '['
+			return nil, err
+		}
+	}
+	var out []session
+	for dec.More() {
+		var s session
+		if err := dec.Decode(&s); err != nil {
+			break // 途中で切れている：それまでの分だけ使う
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// 空白を飛ばして最初の文字が '[' か（読み位置は進めない）
+func isJSONArray(br *bufio.Reader) bool {
+	for i := 1; ; i++ {
+		b, err := br.Peek(i)
+		if err != nil {
+			return false
+		}
+		switch b[i-1] {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b[i-1] == '['
+	}
+}
+
+// dir 内のセッションファイル（activity_*.json / activity_*.ndjson）を名前順に返す
+func sessionFiles(dir string) ([]string, error) {
+	var out []string
+	for _, pat := range []string{"activity_*.json", "activity_*.ndjson"} {
+		paths, err := filepath.Glob(filepath.Join(dir, pat))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, paths...)
+	}
+	sort.Strings(out) // ファイル名が起動時刻なので名前順＝時系列順
+	return out, nil
+}
+
+// logDir内のセッションファイルのうち、更新日時が since 以降のものを古い順に返す
+func sessionFilesSince(dir string, since time.Time) ([]string, error) {
+	paths, err := sessionFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, p := range paths {
+		st, err := os.Stat(p)
+		if err != nil || st.ModTime().Before(since) {
+			continue
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// dir に記録したセッションのうち [from, to) に重なりうるもの。SHIRUSIA_OUTPUT=sqlite なら activity.db から、
+// それ以外は from 以降に更新されたセッションファイルから読む（ファイルは更新日時で選ぶだけなので、範囲外のセッションも含む）
+func readLoggedSessions(dir string, from, to time.Time) ([]session, error) {
+	if outputFormat == "sqlite" {
+		return readSessionsDB(filepath.Join(dir, "activity.db"), from, to)
+	}
+	files, err := sessionFilesSince(dir, from)
+	if err != nil {
+		return nil, err
+	}
+	return readSessionsPaths(files), nil
+}
+
+// 読めないファイルは警告を出して飛ばす
+func readSessionsPaths(files []string) []session {
+	var all []session
+	for _, p := range files {
+		ss, err := readSessionsFile(p)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warn: skip %s: %v\n", p, err)
+			continue
+		}
+		all = append(all, ss...)
+	}
+	return all
+}
@@ -3,14 +3,19 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -21,29 +26,59 @@ import (
 )
 
 /********** 設定 **********/
-const (
-	pollInterval = 1500 * time.Millisecond
-	// セッション（start/end）JSONの保存先
-	logDir = "/Users/kmg2022-40/Desktop/activitylog/log"
-	// Slack本文（1メッセージ=1JSON）の保存先
-	messageDir = "/Users/kmg2022-40/Desktop/activitylog/Message"
+var (
+	// 前面ウィンドウを取る間隔（-interval / SHIRUSIA_INTERVAL。config.go）
+	pollInterval = envPollInterval()
+	// セッション（start/end）JSONの保存先（SHIRUSIA_LOG_DIR。config.go）
+	logDir = loadDataDir("SHIRUSIA_LOG_DIR", "log")
+	// Slack本文（1メッセージ=1JSON）の保存先（SHIRUSIA_MESSAGE_DIR）
+	messageDir = loadDataDir("SHIRUSIA_MESSAGE_DIR", "Message")
 )
 
 /********** データ型 **********/
 type record struct {
-	App       string
+	App       string // 正規化済みのアプリ名（normalizeAppName）
+	RawApp    string // 取得したままのアプリ名
+	BundleID  string
 	Title     string
+	URL       string // ブラウザで表示中のページ（取れたときのみ。セッション開始時のもの）
 	Activity  string
+	MatchedBy string         // カテゴリを決めたルール・キーワード（classifyMatch）
+	OnCall    bool           // -detect-calls 時のみ: カメラ/マイク使用中
+	Sharing   bool           // -detect-sharing 時のみ: 画面収録・画面共有中
+	Idle      bool           // 無操作期間（-idle）
+	Apps      []string       // このセッション中に前面になったアプリ（-track category 用）
+	Tags      []string       // 一致したすべてのルール・観点タグ（-tags）
+	Roles     map[string]int // 観測したフォーカス要素の役割と回数（-focused-role）
+	Tracks    []string       // メディアアプリで再生した曲名（出現順）
+	Meeting   string         // Zoom の会議名（会議・通話のみ）
+	Attendees int            // Zoom の参加者数（取れたときのみ）
+	Trigger   string         // 一致したタイトルトリガーの正規表現（SHIRUSIA_TITLE_TRIGGERS）
+	Space     int            // セッション開始時のスペース番号（-space、不明なら0）
+	Input     inputCounts    // このセッション中の入力回数（-input-mode）
+	Glances   []string       // 猶予中に戻ってきた寄り道（-switch-grace）
+	OtherTabs []string       // 前面以外のブラウザウィンドウで表示中だったタブ（-other-tabs）
 	Timestamp time.Time
 }
 
 type session struct {
-	Start       string `json:"start"` // RFC3339
-	End         string `json:"end"`   // RFC3339
-	App         string `json:"app"`
-	Title       string `json:"title"`
-	Activity    string `json:"activity"`
-	DurationSec int64  `json:"durationSec"` // 秒
+	Start         string            `json:"start"` // RFC3339
+	End           string            `json:"end"`   // RFC3339
+	App           string            `json:"app"`
+	Title         string            `json:"title"`
+	URL           string            `json:"url,omitempty"` // ブラウザのページ（sanitizeURL で整形済み。urlscrub.go）
+	Activity      string            `json:"activity"`
+	MatchedBy     string            `json:"matchedBy,omitempty"`     // カテゴリを決めたルール・キーワード（"app:slack" "title-ext:.go" "default" など）
+	DurationSec   int64             `json:"durationSec"`             // 秒
+	DurationHuman string            `json:"durationHuman,omitempty"` // "1h23m45s"（-human-durations 時のみ。集計には durationSec を使う）
+	IdleSec       int64             `json:"idleSec,omitempty"`       // うち無操作だった秒数（-idle で閉じたアイドル・休憩セッションは全体）
+	EndReason     string            `json:"endReason,omitempty"`     // 終わった理由（-end-reason 時のみ。endreason.go）
+	Seq           int               `json:"seq,omitempty"`           // ファイル内の通し番号（1始まり、-seq 時のみ）
+	OnCall        bool              `json:"onCall,omitempty"`
+	Sharing       bool              `json:"sharing,omitempty"`
+	Space         int               `json:"space,omitempty"` // macOS のスペース番号（-space 時のみ）
+	Tags          []string          `json:"tags,omitempty"`
+	Meta          map[string]string `json:"meta,omitempty"` // apps など付加情報
 }
 
 type messageEntry struct {
@@ -55,26 +90,101 @@ type messageEntry struct {
 	Meta      map[string]string `json:"meta,omitempty"`      // channelId, threadTs, userId, ts など
 }
 
-/********** JSON配列ファイル ライター（セッション用） **********/
-type jsonArrayWriter struct {
-	path       string
-	f          *os.File
-	w          *bufio.Writer
-	wroteFirst bool
+/********** セッションの出力先 **********/
+// セッションファイル・SQLite（sqliteSink）・-post-url（httpSink）・-syslog が満たす形
+type sessionSink interface {
+	AppendSession(s *session) error
+	Close() error
 }
 
-func newJSONArrayWriter() (*jsonArrayWriter, error) {
-	if err := os.MkdirAll(logDir, 0755); err != nil {
-		return nil, err
+// ローテーションするセッションファイル（jsonArrayWriter / jsonLinesWriter）
+type sessionFileSink interface {
+	sessionSink
+	file() *logFile
+}
+
+// SHIRUSIA_OUTPUT=array|ndjson|sqlite（既定 array。sqlite は sqlitesink.go）
+// array は activity_*.json に1つのJSON配列として書く。閉じる前に落ちると末尾の "]" が欠ける
+// （readSessionsFile は読めたところまでを使う）。ndjson は activity_*.ndjson に1行1セッションで書く。
+// 括弧がないので、kill -9 されても最後の行より前はそのまま読める。
+var outputFormat = loadOutputFormat()
+
+func loadOutputFormat() string {
+	v := strings.ToLower(strings.TrimSpace(os.Getenv("SHIRUSIA_OUTPUT")))
+	switch v {
+	case "", "array", "json":
+		return "array"
+	case "ndjson", "jsonl":
+		return "ndjson"
+	case "sqlite":
+		return "sqlite" // sqlitesink.go
 	}
-	name := fmt.Sprintf("activity_%s.json", time.Now().Format("20060102_150405"))
-	path := filepath.Join(logDir, name)
-	f, err := os.Create(path)
+	fmt.Fprintf(os.Stderr, "warn: SHIRUSIA_OUTPUT: unknown format %q (array, ndjson, sqlite); using array\n", v)
+	return "array"
+}
+
+// dir に SHIRUSIA_OUTPUT の形式で新しいセッションファイルを作る
+func newSessionFile(dir string) (sessionFileSink, error) {
+	if outputFormat == "ndjson" {
+		w, err := newJSONLinesWriter(dir)
+		if err != nil {
+			return nil, err
+		}
+		return w, nil
+	}
+	w, err := newJSONArrayWriter(dir)
 	if err != nil {
 		return nil, err
 	}
+	return w, nil
+}
+
+// セッションファイルの共通部分
+type logFile struct {
+	path   string
+	f      *os.File
+	w      *bufio.Writer
+	pretty bool      // 要素ごとにインデントして書く（-pretty。ndjson では使わない）
+	opened time.Time // 作成時刻（日付が変わったら切り替える）
+	size   int64     // 書き込んだバイト数（-max-file-size）
+	synced time.Time // 最後に Sync した時刻
+	dirty  bool      // 最後の Sync のあとに書いたものがある
+}
+
+// ディスクへの同期（fsync）の間隔（-sync-interval）。0 なら追記のたびに同期する。
+// 追記のたびに OS へは渡す（Flush）ので、ロガーが落ちても書いた分は残る。間隔の分だけ失いうるのは
+// OS ごと止まった（電源断・カーネルパニック）ときだけ。閉じるとき（終了・シグナル・切り替え）は必ず同期する。
+var syncInterval = 5 * time.Second
+
+func (l *logFile) file() *logFile { return l }
+
+// prefix_YYYYMMDD_HHMMSS.ext という名前で dir に新しいファイルを作り、head を書く。
+// 同じ秒に切り替えた（{"cmd":"rotate"} を続けて送った等）ときは、今のファイルを上書きしないよう
+// prefix_YYYYMMDD_HHMMSS_02.ext, _03 ... と番号を付ける（名前順＝作った順のまま）
+func createLogFile(dir, prefix, ext, head string) (*logFile, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	stamp := time.Now().Format("20060102_150405")
+	var path string
+	var f *os.File
+	for n := 1; ; n++ {
+		name := fmt.Sprintf("%s_%s%s", prefix, stamp, ext)
+		if n > 1 {
+			name = fmt.Sprintf("%s_%s_%02d%s", prefix, stamp, n, ext)
+		}
+		path = filepath.Join(dir, name)
+		var err error
+		f, err = os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, os.ErrExist) || n == 99 {
+			return nil, err
+		}
+	}
 	w := bufio.NewWriter(f)
-	if _, err := w.WriteString("[\n"); err != nil {
+	if _, err := w.WriteString(head); err != nil {
 		f.Close()
 		return nil, err
 	}
@@ -82,232 +192,1015 @@ func newJSONArrayWriter() (*jsonArrayWriter, error) {
 		f.Close()
 		return nil, err
 	}
-	return &jsonArrayWriter{path: path, f: f, w: w}, nil
+	now := time.Now()
+	return &logFile{path: path, f: f, w: w, opened: now, size: int64(len(head)), synced: now}, nil
+}
+
+// b を書いて OS に渡し、syncInterval が経っていればディスクまで同期する
+func (l *logFile) writeSync(b []byte) error {
+	if _, err := l.w.Write(b); err != nil {
+		return err
+	}
+	l.size += int64(len(b))
+	if err := l.w.Flush(); err != nil {
+		return err
+	}
+	l.dirty = true
+	return l.syncIfDue(time.Now())
+}
+
+// 前回の同期から syncInterval 経っていて、まだ同期していない書き込みがあれば同期する（メインループからも呼ぶ）
+func (l *logFile) syncIfDue(now time.Time) error {
+	if !l.dirty || (syncInterval > 0 && now.Sub(l.synced) < syncInterval) {
+		return nil
+	}
+	if err := l.f.Sync(); err != nil {
+		return err
+	}
+	l.synced, l.dirty = now, false
+	return nil
+}
+
+// tail を書いて同期し、閉じる
+func (l *logFile) closeSync(tail string) error {
+	if _, err := l.w.WriteString(tail); err != nil {
+		return err
+	}
+	if err := l.w.Flush(); err != nil {
+		return err
+	}
+	if err := l.f.Sync(); err != nil {
+		l.f.Close()
+		return err
+	}
+	return l.f.Close()
+}
+
+/********** JSON配列ファイル ライター（セッション用） **********/
+type jsonArrayWriter struct {
+	logFile
+	wroteFirst bool
+}
+
+func newJSONArrayWriter(dir string) (*jsonArrayWriter, error) {
+	return newJSONArrayFile(dir, "activity")
+}
+
+// prefix_YYYYMMDD_HHMMSS.json という名前で dir に新しいJSON配列ファイルを作る
+func newJSONArrayFile(dir, prefix string) (*jsonArrayWriter, error) {
+	lf, err := createLogFile(dir, prefix, ".json", "[\n")
+	if err != nil {
+		return nil, err
+	}
+	return &jsonArrayWriter{logFile: *lf}, nil
 }
 
 func (j *jsonArrayWriter) AppendSession(s *session) error {
-	b, err := json.Marshal(s)
+	return j.Append(s)
+}
+
+// 任意の値を配列の要素として1つ追記する
+func (j *jsonArrayWriter) Append(v any) error {
+	var b []byte
+	var err error
+	if j.pretty {
+		// 配列の中の要素なので1段下げてインデントする（区切りの ",\n" はそのまま使える）
+		b, err = json.MarshalIndent(v, "  ", "  ")
+		b = append([]byte("  "), b...)
+	} else {
+		b, err = json.Marshal(v)
+	}
 	if err != nil {
 		return err
 	}
 	if j.wroteFirst {
-		if _, err := j.w.WriteString(",\n"); err != nil {
-			return err
-		}
+		b = append([]byte(",\n"), b...)
 	} else {
 		j.wroteFirst = true
 	}
-	if _, err := j.w.Write(b); err != nil {
-		return err
-	}
-	if err := j.w.Flush(); err != nil {
-		return err
-	}
-	return j.f.Sync()
+	return j.writeSync(b)
 }
 
 func (j *jsonArrayWriter) Close() error {
-	if _, err := j.w.WriteString("\n]\n"); err != nil {
-		return err
+	return j.closeSync("\n]\n")
+}
+
+/********** JSON Lines ファイル ライター（SHIRUSIA_OUTPUT=ndjson） **********/
+// 1行に1セッションを書く（同期は -sync-interval ごと）。開き・閉じ括弧がないので修復はいらない。
+type jsonLinesWriter struct {
+	logFile
+}
+
+// activity_YYYYMMDD_HHMMSS.ndjson を dir に作る
+func newJSONLinesWriter(dir string) (*jsonLinesWriter, error) {
+	lf, err := createLogFile(dir, "activity", ".ndjson", "")
+	if err != nil {
+		return nil, err
 	}
-	if err := j.w.Flush(); err != nil {
+	return &jsonLinesWriter{logFile: *lf}, nil
+}
+
+func (j *jsonLinesWriter) AppendSession(s *session) error {
+	b, err := json.Marshal(s)
+	if err != nil {
 		return err
 	}
-	return j.f.Close()
+	return j.writeSync(append(b, '\n'))
+}
+
+func (j *jsonLinesWriter) Close() error {
+	return j.closeSync("")
 }
 
 /********** メイン **********/
 func main() {
-	fmt.Println("Activity logger (sessions + Slack self messages) started. Ctrl+C to stop.")
+	// サブコマンド（ロガー本体は引数なし／フラグのみで起動）
+	var watchOut *jsonlWriter
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "watch":
+			// ロガー本体をそのまま動かし、セッションを標準出力へも流す（watch.go）
+			watchOut = startWatchOutput()
+			os.Args = append(os.Args[:1:1], os.Args[2:]...)
+		case "suggest":
+			os.Exit(runSuggest(os.Args[2:]))
+		case "anonymize":
+			os.Exit(runAnonymize(os.Args[2:]))
+		case "report":
+			os.Exit(runReport(os.Args[2:]))
+		case "summarize":
+			os.Exit(runSummarize(os.Args[2:]))
+		case "export-ics":
+			os.Exit(runExportICS(os.Args[2:]))
+		case "import-screentime":
+			os.Exit(runImportScreenTime(os.Args[2:]))
+		}
+	}
 
-	jw, err := newJSONArrayWriter()
+	bucketSize := flag.Duration("buckets", 0, "emit per-bucket rollups of the dominant activity (e.g. 1m); 0 disables")
+	bucketsOnly := flag.Bool("buckets-only", false, "write only bucket rollups, no session file (requires -buckets)")
+	detectCalls := flag.Bool("detect-calls", false, "tag sessions with onCall when the camera or microphone is in use")
+	detectSharing := flag.Bool("detect-sharing", false, "tag sessions with sharing while the screen is being recorded or shared")
+	privateMode := flag.Bool("private", false, "privacy mode: keep only start, durationSec (both rounded to 5 minutes), activity and a hashed app name; no titles, meta or Slack messages")
+	fields := flag.String("fields", "", "comma-separated session fields to write (start and durationSec are required); empty writes all")
+	useSyslog := flag.Bool("syslog", false, "also send each finalized session to the system log")
+	syslogFacility := flag.String("syslog-facility", "user", "syslog facility (user, daemon, local0..local7)")
+	syslogTag := flag.String("syslog-tag", "shirusia", "syslog tag")
+	track := flag.String("track", "title", "session boundary: title (app/title/category), app, or category")
+	pretty := flag.Bool("pretty", false, "indent each object in the session/bucket files (compact by default)")
+	idleAfter := flag.Duration("idle", 2*time.Minute, "treat no keyboard/mouse input for this long as idle (0 disables)")
+	warnLong := flag.Duration("warn-long", 2*time.Hour, "warn when a finalized session is longer than this (0 disables)")
+	httpAddr := flag.String("http", "", "serve the timeline UI and JSON endpoints on this address (e.g. 127.0.0.1:8765)")
+	withRole := flag.Bool("focused-role", false, "record the dominant focused UI element role (AXRole) in meta.focusedRole (extra AX query per tick)")
+	withTags := flag.Bool("tags", false, "store all matching rule categories and facet tags in each session")
+	dailySummary := flag.Bool("daily-summary", true, "write summary_YYYYMMDD.json (time per category, app and top titles) for the previous day at midnight")
+	summaryEvery := flag.Duration("summary-interval", 0, "print today's time per category to the console every interval while running (e.g. 30m); 0 disables")
+	selfProfile := flag.Duration("self-profile", 0, "periodically log the logger's own CPU/memory and osascript spawn count (e.g. 1m)")
+	postURL := flag.String("post-url", "", "POST finalized sessions as JSON arrays to this collector URL")
+	postBatch := flag.Int("post-batch", 20, "max sessions per POST for -post-url")
+	postWindow := flag.Duration("post-window", 30*time.Second, "max time to hold sessions before a POST for -post-url")
+	pprofAddr := flag.String("pprof", "", "debug only: serve net/http/pprof on this address (\":6060\" binds to localhost)")
+	printCfg := flag.Bool("print-config", false, "print the effective configuration as JSON (tokens redacted) and exit")
+	debounce := flag.Duration("debounce", pollInterval, "start a new session only when a change is still there on the next poll this long after it was first seen (0 disables)")
+	switchGraceDur := flag.Duration("switch-grace", 0, "keep the current session when switching to another app and returning within this long (e.g. 5s); 0 disables")
+	tabDwell := flag.Duration("tab-dwell", 0, "start a new session on a browser tab change only after staying on the tab this long (e.g. 5s); 0 disables")
+	humanDur := flag.Bool("human-durations", false, "also write durationHuman (e.g. \"1h23m45s\") next to durationSec in each session")
+	currentFile := flag.String("current-file", defaultCurrentFile(), "atomically rewrite this JSON file with the current activity on every session change (\"\" disables)")
+	endReason := flag.Bool("end-reason", false, "record why each session ended (app, title, idle, shutdown, ...) in endReason")
+	controlStdin := flag.Bool("control-stdin", false, "accept newline-delimited JSON commands (rotate, away, back, snapshot) on stdin and reply with JSON acks on stdout")
+	withSeq := flag.Bool("seq", false, "number sessions 1, 2, 3... within each session file (seq) to detect gaps or reordering")
+	useFocusEvents := flag.Bool("focus-events", false, "react to app activation events immediately and poll only as a coarse fallback for title changes (macOS)")
+	withOtherTabs := flag.Bool("other-tabs", false, "record the active tabs of the other browser windows (e.g. reference material on a second monitor) in meta.otherTabs")
+	withInput := flag.Bool("input-mode", false, "record whether keyboard or pointer input dominated each session in meta.inputMode (extra osascript per tick)")
+	withSpace := flag.Bool("space", false, "record the active macOS Space (virtual desktop) number in each session (yabai or com.apple.spaces)")
+	maxFileSize := flag.String("max-file-size", "", "also switch to a new session file when the current one reaches this size (e.g. 50MB); empty disables")
+	dailyFiles := flag.Bool("rotate-daily", rotateDaily, "switch to a new session file when the local date changes (false keeps one file per run unless -max-file-size)")
+	retain := flag.Int("retain", 0, "keep only the newest N activity_*.json(.gz)/.ndjson files in the log directory (0 keeps all)")
+	replayFile := flag.String("replay", "", "replay a recorded activity_*.json/.ndjson instead of reading the frontmost window (testing)")
+	replaySpeed := flag.Float64("replay-speed", 1, "replay speed multiplier for -replay (e.g. 60 = one minute per second)")
+	interval := flag.Duration("interval", pollInterval, "read the frontmost window this often (Go duration, at least 250ms); overrides SHIRUSIA_INTERVAL")
+	syncEvery := flag.Duration("sync-interval", syncInterval, "fsync the session/bucket files at most this often; writes still reach the OS on every session (0 = fsync after every session)")
+	flag.Parse()
+	fieldSet, err := parseSessionFields(*fields)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "failed to prepare log: %v\n", err)
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "-fields: %v\n", err)
+		os.Exit(2)
+	}
+	sessionFieldSet = fieldSet
+	if *privateMode {
+		if fieldSet != nil {
+			fmt.Fprintln(os.Stderr, "-private cannot be combined with -fields (it already limits the fields)")
+			os.Exit(2)
+		}
+		sessionFieldSet = privateFields
+		*currentFile = "" // 進行中のアプリ・タイトルを書いてしまうので使わない
+	}
+	if *interval < minPollInterval {
+		fmt.Fprintf(os.Stderr, "-interval: %s is too short (minimum %s)\n", *interval, minPollInterval)
+		os.Exit(2)
+	}
+	debounceSet := false
+	flag.Visit(func(f *flag.Flag) { debounceSet = debounceSet || f.Name == "debounce" })
+	if !debounceSet {
+		*debounce = *interval // 既定はポーリング1回分
+	}
+	pollInterval = *interval
+	humanDurations = *humanDur
+	syncInterval = *syncEvery
+	rotateDaily = *dailyFiles
+	maxFileBytes, err := parseByteSize(*maxFileSize)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "-max-file-size: %v\n", err)
+		os.Exit(2)
+	}
+	recordEndReason = *endReason
+	mode, err := parseTrackMode(*track)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if *printCfg {
+		if err := printConfig(mode); err != nil {
+			fmt.Fprintf(os.Stderr, "print-config: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if *bucketsOnly && *bucketSize <= 0 {
+		fmt.Fprintln(os.Stderr, "-buckets-only requires -buckets")
+		os.Exit(2)
 	}
-	fmt.Printf("Logging sessions to: %s\n", jw.path)
 
-	// Slack取り込み（Socket Mode、自分の投稿のみ or 全保存デバッグ）をバックグラウンド起動
-	go startSlackIngest()
+	// 再生モードでは仮想時計で動かす（clock が現在時刻、scale が仮想時間→実時間の換算）
+	clock := time.Now
+	scale := func(d time.Duration) time.Duration { return d }
+	var rp *replaySource
+	if *replayFile != "" {
+		rp, err = newReplaySource(*replayFile, *replaySpeed)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "replay: %v\n", err)
+			os.Exit(1)
+		}
+		clock = rp.Now
+		scale = rp.Scale
+		*idleAfter = 0 // アイドルは記録側の idle セッションで再現する
+		fmt.Printf("Replaying %s at %gx\n", *replayFile, *replaySpeed)
+	}
+
+	// 前面ウィンドウを取れない（osascript や xprop がない）と毎ティック失敗するだけなので、起動時に一度だけ分かりやすく止める
+	var winSrc WindowSource // windowsource.go
+	if rp == nil {
+		winSrc, err = newWindowSource()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	// 無操作の時間を読めない OS では、毎ティック失敗させずに -idle を切る（idle.go）
+	if *idleAfter > 0 {
+		if _, err := inputIdleTime(); errors.Is(err, errIdleUnsupported) {
+			fmt.Fprintf(os.Stderr, "warn: -idle: %v; idle detection disabled\n", err)
+			*idleAfter = 0
+		}
+	}
+
+	fmt.Println("Activity logger (sessions + Slack self messages) started. Ctrl+C to stop.")
+	fmt.Printf("Polling every %s\n", pollInterval)
+
+	// 前回クラッシュして閉じていないファイルを、新しいファイルを作る前に直しておく（repair.go）
+	repairSessionFiles(logDir)
+
+	var jw sessionSink // ファイルなら sessionFileSink（日付・大きさで切り替える）
+	if !*bucketsOnly && outputFormat == "sqlite" {
+		db, err := openSQLiteSink(logDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to prepare log: %v\n", err)
+			os.Exit(1)
+		}
+		jw, messageDB = db, db
+		fmt.Printf("Logging sessions to: %s\n", db.path)
+	} else if !*bucketsOnly {
+		w, err := newSessionFile(logDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to prepare log: %v\n", err)
+			os.Exit(1)
+		}
+		jw = w
+		w.file().pretty = *pretty
+		fmt.Printf("Logging sessions to: %s\n", w.file().path)
+		pruneSessionFiles(logDir, *retain, w.file().path)
+	}
+
+	// 時間バケット集計（-buckets 指定時のみ）
+	var buckets *bucketAccumulator
+	var bw *jsonArrayWriter
+	if *bucketSize > 0 {
+		w, err := newJSONArrayFile(logDir, "buckets")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to prepare bucket log: %v\n", err)
+			os.Exit(1)
+		}
+		bw = w
+		bw.pretty = *pretty
+		buckets = newBucketAccumulator(*bucketSize)
+		fmt.Printf("Logging %s buckets to: %s\n", *bucketSize, bw.path)
+	}
+
+	// syslog 出力（-syslog 指定時のみ）
+	var sl *syslogSink
+	if *useSyslog {
+		w, err := newSyslogSink(*syslogFacility, *syslogTag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to open syslog: %v\n", err)
+			os.Exit(1)
+		}
+		sl = w
+		fmt.Printf("Sending sessions to syslog (facility=%s, tag=%s)\n", *syslogFacility, *syslogTag)
+	}
+
+	// 収集サーバへの送信（-post-url 指定時のみ）
+	var hs *httpSink
+	if *postURL != "" {
+		hs = newHTTPSink(*postURL, *postBatch, *postWindow)
+		fmt.Printf("Posting sessions to %s (batch=%d, window=%s)\n", *postURL, *postBatch, *postWindow)
+	}
+
+	// HTTPサーバ（-http 指定時のみ）。メインループが live を更新し、ハンドラが読む
+	appIntervals := loadAppIntervals()
+	live := newLiveState(healthStaleAfter(appIntervals))
+	live.private = *privateMode
+	if hs != nil {
+		live.AddHealthCheck("post", hs.Err)
+	}
+	var srv *http.Server
+	if *httpAddr != "" {
+		srv = newHTTPServer(*httpAddr, live)
+		go func() {
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				fmt.Fprintf(os.Stderr, "http server error: %v\n", err)
+			}
+		}()
+		fmt.Printf("Serving timeline on http://%s/\n", *httpAddr)
+	}
+
+	// -seq の通し番号。秒単位の時刻が重なっても順序や欠けが分かるよう、確定順に振る（ファイルが切り替わったら1から）
+	seq := 0
+
+	var privRounder privateRounder // -private の端数の持ち越し
+
+	// 確定したセッションを書き出す（セッションファイル・バケット集計・syslog・収集サーバ・HTTP用状態）
+	finalize := func(r *record, start, end time.Time, reason string) (session, error) {
+		s := sessionFrom(r, start, end)
+		if recordEndReason {
+			s.EndReason = reason
+		}
+		// 常駐時は終了時刻の日付が変わっていたら（-max-file-size を超えたときも）ファイルを切り替える。
+		// 書き込む前に切り替えるので、日付をまたいだセッションは新しいファイルに入る。0:00 に区切った前日分
+		// （23:59:59.999 まで、midnight.go）は今のファイルに入る（再生中は仮想時刻なので切り替えない）
+		if f, ok := jw.(sessionFileSink); ok && rp == nil {
+			w, err := rotateSessionFile(f, end, *retain, maxFileBytes)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "log rotation failed: %v\n", err)
+			}
+			if w != f {
+				seq = 0
+			}
+			jw = w
+		}
+		if *withSeq {
+			seq++
+			s.Seq = seq
+		}
+		if *privateMode {
+			p, ok := privRounder.Round(s, start)
+			if !ok {
+				return p, nil // 端数を持ち越しても0分のセッションは書かない（privatemode.go）
+			}
+			s = p
+		}
+		warnSuspiciousDuration(s, end.Sub(start), *warnLong)
+		live.AddSession(s, end)
+		if sl != nil {
+			err := sl.AppendSession(&s)
+			live.SinkResult("syslog", err)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "syslog error: %v\n", err)
+			}
+		}
+		if hs != nil {
+			if err := hs.AppendSession(&s); err != nil {
+				fmt.Fprintf(os.Stderr, "post error: %v\n", err)
+			}
+		}
+		if watchOut != nil {
+			err := watchOut.AppendSession(&s)
+			live.SinkResult("stdout", err)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "stdout error: %v\n", err)
+			}
+		}
+		if buckets != nil {
+			for _, b := range buckets.Add(s.Activity, start, end) {
+				err := bw.Append(&b)
+				live.SinkResult("buckets", err)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "bucket log error: %v\n", err)
+				}
+			}
+		}
+		if jw == nil {
+			return s, nil
+		}
+		err := jw.AppendSession(&s)
+		live.SinkResult("log", err)
+		return s, err
+	}
+
+	// Slack取り込み（Socket Mode、自分の投稿のみ or 全保存デバッグ）をバックグラウンド起動。
+	// 終了時（sinks.Close）に stopSlack で再接続のループを止める
+	stopSlack := func() {}
+	if rp == nil && !*privateMode {
+		ctx, cancel := context.WithCancel(context.Background())
+		stopSlack = cancel
+		go runGuarded("slack ingest", func() { startSlackIngest(ctx) })
+	}
+
+	if *pprofAddr != "" {
+		startPprof(*pprofAddr)
+	}
+	if *selfProfile > 0 {
+		stopProfile := make(chan struct{})
+		defer close(stopProfile)
+		go runSelfProfile(*selfProfile, stopProfile)
+	}
+	if *summaryEvery > 0 {
+		stopSummary := make(chan struct{})
+		defer close(stopSummary)
+		go runGuarded("summary", func() { runSummaries(scale(*summaryEvery), live, clock, stopSummary) })
+	}
 
 	// 終了シグナルで最後のセッションを閉じる
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	if watchOut != nil {
+		signal.Notify(sigCh, syscall.SIGPIPE) // パイプの読み手が終わったら通常どおり閉じて終了する
+	}
+
+	open := &openSession{} // 進行中のセッション（shutdown.go）
+
+	// アプリ別の間隔上書きに対応するため、Tickerではなく毎回Resetするタイマーで回す
+	tabs := &tabDebouncer{dwell: *tabDwell}
+	grace := &switchGrace{grace: *switchGraceDur}
+	flicker := &flickerDebouncer{wait: *debounce}
+	summaryDay := startOfDay(time.Now()) // 日次サマリを書いていない日（-daily-summary）
+	today := startOfDay(time.Now())      // 0:00 の区切り（midnight.go）
+	var calls *callDetector
+	if *detectCalls && rp == nil {
+		calls = &callDetector{}
+	}
+	var locks *lockDetector // 画面ロックで区切るのはアイドルか終わった理由を記録するときだけ（sleep.go）
+	if (*idleAfter > 0 || *endReason) && rp == nil {
+		locks = &lockDetector{probe: screenLocked}
+	}
+	var otherTabs *otherTabsCollector
+	if *withOtherTabs && rp == nil {
+		otherTabs = &otherTabsCollector{}
+	}
+	var inputs *inputSampler
+	if *withInput && rp == nil {
+		inputs = &inputSampler{}
+	}
+	var sharing *sharingDetector
+	if *detectSharing && rp == nil {
+		sharing = newSharingDetector()
+	}
+	timer := time.NewTimer(scale(pollInterval))
+	defer timer.Stop()
+
+	// 前面アプリ切り替えの通知（-focus-events、再生中は使わない）
+	var focusEvents <-chan string
+	var focusObs *focusObserver
+	if *useFocusEvents && rp == nil {
+		obs, err := startFocusObserver()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warn: %v (falling back to polling)\n", err)
+		} else {
+			focusObs = obs
+			focusEvents = obs.Events
+			fmt.Printf("Following app activation events (polling every %s for title changes)\n", focusEventFallback)
+		}
+	}
+	// 前面アプリに応じた次のポーリングまでの間隔（-app-intervals・-focus-events・再生速度を反映する）
+	pollDelay := func(app string) time.Duration {
+		return scale(pollDelayWithEvents(nextPollDelay(app, appIntervals), focusEvents != nil))
+	}
+
+	// 進行中のセッションを end で閉じ、next を at から始める（ふつうは end == at。0:00 の区切りだけ end を 1ms 前にする）
+	switchAt := func(next *record, end, at time.Time, note string) {
+		open.switchAt(next, end, at, func(prev *record, start, end time.Time) {
+			s, err := finalize(prev, start, end, endReasonFor(prev, next, note))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "log error%s: %v\n", paren(note), err)
+				return
+			}
+			fmt.Printf("%s | end   | %s | dur=%ds%s\n",
+				end.Format(time.RFC3339), s.Activity, s.DurationSec, spaced(paren(note)))
+			live.events.Publish(endEvent(s, end, note))
+		})
+		grace.Reset()
+		flicker.Reset()
+		live.SetCurrent(open.last, open.start)
+		if *currentFile != "" && rp == nil { // 再生中は実際の状態ではないので書かない
+			if err := writeCurrentStatus(*currentFile, open.last, open.start, at); err != nil {
+				fmt.Fprintf(os.Stderr, "current file error: %v\n", err)
+			}
+		}
+		if open.last != nil && *privateMode {
+			fmt.Printf("%s | start | %s\n", at.Format(time.RFC3339), open.last.Activity)
+		} else if open.last != nil {
+			fmt.Printf("%s | start | %s | %s — %s\n",
+				at.Format(time.RFC3339), open.last.Activity, open.last.App, short(open.last.Title, 80))
+		}
+		if open.last != nil {
+			live.events.Publish(startEvent(open.last, at, *privateMode))
+		}
+	}
+	switchTo := func(next *record, at time.Time, note string) {
+		switchAt(next, at, at, note)
+	}
+
+	// 出力先をすべて閉じる（通常終了時とパニック時。1回だけ、shutdown.go）
+	sinks := &sinkCloser{closeAll: func() {
+		stopSlack()
+		if focusObs != nil {
+			focusObs.Stop()
+		}
+		if srv != nil {
+			if err := shutdownHTTPServer(srv); err != nil {
+				fmt.Fprintf(os.Stderr, "http shutdown error: %v\n", err)
+			}
+		}
+
+		if buckets != nil {
+			// 途中のバケットも書き出して閉じる
+			if b, ok := buckets.Flush(); ok {
+				if err := bw.Append(&b); err != nil {
+					fmt.Fprintf(os.Stderr, "bucket log error(on exit): %v\n", err)
+				}
+			}
+			if err := bw.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "close error: %v\n", err)
+			}
+		}
+		if sl != nil {
+			sl.Close()
+		}
+		if hs != nil {
+			hs.Close() // 残りを送り切ってから閉じる
+		}
+		if jw != nil {
+			if err := jw.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "close error: %v\n", err)
+			}
+		}
+	}}
+
+	// メインループがパニックしたら、進行中のセッションを書き出してファイルを閉じてから再パニックする
+	defer func() {
+		if p := recover(); p != nil {
+			logPanic("main loop", p)
+			flushOnPanic(func() {
+				switchTo(nil, time.Now(), "panic")
+				sinks.Close()
+			})
+			panic(p)
+		}
+	}()
 
-	var last *record
-	var sessStart time.Time
+	notifyAwaySignals(live)
+	away := false // 手動の離席中
 
-	ticker := time.NewTicker(pollInterval)
-	defer ticker.Stop()
+	// 標準入力からの制御（-control-stdin、control.go）。watch では ack もセッションと同じ出力に書く
+	var controlCmds <-chan controlCommand
+	var ackOut io.Writer = os.Stdout
+	if *controlStdin {
+		controlCmds = startControlReader(os.Stdin)
+		if watchOut != nil {
+			ackOut = watchOut.w
+		}
+	}
 
+	failed := false
+	lastTick := time.Now()                     // 前回のポーリング（スリープの検知用）
+	sleepGap := healthStaleAfter(appIntervals) // これより間が空いたらスリープしていたとみなす
 loop:
 	for {
 		select {
-		case <-ticker.C:
-			app, title, err := frontmostAppAndTitleWithBrowserTabs()
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "warn: %v\n", err)
+		case <-timer.C:
+			live.Tick(time.Now())
+			// スリープ明け: 止まっていた間をアイドルにする（前回のポーリングの時刻で今のセッションを閉じる。sleep.go）
+			if now := time.Now(); rp == nil && sleptBetween(lastTick, now, sleepGap) && open.last != nil && !open.last.Idle {
+				at := lastTick
+				if at.Before(open.start) {
+					at = open.start
+				}
+				switchTo(idleRecord(at), at, "sleep")
+			}
+			lastTick = time.Now()
+			// 書いたまま同期していないセッションを -sync-interval ごとにディスクへ
+			if f, ok := jw.(sessionFileSink); ok {
+				if err := f.file().syncIfDue(time.Now()); err != nil {
+					fmt.Fprintf(os.Stderr, "log sync error: %v\n", err)
+				}
+			}
+			if bw != nil {
+				if err := bw.syncIfDue(time.Now()); err != nil {
+					fmt.Fprintf(os.Stderr, "bucket log sync error: %v\n", err)
+				}
+			}
+			// 0:00 を過ぎたら、進行中のセッションを前日分と当日分に分け、セッションファイルも切り替える
+			if now := time.Now(); rp == nil && !sameDay(today, now) {
+				today = startOfDay(now)
+				if open.last != nil && open.start.Before(today) {
+					switchAt(open.last.continuation(), today.Add(-time.Millisecond), today, "midnight")
+				}
+				if f, ok := jw.(sessionFileSink); ok {
+					w, err := rotateSessionFile(f, now, *retain, maxFileBytes)
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "log rotation failed: %v\n", err)
+					}
+					if w != f {
+						seq = 0
+					}
+					jw = w
+				}
+			}
+			// 日付が変わったら前日分の日次サマリを書く（進行中だったセッションの前日分は上で書き終えている）
+			if now := time.Now(); *dailySummary && rp == nil && !*bucketsOnly && !sameDay(summaryDay, now) {
+				day := summaryDay
+				summaryDay = startOfDay(now)
+				go runGuarded("daily summary", func() { writeDailySummaryOnRollover(logDir, day, defaultSummaryTop) })
+			}
+			if away {
+				timer.Reset(scale(pollInterval)) // 離席中は取得しない（/back まで「離席」のまま）
 				continue
 			}
-			activity := classifyActivity(app, title)
-			now := time.Now()
-			cur := &record{App: app, Title: title, Activity: activity, Timestamp: now}
-
-			if last == nil {
-				last = cur
-				sessStart = now
-				fmt.Printf("%s | start | %s | %s — %s\n",
-					now.Format(time.RFC3339), last.Activity, last.App, short(last.Title, 80))
+			// 画面ロック中は閾値を待たずにアイドル期間にする（sleep.go）
+			if locks != nil && locks.Locked(time.Now()) {
+				timer.Reset(scale(pollInterval))
+				if open.last == nil || !open.last.Idle {
+					now := clock()
+					switchTo(idleRecord(now), now, "lock")
+				}
 				continue
 			}
+			// 無操作が閾値を超えたら、入力が止まった時刻で今のセッションを閉じてアイドル期間にする。
+			// 通話中（カメラ/マイク使用中）は操作がなくても会議とみなしてアイドルにしない
+			if *idleAfter > 0 {
+				now := clock()
+				if idle, err := inputIdleTime(); err == nil && idle >= *idleAfter &&
+					(calls == nil || !calls.OnCall(now)) {
+					timer.Reset(scale(pollInterval))
+					if open.last == nil || !open.last.Idle {
+						at := now.Add(-idle)
+						if open.last != nil && at.Before(open.start) {
+							at = open.start
+						}
+						switchTo(idleRecord(at), at, "idle")
+					}
+					continue
+				}
+			}
 
-			if changed(last, cur) {
-				// 前セッションを確定
-				s := sessionFrom(last, sessStart, now)
-				if err := jw.AppendSession(&s); err != nil {
-					fmt.Fprintf(os.Stderr, "log error: %v\n", err)
-				} else {
-					fmt.Printf("%s | end   | %s | dur=%ds\n",
-						now.Format(time.RFC3339), last.Activity, s.DurationSec)
+			var app, title, bundleID, pageURL string
+			selfFront := false // ロガー自身の画面が前面
+			if rp != nil {
+				ob, done := rp.At(clock())
+				if done {
+					switchTo(nil, rp.End(), "replay end")
+					break loop
+				}
+				if ob.idle {
+					timer.Reset(scale(pollInterval))
+					if open.last == nil || !open.last.Idle {
+						at := ob.at
+						if open.last != nil && at.Before(open.start) {
+							at = open.start
+						}
+						switchTo(idleRecord(at), at, "idle")
+					}
+					continue
+				}
+				app, title = ob.app, ob.title
+			} else {
+				fw, err := readFrontWindow(winSrc)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "warn: %v\n", err)
+					if errors.Is(err, ErrNotPermitted) {
+						fmt.Fprintf(os.Stderr, "hint: %s\n", notPermittedHint) // 文言はローカライズされているので対処は別の行に出す
+					}
+					prev := ""
+					if open.last != nil {
+						prev = open.last.App // 取れなかった間は進行中のセッションのアプリの間隔で取り直す
+					}
+					timer.Reset(pollDelay(prev))
+					continue
+				}
+				app, title, pageURL = fw.App, fw.Title, sanitizeURL(fw.URL) // 分類にもセッションにも整形後のURLだけを使う
+				bundleID = bundleIDOf(app)
+				if isSelfWindow(fw, bundleID) {
+					// ロガー自身の画面（統計を見ている時間）は設定に応じて除外 or 専用カテゴリ
+					timer.Reset(pollDelay(normalizeAppName(app)))
+					if selfWindowMode == selfModeExclude {
+						if open.last != nil {
+							switchTo(nil, clock(), "self")
+						}
+						continue
+					}
+					app, title, bundleID, pageURL = selfAppLabel, "", "", ""
+					selfFront = true
+				} else if fw.Private {
+					if privateWindowMode == privateModeSkip {
+						// シークレットウィンドウの間は何も記録しない
+						timer.Reset(pollDelay(normalizeAppName(app)))
+						if open.last != nil {
+							switchTo(nil, clock(), "private")
+						}
+						continue
+					}
+					app, title, bundleID = maskPrivateWindow(app, title, bundleID)
+					pageURL = ""
+				}
+			}
+			rawApp := app
+			app = normalizeAppName(app)
+			timer.Reset(pollDelay(app))
+			in := activityInput{App: app, BundleID: bundleID, Title: title, URL: pageURL}
+			title = gameTitle(in)
+			in.Title = title
+			now := clock()
+			cls := classifyMatch(in)
+			activity, by := remapByTime(cls.Category, now), cls.MatchedBy
+			if activity != cls.Category {
+				by += ",time" // SHIRUSIA_TIME_OVERRIDES で置き換えた
+			}
+			if selfFront {
+				activity, by = selfActivity, "self"
+			}
+			// 時間帯の上書きで別のカテゴリにしても、分類が金融なら伏せる
+			if cls.Category == financeActivity || activity == financeActivity {
+				title = redactFinanceTitle(title) // 残高や口座番号をログに残さない
+				pageURL = ""
+				by = matchedByKind(by) // 一致したホストやキーワードも残さない
+			}
+			if shouldRedact(app, bundleID, title, pageURL) { // redact.go
+				if title != "" {
+					title = redactedTitle
+				}
+				pageURL = ""
+				by = matchedByKind(by)
+			}
+			cur := &record{App: app, RawApp: rawApp, BundleID: bundleID, Title: title, URL: pageURL, Activity: activity, MatchedBy: by, Timestamp: now}
+			cur.applyTrigger(matchTitleTrigger(title))
+			if *withTags {
+				cur.Tags = classifyTags(in)
+			}
+			role := ""
+			if *withRole && rp == nil {
+				role = focusedRole()
+			}
+			var others []string
+			if otherTabs != nil {
+				others = otherTabs.Tabs(now, rawApp)
+			}
+			if calls != nil {
+				cur.OnCall = calls.OnCall(now)
+			}
+			if sharing != nil {
+				cur.Sharing = sharing.Sharing(now)
+			}
+			if *withSpace && rp == nil {
+				cur.Space = currentSpace()
+			}
+			// 前回のティックからの入力は、その間前面だった進行中のセッションのもの
+			if inputs != nil {
+				if d := inputs.Sample(); open.last != nil && !open.last.Idle {
+					open.last.Input.add(d)
 				}
-				// 新しいセッション開始
-				last = cur
-				sessStart = now
-				fmt.Printf("%s | start | %s | %s — %s\n",
-					now.Format(time.RFC3339), last.Activity, last.App, short(last.Title, 80))
 			}
 
-		case <-sigCh:
-			now := time.Now()
-			if last != nil {
-				s := sessionFrom(last, sessStart, now)
-				if err := jw.AppendSession(&s); err != nil {
-					fmt.Fprintf(os.Stderr, "log error(on exit): %v\n", err)
-				} else {
-					fmt.Printf("%s | end   | %s | dur=%ds (on exit)\n",
-						now.Format(time.RFC3339), last.Activity, s.DurationSec)
+			step := nextTickStep(mode, open.last, cur) // track.go
+			if step == tickSkip {
+				continue
+			}
+
+			cur.noteApp(cur.App)
+			cur.noteRole(role)
+			cur.noteOtherTabs(others)
+			cur.noteTrack(cur.Title)
+			if cur.Activity == meetingActivity && isZoomApp(cur.App) && rp == nil {
+				cur.noteMeeting(zoomMeetingInfo(now))
+			}
+			if step == tickSame {
+				open.last.noteApp(cur.App)
+				open.last.noteRole(role)
+				open.last.noteOtherTabs(others)
+				open.last.noteTrack(cur.Title)
+				open.last.noteMeeting(zoomInfo{topic: cur.Meeting, participants: cur.Attendees})
+				tabs.Reset()
+				grace.Return(open.last, now)
+				flicker.Reset()
+				continue
+			}
+			seen, hold := flicker.Observe(mode, open.last, cur, now)
+			if hold {
+				continue // 一瞬だけのタイトルかもしれない（次のポーリングでも続いていれば区切る）
+			}
+			at, hold := grace.Observe(open.last, cur, now)
+			if hold {
+				continue // ちら見かもしれない別アプリ（戻ってくれば進行中のセッションに含める）
+			}
+			next, at, hold := tabs.Observe(open.last, cur, at)
+			if hold {
+				continue // 通り過ぎただけかもしれないタブ（時間は進行中のセッションに含める）
+			}
+			if seen.Before(at) {
+				at = seen // 確認のために待った分はさかのぼる
+			}
+			switchTo(next, at, "")
+
+		case a := <-live.AwayRequests():
+			if a == away {
+				continue
+			}
+			away = a
+			now := clock()
+			if away {
+				switchTo(awayRecord(now), now, "away")
+			} else {
+				switchTo(nil, now, "back") // 次のティックで前面アプリから新しいセッションを始める
+				timer.Reset(0)
+			}
+
+		case cmd, ok := <-controlCmds:
+			if !ok {
+				controlCmds = nil // 標準入力が閉じた。記録は続ける
+				continue
+			}
+			ack := controlAck{ID: cmd.ID, Cmd: cmd.Cmd, OK: cmd.err == "", Error: cmd.err}
+			switch {
+			case !ack.OK:
+			case cmd.Cmd == "rotate":
+				f, ok := jw.(sessionFileSink)
+				if !ok {
+					ack.OK, ack.Error = false, "no session file (-buckets-only or SHIRUSIA_OUTPUT=sqlite)"
+					break
+				}
+				w, err := switchSessionFile(f, *retain)
+				if err != nil {
+					ack.OK, ack.Error = false, err.Error()
+					break
+				}
+				jw, seq = w, 0
+				ack.File = w.file().path
+			case cmd.Cmd == "away" || cmd.Cmd == "back":
+				if !live.RequestAway(cmd.Cmd == "away") {
+					ack.OK, ack.Error = false, "busy, try again"
 				}
+			case cmd.Cmd == "snapshot":
+				ack.fillSnapshot(live, clock())
 			}
+			if err := writeControlAck(ackOut, ack); err != nil {
+				fmt.Fprintf(os.Stderr, "control ack error: %v\n", err)
+			}
+
+		case _, ok := <-focusEvents:
+			if !ok {
+				focusEvents = nil // 監視が終わったので通常のポーリングに戻す
+			}
+			timer.Reset(0) // すぐに取得し直す
+
+		case <-sigCh:
+			switchTo(nil, clock(), "on exit")
+			break loop
+
+		case err := <-fatalCh:
+			fmt.Fprintf(os.Stderr, "stopping: %v\n", err)
+			switchTo(nil, clock(), "panic")
+			failed = true
 			break loop
 		}
 	}
 
-	if err := jw.Close(); err != nil {
-		fmt.Fprintf(os.Stderr, "close error: %v\n", err)
-	}
+	sinks.Close()
 	fmt.Println("Stopped.")
+	if failed {
+		os.Exit(1)
+	}
 }
 
 /********** セッション化ユーティリティ **********/
+// durationSec は end と start の差（実際に経過した秒数）なので、夏時間の切り替えをまたいでも
+// 壁時計の差（1時間ずれた値）にはならない。start/end はそれぞれの時点のオフセット付きで書く
+// （例: 01:30-05:00 → 03:30-04:00 は 3600 秒）。
 func sessionFrom(r *record, start, end time.Time) session {
 	dur := end.Sub(start).Round(time.Second)
 	if dur < 0 {
 		dur = 0
 	}
-	return session{
+	activity := r.Activity
+	if r.Idle {
+		activity = idleLabel(start, end) // 休憩時間帯に重なるアイドルは「休憩」
+	}
+	s := session{
 		Start:       start.Format(time.RFC3339),
 		End:         end.Format(time.RFC3339),
 		App:         clean(r.App),
 		Title:       clean(r.Title),
-		Activity:    clean(r.Activity),
+		URL:         r.URL,
+		Activity:    clean(activity),
+		MatchedBy:   r.MatchedBy,
 		DurationSec: int64(dur / time.Second),
+		OnCall:      r.OnCall,
+		Sharing:     r.Sharing,
+		Space:       r.Space,
+		Tags:        r.Tags,
+	}
+	if r.Idle {
+		s.IdleSec = s.DurationSec
+	}
+	if humanDurations {
+		s.DurationHuman = fmtDur(s.DurationSec)
+	}
+	if raw := clean(r.RawApp); raw != "" && raw != s.App {
+		s.setMeta("rawApp", raw)
+	}
+	if len(r.Apps) > 1 {
+		s.setMeta("apps", strings.Join(r.Apps, ", "))
+	}
+	if len(r.Glances) > 0 {
+		s.setMeta("glances", glancesMeta(r.Glances))
+	}
+	if mode := r.Input.label(); mode != "" {
+		s.setMeta("inputMode", mode)
+	}
+	if role := dominantRole(r.Roles); role != "" {
+		s.setMeta("focusedRole", role)
+	}
+	if len(r.OtherTabs) > 0 {
+		s.setMeta("otherTabs", strings.Join(r.OtherTabs, " / "))
+	}
+	if len(r.Tracks) > 1 {
+		s.setMeta("tracks", strings.Join(r.Tracks, " / "))
+	}
+	if r.Trigger != "" {
+		s.setMeta("trigger", r.Trigger)
+	}
+	if r.Meeting != "" {
+		s.setMeta("meetingTopic", r.Meeting)
+	}
+	if r.Attendees > 0 {
+		s.setMeta("participants", strconv.Itoa(r.Attendees))
 	}
+	return s
 }
 
-/********** ブラウザのアクティブタブタイトル対応 **********/
-func frontmostAppAndTitleWithBrowserTabs() (string, string, error) {
-	// まず前面アプリ名
-	appScript := `
-		tell application "System Events"
-			set frontApp to name of first process whose frontmost is true
-		end tell
-		return frontApp
-	`
-	app, err := runOSA(appScript)
-	if err != nil {
-		return "", "", fmt.Errorf("get frontmost app failed: %w", err)
-	}
-	app = strings.TrimSpace(app)
-	low := strings.ToLower(app)
-
-	// Safari：現在タブのタイトル
-	if low == "safari" {
-		title, e := runOSA(`
-			tell application "Safari"
-				try
-					if (count of windows) > 0 then
-						return name of current tab of front window
-					else
-						return ""
-					end if
-				on error
-					return ""
-				end try
-			end tell
-		`)
-		if e == nil {
-			return app, strings.TrimSpace(title), nil
-		}
-	}
-
-	// Chromium系（Chrome/Edge/Brave/Vivaldi/Opera/Arc*）
-	if isChromiumBrowser(low) {
-		script := fmt.Sprintf(`
-			tell application "%s"
-				try
-					if (count of windows) > 0 then
-						return title of active tab of front window
-					else
-						return ""
-					end if
-				on error
-					return ""
-				end try
-			end tell
-		`, escapeOSA(app))
-		title, e := runOSA(script)
-		if e == nil {
-			return app, strings.TrimSpace(title), nil
-		}
-	}
-
-	// 取れない場合は従来のウインドウタイトル
-	titleScript := fmt.Sprintf(`
-		tell application "System Events"
-			tell process "%s"
-				try
-					return name of front window
-				on error
-					try
-						return value of attribute "AXTitle" of front window
-					on error
-						return ""
-					end try
-				end try
-			end tell
-		end tell
-	`, escapeOSA(app))
-	title, _ := runOSA(titleScript)
-	return app, strings.TrimSpace(title), nil
-}
-
-func isChromiumBrowser(appLower string) bool {
-	return strings.Contains(appLower, "chrome") ||
-		strings.Contains(appLower, "edge") ||
-		strings.Contains(appLower, "brave") ||
-		strings.Contains(appLower, "vivaldi") ||
-		strings.Contains(appLower, "opera") ||
-		strings.Contains(appLower, "arc")
+func (s *session) setMeta(k, v string) {
+	if s.Meta == nil {
+		s.Meta = map[string]string{}
+	}
+	s.Meta[k] = v
+}
+
+// データ品質の目安: 1秒未満はタイトルのちらつき、長すぎるものは離席を検知できていない可能性が高い
+func warnSuspiciousDuration(s session, dur, longThreshold time.Duration) {
+	switch {
+	case dur < time.Second:
+		fmt.Fprintf(os.Stderr, "warn: very short session (%s) %s — %s: likely title flicker (try -track app)\n",
+			dur.Round(time.Millisecond), s.App, short(s.Title, 40))
+	case longThreshold > 0 && dur > longThreshold:
+		fmt.Fprintf(os.Stderr, "warn: long session (%s > %s) %s — %s: idle time may not be detected\n",
+			dur.Round(time.Second), longThreshold, s.App, short(s.Title, 40))
+	}
 }
 
 /********** AppleScript 実行 **********/
+func checkOSAAvailable() error {
+	if _, err := exec.LookPath("osascript"); err != nil {
+		return errors.New("osascript not found on PATH: this logger reads the frontmost app/window via AppleScript and requires macOS " +
+			"(the ver1/ver2.1 sources have the same requirement; use -replay to run without it)")
+	}
+	return nil
+}
+
 func runOSA(script string) (string, error) {
+	osaSpawns.Add(1)
 	cmd := exec.Command("osascript", "-e", script)
 	var out, stderr bytes.Buffer
 	cmd.Stdout = &out
 	cmd.Stderr = &stderr
 	if err := cmd.Run(); err != nil {
-		return "", errors.New(strings.TrimSpace(stderr.String()))
+		return "", parseOSAError(stderr.String()) // errors.Is(err, ErrNotPermitted) などで判定できる（osaerror.go）
 	}
 	return out.String(), nil
 }
@@ -316,12 +1209,14 @@ func runOSA(script string) (string, error) {
 // 必要環境変数:
 //   SLACK_BOT_TOKEN="xoxb-..."           （Bot Token）
 //   SLACK_APP_TOKEN="xapp-..."           （App-level Token, scope: connections:write）
+//     ※ 2つのトークンは SLACK_TOKEN_FILE のファイルやキーチェーンからも読める（lookupSlackSecret 参照）
 //   SLACK_SELF_USER_ID="UXXXXXXX"        （自分のSlackユーザーID。これと一致するユーザーの投稿だけ保存）
 //   SLACK_DEBUG="1"                      （任意: 接続/イベントのデバッグ出力ON）
 //   SLACK_LOG_ALL="1"                    （任意: 一時的に自分以外も保存＝イベント到達の切り分け）
-func startSlackIngest() {
-	bot := os.Getenv("SLACK_BOT_TOKEN")
-	app := os.Getenv("SLACK_APP_TOKEN")
+//   SLACK_MAX_TEXT="10000"               （任意: 本文の最大文字数。超えた分は切り詰める、0で無制限）
+func startSlackIngest(ctx context.Context) {
+	bot := lookupSlackSecret("SLACK_BOT_TOKEN")
+	app := lookupSlackSecret("SLACK_APP_TOKEN")
 	self := os.Getenv("SLACK_SELF_USER_ID")
 	debug := strings.TrimSpace(os.Getenv("SLACK_DEBUG")) == "1"
 	logAll := strings.TrimSpace(os.Getenv("SLACK_LOG_ALL")) == "1"
@@ -347,91 +1242,107 @@ func startSlackIngest() {
 		fmt.Printf("[slack] auth test error: %v\n", err)
 	}
 
-	sm := socketmode.New(api)
+	// 切断されたり Run が返ったりしたら、待ってから作り直してつなぎ直す（ctx が終われば止める）
+	runSocketModeWithRetry(ctx, func(runCtx context.Context, disconnected func()) error {
+		sm := socketmode.New(api)
+		go runGuarded("slack events", func() {
+			for {
+				select {
+				case <-runCtx.Done():
+					return
+				case evt := <-sm.Events:
+					if evt.Type == socketmode.EventTypeDisconnect {
+						disconnected()
+					}
+					handleSlackEvent(sm, api, evt, self, debug, logAll)
+				}
+			}
+		})
+		return sm.RunContext(runCtx)
+	})
+}
 
-	go func() {
-		for evt := range sm.Events {
-			switch evt.Type {
-			case socketmode.EventTypeConnecting:
-				if debug {
-					fmt.Println("[slack] connecting…")
+// Socket Mode のイベントを1つ処理する
+func handleSlackEvent(sm *socketmode.Client, api *slack.Client, evt socketmode.Event, self string, debug, logAll bool) {
+	switch evt.Type {
+	case socketmode.EventTypeConnecting:
+		if debug {
+			fmt.Println("[slack] connecting…")
+		}
+	case socketmode.EventTypeConnected:
+		if debug {
+			fmt.Println("[slack] connected")
+		}
+	case socketmode.EventTypeHello:
+		if debug {
+			fmt.Println("[slack] hello")
+		}
+	case socketmode.EventTypeEventsAPI:
+		e, ok := evt.Data.(slackevents.EventsAPIEvent)
+		if !ok {
+			sm.Ack(*evt.Request)
+			return
+		}
+		sm.Ack(*evt.Request)
+
+		if e.Type == slackevents.CallbackEvent {
+			inner := e.InnerEvent
+			switch ev := inner.Data.(type) {
+			case *slackevents.MessageEvent:
+				// 新規・編集・削除だけを残す（bot_message などほかのサブタイプは除外。slackedit.go）
+				m, user, ok := messageFromEvent(ev)
+				if !ok {
+					if debug {
+						fmt.Printf("[slack] drop subtype=%q user=%s ch=%s\n", ev.SubType, ev.User, ev.Channel)
+					}
+					break
 				}
-			case socketmode.EventTypeConnected:
-				if debug {
-					fmt.Println("[slack] connected")
+				// 本文が空は除外（削除は本文を持たない）
+				if m.Direction != slackDeleted && strings.TrimSpace(m.Text) == "" {
+					if debug {
+						fmt.Printf("[slack] drop empty text user=%s ch=%s\n", user, ev.Channel)
+					}
+					break
 				}
-			case socketmode.EventTypeHello:
 				if debug {
-					fmt.Println("[slack] hello")
-				}
-			case socketmode.EventTypeEventsAPI:
-				e, ok := evt.Data.(slackevents.EventsAPIEvent)
-				if !ok {
-					sm.Ack(*evt.Request)
-					continue
+					fmt.Printf("[slack] msg %s user=%s ch=%s ts=%s text=%q\n", m.Direction, user, ev.Channel, ev.TimeStamp, m.Text)
 				}
-				sm.Ack(*evt.Request)
-
-				if e.Type == slackevents.CallbackEvent {
-					inner := e.InnerEvent
-					switch ev := inner.Data.(type) {
-					case *slackevents.MessageEvent:
-						// サブタイプ（bot_message, message_changed など）は除外
-						if ev.SubType != "" {
-							if debug {
-								fmt.Printf("[slack] drop subtype=%q user=%s ch=%s\n", ev.SubType, ev.User, ev.Channel)
-							}
-							break
-						}
-						// 本文が空は除外
-						if strings.TrimSpace(ev.Text) == "" {
-							if debug {
-								fmt.Printf("[slack] drop empty text user=%s ch=%s\n", ev.User, ev.Channel)
-							}
-							break
-						}
-						if debug {
-							fmt.Printf("[slack] msg user=%s ch=%s ts=%s text=%q\n", ev.User, ev.Channel, ev.TimeStamp, ev.Text)
-						}
-						// 自分だけ or 一時テストで全保存
-						if !logAll && ev.User != self {
-							if debug {
-								fmt.Printf("[slack] drop not self (want=%s)\n", self)
-							}
-							break
-						}
-						m := messageEntry{
-							Timestamp: time.Now().Format(time.RFC3339),
-							Source:    "Slack",
-							Direction: "sent",
-							Title:     ev.Channel, // 例: Cxxxx / Dxxxx（チャンネル名解決は後で拡張可）
-							Text:      ev.Text,
-							Meta: map[string]string{
-								"channelId": ev.Channel,
-								"threadTs":  ev.ThreadTimeStamp,
-								"userId":    ev.User,
-								"ts":        ev.TimeStamp,
-							},
-						}
-						if err := saveMessageJSON(m); err != nil {
-							fmt.Fprintf(os.Stderr, "save slack msg error: %v\n", err)
-						}
+				// 自分だけ or 一時テストで全保存
+				if !logAll && user != self {
+					if debug {
+						fmt.Printf("[slack] drop not self (want=%s)\n", self)
 					}
+					break
+				}
+				m.Title = slackChannelNames.Resolve(api, ev.Channel) // "#general" / DM の相手の名前（引けなければ Cxxxx / Dxxxx）
+				if t := conversationType(api, ev.ChannelType, ev.Channel); t != "" {
+					m.Meta["conversationType"] = t
+				}
+				if text, full, cut := truncateText(m.Text, slackMaxText); cut {
+					m.Text = text
+					m.Meta["fullLength"] = strconv.Itoa(full)
+				}
+				if err := saveMessage(m); err != nil {
+					fmt.Fprintf(os.Stderr, "save slack msg error: %v\n", err)
 				}
-			case socketmode.EventTypeErrorBadMessage, socketmode.EventTypeErrorWriteFailed, socketmode.EventTypeDisconnect:
-				fmt.Fprintf(os.Stderr, "socketmode error: %#v\n", evt)
 			}
 		}
-	}()
-
-	if err := sm.Run(); err != nil {
-		fmt.Fprintf(os.Stderr, "socketmode run error: %v\n", err)
+	case socketmode.EventTypeErrorBadMessage, socketmode.EventTypeErrorWriteFailed, socketmode.EventTypeDisconnect:
+		fmt.Fprintf(os.Stderr, "socketmode error: %#v\n", evt)
 	}
 }
 
 /********** Slackメッセージ保存 **********/
-func saveMessageJSON(m messageEntry) error {
-	if err := os.MkdirAll(messageDir, 0755); err != nil {
+// SHIRUSIA_OUTPUT=sqlite なら DB の messages に、それ以外は messageDir に1メッセージ1ファイルで書く
+func saveMessage(m messageEntry) error {
+	if messageDB != nil {
+		return messageDB.AppendMessage(&m)
+	}
+	return saveMessageJSON(messageDir, m)
+}
+
+func saveMessageJSON(dir string, m messageEntry) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
 		return err
 	}
 	ts := time.Now().Format("20060102_150405.000")
@@ -442,7 +1353,7 @@ func saveMessageJSON(m messageEntry) error {
 		return s
 	}
 	fname := fmt.Sprintf("msg_%s_%s.json", ts, safe(m.Title))
-	path := filepath.Join(messageDir, fname)
+	path := filepath.Join(dir, fname)
 	f, err := os.Create(path)
 	if err != nil {
 		return err
@@ -455,66 +1366,183 @@ func saveMessageJSON(m messageEntry) error {
 }
 
 /********** ラベリング・ヘルパ **********/
+// 分類の入力。BundleID / URL は取得できない場合は空のまま
+type activityInput struct {
+	App      string
+	BundleID string
+	Title    string
+	URL      string
+}
+
 func classifyActivity(app, title string) string {
-	a := strings.ToLower(app)
-	t := strings.ToLower(title)
+	return classify(activityInput{App: app, Title: title})
+}
 
-	// メール
-	if a == "mail" || strings.Contains(a, "outlook") ||
-		strings.Contains(t, "gmail") || strings.Contains(t, "outlook") || strings.Contains(t, "yahoo mail") {
-		return "メールのやり取り"
-	}
+// 分類ルール。上から順に評価し、最初に一致したものが主カテゴリになる
+type activityRule struct {
+	Category string
+	By       string                                   // 一致したときの matchedBy（classify_match.go の matchedBy で値を付ける）
+	Match    func(in activityInput, a, t string) bool // a, t は小文字化したアプリ名・タイトル
+}
 
-	// コーディング
-	if strings.Contains(a, "visual studio code") || a == "xcode" ||
-		strings.Contains(a, "intellij") || strings.Contains(a, "goland") {
-		return "プログラムの制作"
-	}
-	for _, ext := range []string{".go", ".py", ".js", ".ts", ".rs", ".cpp", ".c", ".java", ".rb", ".kt", ".swift", ".cs"} {
-		if strings.Contains(t, ext) {
-			return "プログラムの制作"
-		}
-	}
+// ソースファイルの拡張子。タイトルの項目の末尾で判定する（hasSourceFileTitle）
+var sourceExts = []string{".go", ".py", ".js", ".ts", ".rs", ".cpp", ".c", ".java", ".rb", ".kt", ".swift", ".cs"}
 
-	// コミュニケーション
-	if strings.Contains(a, "slack") || strings.Contains(a, "teams") ||
-		strings.Contains(a, "discord") || strings.Contains(a, "zoom") || strings.Contains(a, "meet") {
-		return "コミュニケーション"
-	}
+func isCodeEditor(a string) bool {
+	return strings.Contains(a, "visual studio code") || a == "xcode" ||
+		strings.Contains(a, "intellij") || strings.Contains(a, "goland")
+}
 
-	// ブラウザ
-	if strings.Contains(a, "safari") || strings.Contains(a, "chrome") ||
+func isBrowserApp(a string) bool {
+	return strings.Contains(a, "safari") || strings.Contains(a, "chrome") ||
 		strings.Contains(a, "arc") || strings.Contains(a, "firefox") ||
 		strings.Contains(a, "edge") || strings.Contains(a, "brave") ||
-		strings.Contains(a, "opera") || strings.Contains(a, "vivaldi") {
-		if hasAny(t, []string{"arxiv", "qiita", "stackoverflow", "docs", "doc:", "documentation", "mdn"}) {
-			return "調査・ドキュメント閲覧"
-		}
-		return "Webブラウジング"
-	}
+		strings.Contains(a, "opera") || strings.Contains(a, "vivaldi")
+}
 
+var builtinRules = []activityRule{
+	// メディアプレイヤー（曲名が他のルールのキーワードに当たらないよう最初に判定）
+	{"メディア視聴・再生", "app", func(in activityInput, a, t string) bool {
+		return isMediaApp(in.App)
+	}},
+	// ゲーム（全画面でタイトルが取れないことが多い。gameTitle）
+	{"ゲーム", "game", func(in activityInput, a, t string) bool {
+		return isGameApp(in)
+	}},
+	// メール
+	{"メールのやり取り", "app", func(in activityInput, a, t string) bool {
+		return a == "mail" || strings.Contains(a, "outlook")
+	}},
+	{"メールのやり取り", "title:webmail", func(in activityInput, a, t string) bool {
+		return strings.Contains(t, "gmail") || strings.Contains(t, "outlook") || strings.Contains(t, "yahoo mail")
+	}},
+	// デザイン（ブラウザ上のFigma等も拾うため、ブラウザ判定・拡張子判定より前）
+	{"デザイン作業", "design", func(in activityInput, a, t string) bool {
+		return isDesignTool(in)
+	}},
+	// AIツール（チャット画面のタイトル "chatgpt.com" などが拡張子判定に拾われないよう、コーディングより前。
+	// ただしエディタ内のCopilot等はコーディングのまま）
+	{"AI活用", "ai", func(in activityInput, a, t string) bool {
+		return !isCodeEditor(a) && isAITool(in)
+	}},
+	// 金融・経理（ドメインがタイトルに出ても拡張子判定に拾われないよう、コーディングより前）
+	{financeActivity, "finance", func(in activityInput, a, t string) bool {
+		return !isCodeEditor(a) && isFinanceWork(in)
+	}},
+	// インフラ・運用（エディタで .tf を開いているのはコーディングのまま）
+	{"インフラ・運用", "infra", func(in activityInput, a, t string) bool {
+		return !isCodeEditor(a) && isInfraWork(in)
+	}},
+	// コーディング
+	{"プログラムの制作", "app", func(in activityInput, a, t string) bool {
+		return isCodeEditor(a)
+	}},
+	{"プログラムの制作", "title-ext", func(in activityInput, a, t string) bool {
+		return hasSourceFileTitle(in.Title)
+	}},
+	// ブラウザで見ているローカル開発サーバ（localhost:3000 など）
+	{"プログラムの制作", "dev-url", func(in activityInput, a, t string) bool {
+		return isDevURL(in)
+	}},
+	// ターミナル（ソースファイル名やインフラ系コマンドのタイトルは上で判定済み。terminal.go）
+	{"プログラムの制作", "terminal-dev", func(in activityInput, a, t string) bool {
+		return isTerminal(in) && hasTerminalDevContext(in.Title)
+	}},
+	{terminalActivity, "terminal", func(in activityInput, a, t string) bool {
+		return isTerminal(in)
+	}},
+	// 会議中のウィンドウ（アプリ名だけで「コミュニケーション」になる前に。zoom.go / meeting.go）
+	{meetingActivity, "meeting:zoom", func(in activityInput, a, t string) bool {
+		return isZoomMeeting(a, t)
+	}},
+	{meetingActivity, "meeting:meet", func(in activityInput, a, t string) bool {
+		return isGoogleMeet(in, a)
+	}},
+	{meetingActivity, "meeting:teams", func(in activityInput, a, t string) bool {
+		return isTeamsMeeting(a, t)
+	}},
+	{meetingActivity, "meeting:webex", func(in activityInput, a, t string) bool {
+		return isWebexMeeting(a, t)
+	}},
+	// コミュニケーション
+	{"コミュニケーション", "app", func(in activityInput, a, t string) bool {
+		return strings.Contains(a, "slack") || strings.Contains(a, "teams") ||
+			strings.Contains(a, "discord") || strings.Contains(a, "zoom") || strings.Contains(a, "meet") ||
+			strings.Contains(a, "webex")
+	}},
+	// 電子書籍・PDF（ブラウザで開いたPDFも含むので、ブラウザ判定より前）
+	{"読書・資料閲覧", "reading", func(in activityInput, a, t string) bool {
+		return isReadingApp(in)
+	}},
+	// 求人サイト・履歴書（ブラウザ判定や文書作成より前）
+	{"キャリア・求職", "career", func(in activityInput, a, t string) bool {
+		return isCareerWork(in)
+	}},
+	// ノート・PKM（既定は「ドキュメント編集」。SHIRUSIA_PKM_CATEGORY で分けられる。ブラウザの Roam も拾うのでブラウザ判定より前）
+	noteRule(pkmActivity),
+	// ブラウザ
+	{"調査・ドキュメント閲覧", "url-host", func(in activityInput, a, t string) bool {
+		return isBrowserApp(a) && isResearchSite(in)
+	}},
+	{"調査・ドキュメント閲覧", "title:research", func(in activityInput, a, t string) bool {
+		return isBrowserApp(a) && researchTitles.match(in.Title)
+	}},
+	{"Webブラウジング", "app", func(in activityInput, a, t string) bool {
+		return isBrowserApp(a)
+	}},
 	// ドキュメント/表計算/プレゼン
-	if strings.Contains(a, "word") || strings.Contains(a, "pages") || strings.Contains(a, "notion") || strings.Contains(a, "obsidian") {
-		return "ドキュメント編集"
-	}
-	if strings.Contains(a, "excel") || strings.Contains(a, "numbers") || strings.Contains(a, "sheets") {
-		return "表計算・データ整理"
-	}
-	if strings.Contains(a, "powerpoint") || strings.Contains(a, "keynote") {
-		return "プレゼン資料作成"
-	}
-
+	{"ドキュメント編集", "app", func(in activityInput, a, t string) bool {
+		return strings.Contains(a, "word") || strings.Contains(a, "pages")
+	}},
+	{"表計算・データ整理", "app", func(in activityInput, a, t string) bool {
+		return strings.Contains(a, "excel") || strings.Contains(a, "numbers") || strings.Contains(a, "sheets")
+	}},
+	{"プレゼン資料作成", "app", func(in activityInput, a, t string) bool {
+		return strings.Contains(a, "powerpoint") || strings.Contains(a, "keynote")
+	}},
 	// ファイル操作
-	if strings.Contains(a, "finder") || strings.Contains(a, "path finder") {
-		return "ファイル操作"
-	}
-
+	{"ファイル操作", "app", func(in activityInput, a, t string) bool {
+		return strings.Contains(a, "finder") || strings.Contains(a, "path finder")
+	}},
 	// メディア
-	if hasAny(t, []string{"youtube", "netflix", "twitch", "spotify", "music", "soundcloud"}) {
-		return "メディア視聴・再生"
-	}
+	{"メディア視聴・再生", "title:media-site", func(in activityInput, a, t string) bool {
+		return hasAny(t, []string{"youtube", "netflix", "twitch", "spotify", "music", "soundcloud"})
+	}},
+}
+
+const defaultActivity = "その他"
 
-	return "その他"
+// 分類の結果と、それを決めたルールやキーワード（session.matchedBy。「その他」になった理由の確認用）
+type classification struct {
+	Category  string
+	MatchedBy string // "rules:3" "app:visual studio code" "title-ext:.go" "url-host:github.com" "vendor:com.adobe" "default" など
+}
+
+func classify(in activityInput) string {
+	return classifyMatch(in).Category
+}
+
+// ルールファイル（rules.go）→ 組み込みルール → bundle ID のベンダーの順に見る
+func classifyMatch(in activityInput) classification {
+	return classifyWithRules(in, builtinRules)
+}
+
+// ルールファイル → rules（組み込みのルール表）→ bundle ID のベンダー → 「その他」の順に当てる
+func classifyWithRules(in activityInput, rules []activityRule) classification {
+	if c, by := userRules.match(in); c != "" {
+		return classification{c, by}
+	}
+	a := strings.ToLower(in.App)
+	t := strings.ToLower(in.Title)
+	for _, r := range rules {
+		if r.Match(in, a, t) {
+			return classification{r.Category, matchedBy(r.By, in, a)}
+		}
+	}
+	if c, prefix := vendorMatch(in.BundleID); c != "" {
+		return classification{c, "vendor:" + prefix}
+	}
+	return classification{defaultActivity, "default"}
 }
 
 func hasAny(s string, keys []string) bool {
@@ -526,12 +1554,28 @@ func hasAny(s string, keys []string) bool {
 	return false
 }
 
+// s に含まれる最初のキー（なければ空）
+func firstContained(s string, keys []string) string {
+	for _, k := range keys {
+		if k != "" && strings.Contains(s, k) {
+			return k
+		}
+	}
+	return ""
+}
+
+// アプリ名が空（前面アプリ切り替えの途中など）のレコードは意味を持たない
+func isEmptyRecord(r *record) bool {
+	return r == nil || clean(r.App) == ""
+}
+
 func changed(prev, cur *record) bool {
 	if prev == nil {
 		return true
 	}
-	// アプリ / タイトル / ラベル のどれかが変われば新しいセッションとみなす
-	return prev.App != cur.App || prev.Title != cur.Title || prev.Activity != cur.Activity
+	// アプリ / タイトル / ラベル / 通話中・画面共有中フラグ のどれかが変われば新しいセッションとみなす
+	return prev.App != cur.App || prev.Title != cur.Title || prev.Activity != cur.Activity ||
+		prev.OnCall != cur.OnCall || prev.Sharing != cur.Sharing
 }
 
 var spaceRe = regexp.MustCompile(`\s+`)
@@ -542,6 +1586,21 @@ func clean(s string) string {
 	return strings.TrimSpace(spaceRe.ReplaceAllString(s, " "))
 }
 
+// "on exit" → "(on exit)"、空なら空
+func paren(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "(" + s + ")"
+}
+
+func spaced(s string) string {
+	if s == "" {
+		return ""
+	}
+	return " " + s
+}
+
 func short(s string, n int) string {
 	rs := []rune(s)
 	if len(rs) <= n {
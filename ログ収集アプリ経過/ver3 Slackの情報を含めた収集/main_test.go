@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 手元の rules.yaml（SHIRUSIA_RULES）に左右されないよう、組み込みのルールだけで分類する
+func TestMain(m *testing.M) {
+	userRules = &ruleSet{}
+	os.Exit(m.Run())
+}
+
+type classifyCase struct {
+	name string
+	in   activityInput
+	want string
+}
+
+// 2025-09-01 のローカル時刻
+func testTime(h, m int) time.Time {
+	return time.Date(2025, 9, 1, h, m, 0, 0, time.Local)
+}
+
+// t.TempDir() に name というファイルを作ってパスを返す
+func writeTestFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func checkClassify(t *testing.T, tests []classifyCase) {
+	t.Helper()
+	for _, tt := range tests {
+		if got := classify(tt.in); got != tt.want {
+			t.Errorf("%s: classify(%+v) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+// 切り替え途中の空のアプリ名は記録しない（メインループは isEmptyRecord で読み飛ばす）
+func TestIsEmptyRecord(t *testing.T) {
+	tests := []struct {
+		name string
+		r    *record
+		want bool
+	}{
+		{"nil", nil, true},
+		{"empty app", &record{App: "", Title: "main.go"}, true},
+		{"blank app", &record{App: " \t ", Title: "main.go"}, true},
+		{"nul only", &record{App: "\u0000"}, true},
+		{"app without title", &record{App: "Finder"}, false},
+		{"app and title", &record{App: "Visual Studio Code", Title: "main.go"}, false},
+	}
+	for _, tt := range tests {
+		if got := isEmptyRecord(tt.r); got != tt.want {
+			t.Errorf("%s: isEmptyRecord = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// 空の取得が続いても、前後のセッションは1つにつながる（空のセッションはできない）
+func TestEmptyRecordsDoNotSplitSessions(t *testing.T) {
+	base := time.Date(2025, 9, 1, 10, 0, 0, 0, time.Local)
+	polls := []*record{
+		{App: "Visual Studio Code", Title: "main.go", Activity: "プログラムの制作"},
+		{App: "", Title: ""},
+		{App: "", Title: "main.go"},
+		{App: " \u0000", Title: "main.go"},
+		{App: "Visual Studio Code", Title: "main.go", Activity: "プログラムの制作"},
+	}
+	want := []tickStep{tickSwitch, tickSkip, tickSkip, tickSkip, tickSame}
+	for _, mode := range []trackMode{trackTitle, trackApp, trackCategory} {
+		var last *record
+		for i, cur := range polls {
+			cur.Timestamp = base.Add(time.Duration(i) * time.Second)
+			step := nextTickStep(mode, last, cur)
+			if step != want[i] {
+				t.Errorf("%s: poll %d (%q): step = %d, want %d", mode, i, cur.App, step, want[i])
+			}
+			if step == tickSwitch {
+				last = cur
+			}
+		}
+	}
+}
+
+// アイドル中は同じアプリに戻っても新しいセッションにする
+func TestNextTickStepAfterIdle(t *testing.T) {
+	cur := &record{App: "Visual Studio Code", Title: "main.go", Activity: "プログラムの制作"}
+	if got := nextTickStep(trackTitle, idleRecord(testTime(10, 0)), cur); got != tickSwitch {
+		t.Errorf("step after idle = %d, want tickSwitch", got)
+	}
+	if got := nextTickStep(trackTitle, nil, cur); got != tickSwitch {
+		t.Errorf("step with no session = %d, want tickSwitch", got)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+/********** メディアプレイヤー **********/
+// 音楽・動画アプリは曲が変わるたびにウィンドウタイトルが変わるので、そのままでは
+// 「メディア視聴・再生」が曲ごとの細切れのセッションになる。既知のメディアアプリの中では
+// タイトル変化でセッションを区切らず（-track app と同じ扱い）、再生した曲名を
+// meta.tracks に出現順で残す。
+//   SHIRUSIA_MEDIA_APPS="Plexamp,Audirvana"  追加のメディアアプリ（アプリ名、カンマ区切り）
+var mediaApps = append([]string{
+	"music", "spotify", "tv", "podcasts", "quicktime player", "vlc", "iina",
+	"amazon music", "youtube music", "infuse", "plex",
+}, lowerList(splitList(os.Getenv("SHIRUSIA_MEDIA_APPS")))...)
+
+const maxTracks = 50 // 長時間の再生で meta が膨らみすぎないように
+
+func isMediaApp(app string) bool {
+	low := strings.ToLower(strings.TrimSpace(app))
+	for _, m := range mediaApps {
+		if low == m {
+			return true
+		}
+	}
+	return false
+}
+
+func lowerList(list []string) []string {
+	for i, s := range list {
+		list[i] = strings.ToLower(s)
+	}
+	return list
+}
+
+// メディアアプリのセッション中に表示されたタイトル（曲名）を記録する（連続する重複なし）
+func (r *record) noteTrack(title string) {
+	if !isMediaApp(r.App) {
+		return
+	}
+	title = clean(title)
+	if title == "" || len(r.Tracks) >= maxTracks {
+		return
+	}
+	if n := len(r.Tracks); n > 0 && r.Tracks[n-1] == title {
+		return
+	}
+	r.Tracks = append(r.Tracks, title)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+/********** Google Meet・Teams・Webex の会議 **********/
+// アプリ名だけでは会議中かチャットかが分からないので、会議のウィンドウのタイトルで「会議・通話」にする
+// （Zoom は zoom.go）。当たらないウィンドウは従来どおり「コミュニケーション」のまま。
+//   Google Meet  ブラウザのタブが "Meet – abc-defg-hij" "Meet — 定例" の形（会議に入るとこうなる。
+//                トップページの "Google Meet" は当てない）。URL が取れれば meet.google.com/abc-defg-hij でも判定する
+//   Teams        "Meeting with …" "Meeting in …" "Call with …"、日本語版の "会議" "通話" を含むウィンドウ
+//   Webex        "Meeting" "Personal Room"、日本語版の "ミーティング" "パーソナル会議室" を含むウィンドウ
+//                （アプリ本体のウィンドウ名 "Webex" "Cisco Webex Meetings" は除く）
+var (
+	meetTitleRe = regexp.MustCompile(`(?i)^meet\s+[-–—:]\s+\S`)
+	meetCodeRe  = regexp.MustCompile(`^/[a-z]{3}-[a-z]{4}-[a-z]{3}(?:/|$)`)
+
+	teamsMeetingTitles  = []string{"meeting", "call with", "会議", "通話"}
+	webexMeetingTitles  = []string{"meeting", "personal room", "ミーティング", "パーソナル会議室"}
+	webexGenericWindows = []string{"webex", "cisco webex meetings", "webex meetings"}
+)
+
+// a は小文字のアプリ名。Meet のアプリ（PWA）かブラウザのみ
+func isGoogleMeet(in activityInput, a string) bool {
+	if !isBrowserApp(a) && !strings.Contains(a, "meet") {
+		return false
+	}
+	if meetTitleRe.MatchString(strings.TrimSpace(in.Title)) {
+		return true
+	}
+	if in.URL == "" {
+		return false
+	}
+	u, err := url.Parse(in.URL)
+	return err == nil && strings.EqualFold(u.Hostname(), "meet.google.com") && meetCodeRe.MatchString(u.Path)
+}
+
+// a, t は小文字
+func isTeamsMeeting(a, t string) bool {
+	return strings.Contains(a, "teams") && hasAny(t, teamsMeetingTitles)
+}
+
+// a, t は小文字
+func isWebexMeeting(a, t string) bool {
+	return strings.Contains(a, "webex") && !containsString(webexGenericWindows, strings.TrimSpace(t)) && hasAny(t, webexMeetingTitles)
+}
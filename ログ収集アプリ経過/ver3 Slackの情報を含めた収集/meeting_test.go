@@ -0,0 +1,36 @@
+package main
+
+import "testing"
+
+// 会議中のウィンドウだけを「会議・通話」にし、同じアプリのチャットやトップページは「コミュニケーション」のまま
+func TestClassifyMeetings(t *testing.T) {
+	checkClassify(t, []classifyCase{
+		// Zoom
+		{"zoom meeting", activityInput{App: "zoom.us", Title: "Zoom Meeting"}, meetingActivity},
+		{"zoom meeting ja", activityInput{App: "zoom.us", Title: "Zoom ミーティング"}, meetingActivity},
+		{"zoom webinar", activityInput{App: "zoom.us", Title: "Zoom Webinar"}, meetingActivity},
+		{"zoom home", activityInput{App: "zoom.us", Title: "Zoom Workplace"}, "コミュニケーション"},
+		{"zoom chat", activityInput{App: "zoom.us", Title: "Chat"}, "コミュニケーション"},
+
+		// Google Meet（ブラウザのタブ・PWA）
+		{"meet code", activityInput{App: "Google Chrome", Title: "Meet – abc-defg-hij"}, meetingActivity},
+		{"meet named", activityInput{App: "Arc", Title: "Meet — 週次定例"}, meetingActivity},
+		{"meet pwa", activityInput{App: "Google Meet", Title: "Meet - abc-defg-hij"}, meetingActivity},
+		{"meet url", activityInput{App: "Safari", Title: "abc-defg-hij", URL: "https://meet.google.com/abc-defg-hij"}, meetingActivity},
+		{"meet landing", activityInput{App: "Google Chrome", Title: "Google Meet", URL: "https://meet.google.com/landing"}, "Webブラウジング"},
+		{"meetup page", activityInput{App: "Google Chrome", Title: "Meetup - Go勉強会"}, "Webブラウジング"},
+
+		// Teams
+		{"teams meeting", activityInput{App: "Microsoft Teams", Title: "Meeting with Sato | Microsoft Teams"}, meetingActivity},
+		{"teams call", activityInput{App: "Microsoft Teams", Title: "Call with Tanaka"}, meetingActivity},
+		{"teams ja", activityInput{App: "Microsoft Teams", Title: "定例会議 | Microsoft Teams"}, meetingActivity},
+		{"teams chat", activityInput{App: "Microsoft Teams", Title: "Chat | 開発チーム | Microsoft Teams"}, "コミュニケーション"},
+
+		// Webex
+		{"webex meeting", activityInput{App: "Webex", Title: "Design Review Meeting"}, meetingActivity},
+		{"webex personal room", activityInput{App: "Webex", Title: "Sato's Personal Room"}, meetingActivity},
+		{"webex ja", activityInput{App: "Webex", Title: "佐藤のパーソナル会議室"}, meetingActivity},
+		{"webex app window", activityInput{App: "Cisco Webex Meetings", Title: "Cisco Webex Meetings"}, "コミュニケーション"},
+		{"webex home", activityInput{App: "Webex", Title: "Webex"}, "コミュニケーション"},
+	})
+}
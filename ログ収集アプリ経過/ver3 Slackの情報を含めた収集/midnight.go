@@ -0,0 +1,23 @@
+package main
+
+/********** 0:00 での区切り **********/
+// 常駐させたままだと、日付をまたいだセッションが丸ごと翌日のファイルに入り、前日分の時間が前日に数えられない。
+// メインループは日付が変わったのを見つけたら、進行中のセッションを 23:59:59.999 で閉じて
+// 今のファイル（前日分）に書き、同じアプリ・タイトル・カテゴリのセッションを 0:00 から始め直してから
+// 新しいセッションファイル（activity_<翌日の日付>_....json）に切り替える（-rotate-daily=false なら切り替えない）。
+// 前日分の endReason は "midnight"。再生中（-replay）は仮想時刻なので区切らない。
+
+// 0:00 から続ける同じ内容のレコード。セッション中に集めたもの（寄り道・曲名・入力回数など）は
+// 前日分に残し、新しいセッションでは集め直す
+func (r *record) continuation() *record {
+	c := *r
+	c.Apps = nil
+	c.Roles = nil
+	c.Tracks = nil
+	c.Glances = nil
+	c.OtherTabs = nil
+	c.Input = inputCounts{}
+	c.noteApp(c.App)
+	c.noteTrack(c.Title)
+	return &c
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+/********** アプリ名の正規化 **********/
+// "Visual Studio Code - Insiders" や "IntelliJ IDEA 2024.1" のようなエディション/バージョン付きの名前を
+// 基本名にそろえ、分類・集計が分散しないようにする。元の名前はセッションの meta.rawApp に残す。
+//
+// SHIRUSIA_APP_ALIASES="<正規表現>=><置換後>;..."
+//   既定ルールより先に評価する。置換後には $1 などのキャプチャを使える。
+//   例: SHIRUSIA_APP_ALIASES="^(Sublime Text) \d+$=>$1;^Arc Canary$=>Arc"
+type appAlias struct {
+	re   *regexp.Regexp
+	repl string
+}
+
+var defaultAppAliases = []appAlias{
+	{regexp.MustCompile(`^Visual Studio Code\s*[-–]\s*Insiders$`), "Visual Studio Code"},
+	{regexp.MustCompile(`^Code\s*[-–]\s*Insiders$`), "Code"},
+	{regexp.MustCompile(`^(IntelliJ IDEA)(?:\s+(?:Ultimate|Community Edition|CE|EAP))*(?:\s+\d{4}(?:\.\d+)*)?$`), "$1"},
+	{regexp.MustCompile(`^(GoLand|PyCharm|WebStorm|CLion|Rider|PhpStorm|RubyMine|DataGrip)(?:\s+(?:Professional|Community|CE|EAP))*(?:\s+\d{4}(?:\.\d+)*)?$`), "$1"},
+	{regexp.MustCompile(`^(Google Chrome|Microsoft Edge)\s+(?:Beta|Dev|Canary)$`), "$1"},
+	{regexp.MustCompile(`^(Safari) Technology Preview$`), "$1"},
+	// 年号・バージョンが名前に入る既知のアプリ（"Adobe Photoshop 2024" "Affinity Designer 2" "Ableton Live 12 Suite"）。
+	// "Things 3" や "1Password 7" のように数字までが名前のアプリもあるので、末尾の数字を一律には外さない
+	{regexp.MustCompile(`^(Adobe (?:Photoshop|Illustrator|InDesign|Premiere Pro|After Effects|Lightroom Classic|Media Encoder|Audition|Animate|Bridge|Dreamweaver))(?:\s+CC)?(?:\s+\d{4})?$`), "$1"},
+	{regexp.MustCompile(`^(Affinity (?:Designer|Photo|Publisher))\s+\d+$`), "$1"},
+	{regexp.MustCompile(`^(Ableton Live)\s+\d+(?:\s+(?:Suite|Standard|Intro|Lite|Trial))?$`), "$1"},
+}
+
+var appAliases = append(loadAppAliases(), defaultAppAliases...)
+
+func loadAppAliases() []appAlias {
+	var out []appAlias
+	for _, part := range strings.Split(os.Getenv("SHIRUSIA_APP_ALIASES"), ";") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		pat, repl, ok := strings.Cut(part, "=>")
+		re, err := regexp.Compile(strings.TrimSpace(pat))
+		if !ok || err != nil {
+			fmt.Fprintf(os.Stderr, "warn: SHIRUSIA_APP_ALIASES: invalid entry %q\n", part)
+			continue
+		}
+		out = append(out, appAlias{re: re, repl: strings.TrimSpace(repl)})
+	}
+	return out
+}
+
+// 最初に一致したルールで基本名に置き換える（一致しなければそのまま）
+func normalizeAppName(app string) string {
+	app = strings.TrimSpace(app)
+	for _, a := range appAliases {
+		if a.re.MatchString(app) {
+			return strings.TrimSpace(a.re.ReplaceAllString(app, a.repl))
+		}
+	}
+	return app
+}
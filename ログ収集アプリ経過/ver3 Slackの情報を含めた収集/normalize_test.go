@@ -0,0 +1,53 @@
+package main
+
+import "testing"
+
+func TestNormalizeAppName(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		// Insiders
+		{"Visual Studio Code - Insiders", "Visual Studio Code"},
+		{"Visual Studio Code – Insiders", "Visual Studio Code"},
+		{"Code - Insiders", "Code"},
+		{"Visual Studio Code", "Visual Studio Code"},
+		// IntelliJ
+		{"IntelliJ IDEA 2024.1", "IntelliJ IDEA"},
+		{"IntelliJ IDEA Ultimate 2024.1.4", "IntelliJ IDEA"},
+		{"IntelliJ IDEA Community Edition", "IntelliJ IDEA"},
+		{"IntelliJ IDEA CE 2023.3", "IntelliJ IDEA"},
+		{"GoLand 2024.2", "GoLand"},
+		{"PyCharm Professional 2024.1", "PyCharm"},
+		// ほかの既知のアプリ
+		{"Google Chrome Canary", "Google Chrome"},
+		{"Safari Technology Preview", "Safari"},
+		{"Adobe Photoshop 2024", "Adobe Photoshop"},
+		{"Adobe Illustrator CC 2019", "Adobe Illustrator"},
+		{"Affinity Designer 2", "Affinity Designer"},
+		{"Ableton Live 12 Suite", "Ableton Live"},
+		// 数字までが名前のアプリは変えない
+		{"Things 3", "Things 3"},
+		{"1Password 7", "1Password 7"},
+		{"Microsoft Excel", "Microsoft Excel"},
+		{"Final Cut Pro 10.8", "Final Cut Pro 10.8"},
+		{"  Slack  ", "Slack"},
+	}
+	for _, tt := range tests {
+		if got := normalizeAppName(tt.in); got != tt.want {
+			t.Errorf("normalizeAppName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// 正規化した名前で分類し、元の名前は meta.rawApp に残す
+func TestNormalizedAppKeepsRawNameInMeta(t *testing.T) {
+	raw := "Visual Studio Code - Insiders"
+	r := &record{App: normalizeAppName(raw), RawApp: raw, Title: "main.go", Activity: "プログラムの制作"}
+	s := sessionFrom(r, testTime(10, 0), testTime(10, 5))
+	if s.App != "Visual Studio Code" || s.Meta["rawApp"] != raw {
+		t.Errorf("app = %q, meta.rawApp = %q", s.App, s.Meta["rawApp"])
+	}
+	if got := classifyActivity(s.App, "main.go"); got != "プログラムの制作" {
+		t.Errorf("classify = %q", got)
+	}
+}
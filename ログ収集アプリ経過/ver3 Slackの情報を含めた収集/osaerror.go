@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+/********** AppleScript のエラー **********/
+// osascript の標準エラーはシステムの言語で出る（日本語環境なら日本語）ので、文言では判定できない。
+// 末尾の "(-1743)" のようなエラー番号を取り出し、errors.Is で判定できるようにする。
+//   execution error: Not authorized to send Apple events to System Events. (-1743)
+//   実行エラー: System Eventsでエラーが起きました: Apple Eventsを送信する権限がありません。 (-1743)
+var (
+	ErrNotPermitted  = errors.New("not permitted (" + notPermittedHint + ")")
+	ErrAppNotRunning = errors.New("application is not running")
+)
+
+// ErrNotPermitted のときに出す対処（osascript の文言には含まれない）
+const notPermittedHint = "allow Automation / Accessibility for this terminal in System Settings → Privacy & Security"
+
+var osaCodeRe = regexp.MustCompile(`\((-?\d+)\)\s*$`)
+
+var osaErrorKinds = map[int]error{
+	-1743:  ErrNotPermitted,  // Apple Events の送信が許可されていない（オートメーション）
+	-1719:  ErrNotPermitted,  // 補助アクセス（アクセシビリティ）が許可されていない
+	-25211: ErrNotPermitted,  // 同上（System Events の UI 要素）
+	-600:   ErrAppNotRunning, // アプリケーションが起動していない
+	-609:   ErrAppNotRunning, // 接続が無効（問い合わせ中にアプリが終了した）
+}
+
+type osaError struct {
+	Code int    // AppleScript のエラー番号（取れなければ 0）
+	Msg  string // osascript の標準エラー（ローカライズされた文言）
+}
+
+func (e *osaError) Error() string {
+	return e.Msg
+}
+
+func (e *osaError) Is(target error) bool {
+	kind, ok := osaErrorKinds[e.Code]
+	return ok && kind == target
+}
+
+func parseOSAError(stderr string) error {
+	msg := strings.TrimSpace(stderr)
+	e := &osaError{Msg: msg}
+	if m := osaCodeRe.FindStringSubmatch(msg); m != nil {
+		e.Code, _ = strconv.Atoi(m[1])
+	}
+	return e
+}
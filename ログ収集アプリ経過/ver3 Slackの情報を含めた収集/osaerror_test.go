@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestParseOSAError(t *testing.T) {
+	tests := []struct {
+		name   string
+		stderr string
+		code   int
+		want   error
+	}{
+		{"automation en", "36:120: execution error: Not authorized to send Apple events to System Events. (-1743)\n", -1743, ErrNotPermitted},
+		{"automation ja", "36:120: 実行エラー: System Eventsでエラーが起きました: Apple Eventsを送信する権限がありません。 (-1743)\n", -1743, ErrNotPermitted},
+		{"accessibility en", "execution error: System Events got an error: osascript is not allowed assistive access. (-1719)", -1719, ErrNotPermitted},
+		{"accessibility ja", "実行エラー: System Eventsでエラーが起きました: osascriptには補助アクセスは許可されません。 (-25211)", -25211, ErrNotPermitted},
+		{"not running en", "execution error: Google Chrome got an error: Application isn’t running. (-600)", -600, ErrAppNotRunning},
+		{"not running ja", "実行エラー: Google Chromeでエラーが起きました: アプリケーションは起動していません。 (-600)", -600, ErrAppNotRunning},
+		{"other code", "execution error: Can’t get window 1. (-1728)", -1728, nil},
+		{"no code", "osascript: couldn't connect", 0, nil},
+	}
+	for _, tt := range tests {
+		err := parseOSAError(tt.stderr)
+		var oe *osaError
+		if !errors.As(err, &oe) || oe.Code != tt.code {
+			t.Errorf("%s: parsed %#v, want code %d", tt.name, err, tt.code)
+			continue
+		}
+		for _, kind := range []error{ErrNotPermitted, ErrAppNotRunning} {
+			if got := errors.Is(err, kind); got != (kind == tt.want) {
+				t.Errorf("%s: errors.Is(%v) = %v", tt.name, kind, got)
+			}
+		}
+	}
+}
+
+// 呼び出し側が %w で包んでも判定でき、文言（ローカライズされたまま）も残る
+func TestOSAErrorWrapped(t *testing.T) {
+	base := parseOSAError("実行エラー: System Eventsでエラーが起きました: Apple Eventsを送信する権限がありません。 (-1743)")
+	err := fmt.Errorf("get frontmost app failed: %w", base)
+	if !errors.Is(err, ErrNotPermitted) {
+		t.Errorf("errors.Is(%v, ErrNotPermitted) = false through %%w", err)
+	}
+	if errors.Is(err, ErrAppNotRunning) {
+		t.Errorf("errors.Is(%v, ErrAppNotRunning) = true", err)
+	}
+	// メインループは対処の案内を添えて包み直す
+	err = fmt.Errorf("%w: %w", err, ErrNotPermitted)
+	if !errors.Is(err, ErrNotPermitted) {
+		t.Errorf("errors.Is(%v, ErrNotPermitted) = false after wrapping twice", err)
+	}
+	if got := err.Error(); got != "get frontmost app failed: "+base.Error()+": "+ErrNotPermitted.Error() {
+		t.Errorf("message = %q", got)
+	}
+}
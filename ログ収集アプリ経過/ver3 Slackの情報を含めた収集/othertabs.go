@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+/********** ほかのブラウザウィンドウのタブ（-other-tabs、任意） **********/
+// 前面のウィンドウ以外（別モニタに開いた資料など）も文脈として残すため、起動中のブラウザの
+// 各ウィンドウで表示中のタブのタイトルとURLを meta.otherTabs に入れる（"タイトル <URL> / ..."）。
+// セッション自体は従来どおり前面のウィンドウで区切る。前面のブラウザの前面ウィンドウは含めない。
+//   - ブラウザごとに osascript を起動するので、結果は otherTabsTTL の間キャッシュする（既定はオフ）
+//   - URL は保存前に sanitizeURL で整形する（urlscrub.go）
+//   - Chromium 系のシークレットウィンドウは含めない。Safari のプライベートウィンドウは
+//     AppleScript から見分けられないので含まれてしまう（private.go と同じ制約）
+const (
+	otherTabsTTL = 30 * time.Second
+	maxOtherTabs = 20
+)
+
+var otherTabBrowsers = []string{"Safari", "Google Chrome", "Microsoft Edge", "Brave Browser", "Vivaldi", "Arc"}
+
+type otherTabsCollector struct {
+	checkedAt time.Time
+	front     string
+	tabs      []string
+}
+
+// frontApp 以外のウィンドウで表示中のタブ
+func (c *otherTabsCollector) Tabs(now time.Time, frontApp string) []string {
+	if !c.checkedAt.IsZero() && now.Sub(c.checkedAt) < otherTabsTTL && c.front == frontApp {
+		return c.tabs
+	}
+	c.tabs = nil
+	for _, b := range otherTabBrowsers {
+		if out, err := runCmd("pgrep", "-x", b); err != nil || strings.TrimSpace(out) == "" {
+			continue // 起動していないブラウザに tell すると起動や問い合わせのダイアログになる
+		}
+		c.tabs = append(c.tabs, browserWindowTabs(b, b == frontApp)...)
+	}
+	c.checkedAt, c.front = now, frontApp
+	return c.tabs
+}
+
+// ブラウザの各ウィンドウで表示中のタブ（skipFront なら前面のウィンドウを除く）
+func browserWindowTabs(browser string, skipFront bool) []string {
+	first := 1
+	if skipFront {
+		first = 2
+	}
+	var script string
+	if browser == "Safari" {
+		script = fmt.Sprintf(`
+			tell application "Safari"
+				set out to ""
+				repeat with i from %d to (count of windows)
+					try
+						set t to current tab of window i
+						set out to out & (name of t) & tab & (URL of t) & linefeed
+					end try
+				end repeat
+				return out
+			end tell
+		`, first)
+	} else {
+		script = fmt.Sprintf(`
+			tell application "%s"
+				set out to ""
+				repeat with i from %d to (count of windows)
+					try
+						set m to "normal"
+						try
+							set m to mode of window i
+						end try
+						if m is not "incognito" then
+							set t to active tab of window i
+							set out to out & (title of t) & tab & (URL of t) & linefeed
+						end if
+					end try
+				end repeat
+				return out
+			end tell
+		`, escapeOSA(browser), first)
+	}
+	out, err := runOSA(script)
+	if err != nil {
+		return nil
+	}
+	var tabs []string
+	for _, line := range strings.Split(out, "\n") {
+		title, u, _ := strings.Cut(line, "\t")
+		title, u = clean(title), sanitizeURL(u)
+		switch {
+		case title != "" && u != "":
+			tabs = append(tabs, title+" <"+u+">")
+		case title != "":
+			tabs = append(tabs, title)
+		case u != "":
+			tabs = append(tabs, "<"+u+">")
+		}
+	}
+	return tabs
+}
+
+// セッション中に見えていたタブを重複なく覚える
+func (r *record) noteOtherTabs(tabs []string) {
+	for _, t := range tabs {
+		if len(r.OtherTabs) >= maxOtherTabs {
+			return
+		}
+		if !containsString(r.OtherTabs, t) {
+			r.OtherTabs = append(r.OtherTabs, t)
+		}
+	}
+}
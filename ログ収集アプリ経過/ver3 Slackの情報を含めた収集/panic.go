@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime/debug"
+)
+
+/********** パニック時の後始末 **********/
+// メインループや Slack 取り込みがパニックすると、進行中のセッションが失われ、
+// セッションファイルも閉じ括弧のないまま残る。ゴルーチンの先頭とメインループで recover し、
+// スタックトレースを出してから、進行中のセッションを書き出してファイルを閉じる。
+// （閉じきれなかったファイルも readSessionsFile は途中まで読める）
+//
+// ゴルーチンでのパニックは fatalCh でメインループに知らせ、メインループ側で後始末して終了する。
+var fatalCh = make(chan error, 1)
+
+func logPanic(where string, p any) {
+	fmt.Fprintf(os.Stderr, "panic in %s: %v\n%s", where, p, debug.Stack())
+}
+
+// f を実行し、パニックしたらログを出してメインループに終了を依頼する
+func runGuarded(name string, f func()) {
+	defer func() {
+		if p := recover(); p != nil {
+			logPanic(name, p)
+			select {
+			case fatalCh <- fmt.Errorf("%s panicked: %v", name, p):
+			default: // すでに終了を依頼済み
+			}
+		}
+	}()
+	f()
+}
+
+// 後始末自体がパニックしても元のパニックを隠さないよう、ここで止める
+func flushOnPanic(f func()) {
+	defer func() {
+		if p := recover(); p != nil {
+			fmt.Fprintf(os.Stderr, "panic while flushing: %v\n", p)
+		}
+	}()
+	f()
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"strings"
+	"time"
+)
+
+/********** pprof（-pprof、開発・調査用） **********/
+// 動作中のプロセスからCPU/ヒープ/goroutineのプロファイルを取るためのもの
+// （例: Slack再接続まわりのgoroutineリーク調査）。
+//   go tool pprof http://127.0.0.1:6060/debug/pprof/profile?seconds=30
+// ホストを省略した ":6060" は 127.0.0.1 に限定して待ち受ける。
+// プロファイルから内部状態が読めるため、普段の運用では指定しないこと。
+func startPprof(addr string) {
+	if strings.HasPrefix(addr, ":") {
+		addr = "127.0.0.1" + addr
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil {
+			fmt.Fprintf(os.Stderr, "pprof server error: %v\n", err)
+		}
+	}()
+	fmt.Fprintf(os.Stderr, "pprof enabled on http://%s/debug/pprof/ (debug only)\n", addr)
+}
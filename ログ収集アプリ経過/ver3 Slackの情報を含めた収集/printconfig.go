@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+/********** 実際に効いている設定の表示（-print-config） **********/
+// 環境変数・フラグ・既定値が混ざると、どの設定で動いているのか分かりにくい。
+// 解決済みの設定を JSON で標準出力に書いて終了する（ロガーは起動しない）。
+// トークン類は値を出さず、先頭の種別（"xoxb-" など）と読み込み元だけを出す。
+type effectiveConfig struct {
+	LogDir       string            `json:"logDir"`
+	MessageDir   string            `json:"messageDir"`
+	Output       string            `json:"output"` // セッションファイルの形式（SHIRUSIA_OUTPUT）
+	PollInterval string            `json:"pollInterval"`
+	AppIntervals map[string]string `json:"appIntervals,omitempty"`
+	Track        string            `json:"track"`
+	RulesSource  string            `json:"rulesSource"`
+	BreakWindows []string          `json:"breakWindows"`
+	Flags        map[string]string `json:"flags"`
+	Env          map[string]string `json:"env"` // 設定に関係する環境変数のうち、設定されているもの
+	Slack        slackConfig       `json:"slack"`
+}
+
+type slackConfig struct {
+	BotToken        string `json:"botToken"`
+	BotTokenSource  string `json:"botTokenSource,omitempty"`
+	AppToken        string `json:"appToken"`
+	AppTokenSource  string `json:"appTokenSource,omitempty"`
+	SelfUserID      string `json:"selfUserId"`
+	TokenFile       string `json:"tokenFile,omitempty"`
+	KeychainService string `json:"keychainService"`
+	Debug           bool   `json:"debug"`
+	LogAll          bool   `json:"logAll"`
+	Enabled         bool   `json:"enabled"`
+}
+
+func printConfig(mode trackMode) error {
+	cfg := effectiveConfig{
+		LogDir:       logDir,
+		MessageDir:   messageDir,
+		Output:       outputFormat,
+		PollInterval: pollInterval.String(),
+		Track:        string(mode),
+		RulesSource:  userRules.describe(),
+		BreakWindows: []string{},
+		Flags:        map[string]string{},
+		Env:          map[string]string{},
+	}
+	if m := loadAppIntervals(); len(m) > 0 {
+		cfg.AppIntervals = map[string]string{}
+		for app, d := range m {
+			cfg.AppIntervals[app] = d.String()
+		}
+	}
+	for _, w := range breakWindows {
+		cfg.BreakWindows = append(cfg.BreakWindows, fmtClock(w.from)+"-"+fmtClock(w.to))
+	}
+	flag.VisitAll(func(f *flag.Flag) {
+		cfg.Flags[f.Name] = f.Value.String()
+	})
+	for _, kv := range os.Environ() {
+		k, v, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(k, "SHIRUSIA_") && !strings.HasPrefix(k, "SLACK_") {
+			continue
+		}
+		if isSecretEnv(k) {
+			v = redactSecret(v)
+		}
+		cfg.Env[k] = v
+	}
+
+	bot, botSrc := lookupSlackSecretSource("SLACK_BOT_TOKEN")
+	app, appSrc := lookupSlackSecretSource("SLACK_APP_TOKEN")
+	service := strings.TrimSpace(os.Getenv("SLACK_KEYCHAIN_SERVICE"))
+	if service == "" {
+		service = "Shirusia"
+	}
+	cfg.Slack = slackConfig{
+		BotToken:        redactSecret(bot),
+		BotTokenSource:  botSrc,
+		AppToken:        redactSecret(app),
+		AppTokenSource:  appSrc,
+		SelfUserID:      strings.TrimSpace(os.Getenv("SLACK_SELF_USER_ID")),
+		TokenFile:       strings.TrimSpace(os.Getenv("SLACK_TOKEN_FILE")),
+		KeychainService: service,
+		Debug:           strings.TrimSpace(os.Getenv("SLACK_DEBUG")) == "1",
+		LogAll:          strings.TrimSpace(os.Getenv("SLACK_LOG_ALL")) == "1",
+		Enabled:         bot != "" && app != "",
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(cfg)
+}
+
+func isSecretEnv(k string) bool {
+	for _, s := range []string{"TOKEN", "SECRET", "PASSWORD"} {
+		if strings.Contains(k, s) && !strings.HasSuffix(k, "_FILE") {
+			return true
+		}
+	}
+	return false
+}
+
+// "xoxb-1234-..." → "xoxb-***"。種別の接頭辞がなければ "***"
+func redactSecret(v string) string {
+	if v == "" {
+		return ""
+	}
+	if i := strings.Index(v, "-"); i > 0 && i <= 5 {
+		return v[:i+1] + "***"
+	}
+	return "***"
+}
+
+// 0:00 からの経過 → "HH:MM"
+func fmtClock(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
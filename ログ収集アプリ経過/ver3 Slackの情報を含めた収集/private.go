@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+/********** プライベートブラウズ / シークレットウィンドウ **********/
+// シークレットウィンドウのタイトルやURLを記録しては、利用者の「残したくない」という意図に反する。
+// Chromium系ブラウザは AppleScript の `mode of front window` が "incognito" になるので、それで判定する。
+//
+// SHIRUSIA_PRIVATE_WINDOWS（既定 "app"）
+//   app   : ブラウザ名だけ記録し、タイトル・URLは記録しない
+//   hide  : ブラウザ名も残さず、"Private Browsing" として記録する（何を使っていたかも残さない）
+//   skip  : 何も記録しない（前面にある間は記録を止め、戻ったら新しいセッションを始める）
+//   track : 通常のウィンドウと同じく記録する
+//
+// 制限: Safari の AppleScript にはプライベートウィンドウかどうかを返すプロパティがないため、
+// Safari のプライベートブラウズは判定できず、通常のウィンドウと同じように記録される。
+// Safari で記録を残したくない場合は、そのあいだロガーを止めるか Chromium系ブラウザを使うこと。
+// Arc など mode を持たないブラウザも同様に通常のウィンドウとして扱う。
+const (
+	privateModeApp   = "app"
+	privateModeHide  = "hide"
+	privateModeSkip  = "skip"
+	privateModeTrack = "track"
+
+	privateAppLabel = "Private Browsing"
+)
+
+var privateWindowMode = loadPrivateWindowMode()
+
+func loadPrivateWindowMode() string {
+	switch m := strings.ToLower(strings.TrimSpace(os.Getenv("SHIRUSIA_PRIVATE_WINDOWS"))); m {
+	case "":
+		return privateModeApp
+	case privateModeApp, privateModeHide, privateModeSkip, privateModeTrack:
+		return m
+	default:
+		fmt.Fprintf(os.Stderr, "warn: SHIRUSIA_PRIVATE_WINDOWS: unknown mode %q (app, hide, skip, track)\n", m)
+		return privateModeApp
+	}
+}
+
+// シークレットウィンドウで取得した app / title / bundleID を設定に合わせて伏せる
+// （skip のときはメインループが記録自体をしないので、ここには来ない）
+func maskPrivateWindow(app, title, bundleID string) (string, string, string) {
+	switch privateWindowMode {
+	case privateModeTrack:
+		return app, title, bundleID
+	case privateModeHide:
+		return privateAppLabel, "", ""
+	}
+	return app, "", bundleID
+}
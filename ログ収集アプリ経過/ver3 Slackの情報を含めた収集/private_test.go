@@ -0,0 +1,44 @@
+package main
+
+import "testing"
+
+func TestLoadPrivateWindowMode(t *testing.T) {
+	tests := []struct{ env, want string }{
+		{"", privateModeApp},
+		{"skip", privateModeSkip},
+		{" Hide ", privateModeHide},
+		{"track", privateModeTrack},
+		{"nope", privateModeApp},
+	}
+	for _, tt := range tests {
+		t.Setenv("SHIRUSIA_PRIVATE_WINDOWS", tt.env)
+		if got := loadPrivateWindowMode(); got != tt.want {
+			t.Errorf("SHIRUSIA_PRIVATE_WINDOWS=%q: mode = %q, want %q", tt.env, got, tt.want)
+		}
+	}
+}
+
+func TestMaskPrivateWindow(t *testing.T) {
+	defer func(m string) { privateWindowMode = m }(privateWindowMode)
+	tests := []struct {
+		mode                 string
+		app, title, bundleID string
+	}{
+		{privateModeApp, "Google Chrome", "", "com.google.Chrome"},
+		{privateModeHide, privateAppLabel, "", ""},
+		{privateModeTrack, "Google Chrome", "病院の予約 - Google 検索", "com.google.Chrome"},
+	}
+	for _, tt := range tests {
+		privateWindowMode = tt.mode
+		app, title, bundleID := maskPrivateWindow("Google Chrome", "病院の予約 - Google 検索", "com.google.Chrome")
+		if app != tt.app || title != tt.title || bundleID != tt.bundleID {
+			t.Errorf("%s: got (%q, %q, %q), want (%q, %q, %q)", tt.mode, app, title, bundleID, tt.app, tt.title, tt.bundleID)
+		}
+	}
+}
+
+func TestEndReasonPrivate(t *testing.T) {
+	if got := endReasonFor(&record{App: "Google Chrome"}, nil, "private"); got != endPrivate {
+		t.Errorf("endReasonFor(private) = %q, want %q", got, endPrivate)
+	}
+}
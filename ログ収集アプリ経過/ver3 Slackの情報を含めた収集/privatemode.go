@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"time"
+)
+
+/********** 集計専用のプライバシーモード（-private） **********/
+// アプリやタイトルを明かさずに「いつ・どのカテゴリに・どれくらい」の傾向だけを共有したい人向けに、
+// ハッシュ化・丸め・フィールドの絞り込みをまとめて有効にする。
+//
+// 保存するもの（これ以外のフィールドは書かない）:
+//   start       開始時刻（5分単位に丸める）
+//   durationSec 長さ（5分単位に丸める。端数の扱いは下記）
+//   activity    カテゴリ
+//   app         "app-xxxxxxxx"（anonymize -apps と同じ HMAC-SHA256 の先頭8桁。SHIRUSIA_PRIVATE_SALT を鍵にする）
+// 保存しないもの:
+//   title・end・endReason・seq・tags・onCall・sharing・space、meta のすべて（元のアプリ名、曲名、
+//   会議名と参加者数、ほかのタブ、入力の種類など）、Slack のメッセージ（取り込み自体を行わない）、
+//   current.json（書かない）。画面の start/end 表示にもアプリ名・タイトルは出さない。
+// 出力先（セッションファイル・-post-url・-syslog・watch・HTTP・-control-stdin の応答）はすべて同じ内容になる。
+// HTTP の /current・/today・/sessions に出す進行中のセッションも同じように丸める（経過時間も5分単位）。
+//
+// 端数の持ち越し: 5分単位に丸めると2.5分未満のセッションは0分になる。捨てると細かい切り替えの多い日ほど
+// 合計が減るので、丸めで出た差（端数、負もありうる）はカテゴリごとに持ち越し、同じカテゴリの次のセッションに
+// 足してから丸める。足しても0分なら書かずに端数だけ持ち越す。これでカテゴリごとの合計は実際の時間と
+// 最大2.5分しか違わない。持ち越した時間は次のセッションのアプリ・開始時刻に付く（どのアプリだったかは残らない）。
+// 持ち越しは起動中だけで、終了時に残っている端数（2.5分未満）は書かない。
+//
+// SHIRUSIA_PRIVATE_SALT を設定しないと、有名なアプリは名前からハッシュを逆算できる。
+const privateBucket = 5 * time.Minute
+
+var privateFields = map[string]bool{"start": true, "durationSec": true, "activity": true, "app": true}
+
+var privateSalt = os.Getenv("SHIRUSIA_PRIVATE_SALT")
+
+// 保存してよい形（丸めた start・durationSec、activity、ハッシュ化した app）に変換する。durationSec は0になりうる
+func privatizeSession(s session, start time.Time) session {
+	return session{
+		Start:       start.Round(privateBucket).Format(time.RFC3339),
+		DurationSec: int64(roundPrivate(time.Duration(s.DurationSec)*time.Second) / time.Second),
+		Activity:    s.Activity,
+		App:         anonymousAppLabel(s.App, privateSalt),
+	}
+}
+
+func roundPrivate(d time.Duration) time.Duration {
+	if d = d.Round(privateBucket); d < 0 {
+		return 0
+	}
+	return d
+}
+
+// 確定したセッションを、カテゴリごとの端数を持ち越しながら丸める
+type privateRounder struct {
+	carry map[string]time.Duration // カテゴリ → 持ち越している端数
+}
+
+// 書くセッションを返す（0分になって書かないなら ok=false。そのときも丸めた形は返す）
+func (r *privateRounder) Round(s session, start time.Time) (out session, ok bool) {
+	if r.carry == nil {
+		r.carry = map[string]time.Duration{}
+	}
+	total := time.Duration(s.DurationSec)*time.Second + r.carry[s.Activity]
+	dur := roundPrivate(total)
+	r.carry[s.Activity] = total - dur
+	out = privatizeSession(s, start)
+	out.DurationSec = int64(dur / time.Second)
+	return out, dur > 0
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// 2.5分未満のセッションも捨てずに、端数をカテゴリごとに持ち越して合計を保つ
+func TestPrivateRounderCarriesRemainder(t *testing.T) {
+	var r privateRounder
+	start := testTime(9, 0)
+	var written []session
+	var want, got int64
+	for i, sec := range []int64{120, 90, 60, 130, 400, 30} {
+		s := session{App: "Slack", Activity: "コミュニケーション", DurationSec: sec}
+		want += sec
+		if p, ok := r.Round(s, start.Add(time.Duration(i)*10*time.Minute)); ok {
+			written = append(written, p)
+			got += p.DurationSec
+		}
+	}
+	if len(written) == 0 {
+		t.Fatal("all short sessions were dropped")
+	}
+	for _, p := range written {
+		if p.DurationSec%int64(privateBucket/time.Second) != 0 {
+			t.Errorf("durationSec %d is not a multiple of 5 minutes", p.DurationSec)
+		}
+	}
+	if d := got - want; d > 150 || d < -150 {
+		t.Errorf("written total %ds, actual %ds: off by more than 2.5 minutes", got, want)
+	}
+}
+
+func TestPrivateRounderPerCategory(t *testing.T) {
+	var r privateRounder
+	at := testTime(9, 0)
+	// 別カテゴリの端数は混ぜない
+	if _, ok := r.Round(session{Activity: "コミュニケーション", DurationSec: 140}, at); ok {
+		t.Fatal("140s session written")
+	}
+	if _, ok := r.Round(session{Activity: "プログラムの制作", DurationSec: 140}, at); ok {
+		t.Error("carry from another category was added")
+	}
+	p, ok := r.Round(session{Activity: "コミュニケーション", DurationSec: 140}, at)
+	if !ok || p.DurationSec != 300 {
+		t.Errorf("second 140s session = %d, %v; want 300, true", p.DurationSec, ok)
+	}
+}
+
+func TestLiveStatePrivateInProgress(t *testing.T) {
+	l := newLiveState(time.Minute)
+	l.private = true
+	start := time.Date(2025, 9, 1, 10, 1, 40, 0, time.Local)
+	l.SetCurrent(&record{App: "Visual Studio Code", Title: "secret.go", Activity: "プログラムの制作"}, start)
+
+	now := start.Add(12*time.Minute + 50*time.Second)
+	s, elapsed, ok := l.Current(now)
+	if !ok {
+		t.Fatal("no current session")
+	}
+	if strings.Contains(s.App, "Visual Studio") || s.Title != "" || s.End != "" {
+		t.Errorf("current session leaks app/title/end: %+v", s)
+	}
+	if !strings.HasPrefix(s.App, "app-") {
+		t.Errorf("app = %q, want a hashed label", s.App)
+	}
+	if s.Start != start.Round(privateBucket).Format(time.RFC3339) || s.DurationSec != 900 {
+		t.Errorf("current = %s %ds, want rounded start and 900s", s.Start, s.DurationSec)
+	}
+	if elapsed != 15*time.Minute {
+		t.Errorf("elapsed = %s, want 15m", elapsed)
+	}
+
+	today := l.Today(now)
+	if len(today) != 1 || today[0].App != s.App || today[0].Start != s.Start || today[0].DurationSec != s.DurationSec {
+		t.Errorf("today = %+v, want the same rounded session", today)
+	}
+	// 0分に丸まる進行中セッションは出さない
+	if got := l.Today(start.Add(time.Minute)); len(got) != 0 {
+		t.Errorf("today right after start = %+v", got)
+	}
+
+	l.private = false
+	if s, _, _ := l.Current(now); s.App != "Visual Studio Code" || s.Title != "secret.go" {
+		t.Errorf("without -private = %+v", s)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+/********** タイトル・URLを伏せるアプリとパターン **********/
+// パスワードマネージャのエントリ名や非公開の文書名、銀行のページなどはタイトルだけでも中身が分かってしまう。
+// 一致したときはタイトルを "[redacted]" に置き換え、URL は記録しない。アプリ名・カテゴリ・時間はそのまま残す。
+// カテゴリは伏せる前のタイトルで判定する。画面の start 表示（short）も保存するセッションも伏せたタイトルになる。
+//
+// SHIRUSIA_REDACT_APPS="Secret Notes,com.example.vault"
+//   アプリ名または bundle ID（大文字小文字を無視した完全一致）。defaultRedactApps に追加される
+// SHIRUSIA_REDACT_TITLES="payslip;^Private -"
+//   タイトルかURLに一致したら伏せる正規表現（";" 区切り。大文字小文字は無視）
+const redactedTitle = "[redacted]"
+
+var defaultRedactApps = []string{
+	"1password", "bitwarden", "keychain access", "passwords", "keepassxc", "dashlane", "lastpass", "enpass",
+	"com.1password.1password", "com.bitwarden.desktop", "com.apple.keychainaccess", "com.apple.passwords",
+}
+
+var (
+	redactApps   = append(append([]string{}, defaultRedactApps...), splitList(os.Getenv("SHIRUSIA_REDACT_APPS"))...)
+	redactTitles = loadRedactTitles()
+)
+
+func loadRedactTitles() []*regexp.Regexp {
+	var out []*regexp.Regexp
+	for _, p := range strings.Split(os.Getenv("SHIRUSIA_REDACT_TITLES"), ";") {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		re, err := regexp.Compile("(?i)" + p)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warn: SHIRUSIA_REDACT_TITLES: invalid pattern %q: %v\n", p, err)
+			continue
+		}
+		out = append(out, re)
+	}
+	return out
+}
+
+// 伏せるべきウィンドウか
+func shouldRedact(app, bundleID, title, pageURL string) bool {
+	if containsString(redactApps, strings.ToLower(app)) ||
+		(bundleID != "" && containsString(redactApps, strings.ToLower(bundleID))) {
+		return true
+	}
+	for _, re := range redactTitles {
+		if re.MatchString(title) || (pageURL != "" && re.MatchString(pageURL)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+/********** 途中で切れたJSON配列ファイルの修復 **********/
+// 閉じ括弧の "]" は jsonArrayWriter.Close でしか書かないので、クラッシュや kill -9 のあとは
+// activity_*.json / buckets_*.json が JSON として読めないまま残る（readSessionsFile は読めるが、
+// jq など外のツールでは開けない）。起動時、新しいファイルを作る前に logDir の .json を調べ、
+// 壊れているものを修復する。
+//
+// 修復は「最後まで読めた要素の直後で切り、"]" を付ける」だけ。末尾の ",\n" や書きかけの要素は捨てる。
+// 同じディレクトリの一時ファイルに書いてから rename する（writeFileAtomic）ので、途中で落ちても元のファイルは残る。
+// 更新日時は元のまま（report などは更新日時で当日分を選ぶため）。
+var errNotJSONArray = errors.New("not a JSON array")
+
+// logDir 内の壊れた activity_*.json / buckets_*.json を修復する
+func repairSessionFiles(dir string) {
+	var paths []string
+	for _, pat := range []string{"activity_*.json", "buckets_*.json"} {
+		m, _ := filepath.Glob(filepath.Join(dir, pat))
+		paths = append(paths, m...)
+	}
+	for _, p := range paths {
+		n, repaired, err := repairArrayFile(p)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "repair %s: %v\n", p, err)
+			continue
+		}
+		if repaired {
+			fmt.Printf("repaired truncated log %s (%d entries kept)\n", p, n)
+		}
+	}
+}
+
+// path が閉じていないJSON配列なら、読めた要素までで閉じて書き直す。
+// n は残した要素数、repaired は書き直したかどうか（正しいファイルなら false）
+func repairArrayFile(path string) (n int, repaired bool, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false, err
+	}
+	if json.Valid(data) {
+		return 0, false, nil
+	}
+	fixed, n, err := truncateArray(data)
+	if err != nil {
+		return 0, false, err
+	}
+	st, err := os.Stat(path)
+	if err != nil {
+		return 0, false, err
+	}
+	if err := writeFileAtomic(path, fixed); err != nil { // current.go
+		return 0, false, err
+	}
+	os.Chtimes(path, st.ModTime(), st.ModTime())
+	return n, true, nil
+}
+
+// 最後まで読めた要素の直後で切って "\n]\n" を付けたものを返す
+func truncateArray(data []byte) ([]byte, int, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return []byte("[\n]\n"), 0, nil // "[" を書く前に落ちた
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return nil, 0, errNotJSONArray
+	}
+	end := dec.InputOffset() // '[' の直後
+	n := 0
+	for dec.More() {
+		var v json.RawMessage
+		if err := dec.Decode(&v); err != nil {
+			break
+		}
+		end = dec.InputOffset()
+		n++
+	}
+	out := append(bytes.TrimRight(data[:end:end], " \t\r\n"), "\n]\n"...)
+	return out, n, nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRepairArrayFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		n        int
+		repaired bool
+		err      error
+	}{
+		{"closed", "[\n{\"app\":\"a\"},\n{\"app\":\"b\"}\n]\n", 0, false, nil},
+		{"closed empty", "[\n]\n", 0, false, nil},
+		{"truncated mid-object", "[\n{\"app\":\"a\"},\n{\"app\":\"b\"},\n{\"app\":", 2, true, nil},
+		{"truncated inside a string", "[\n{\"app\":\"a\"},\n{\"title\":\"途中で", 1, true, nil},
+		{"truncated after a comma", "[\n{\"app\":\"a\"},\n{\"app\":\"b\"},\n", 2, true, nil},
+		{"only the bracket", "[\n", 0, true, nil},
+		{"empty file", "", 0, true, nil},
+		{"not an array", "{\"app\":\"a\"", 0, false, errNotJSONArray},
+	}
+	old := time.Date(2025, 9, 1, 23, 0, 0, 0, time.Local)
+	for _, tt := range tests {
+		path := writeTestFile(t, "activity_20250901_090000.json", tt.content)
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+		n, repaired, err := repairArrayFile(path)
+		if !errors.Is(err, tt.err) || n != tt.n || repaired != tt.repaired {
+			t.Errorf("%s: repairArrayFile = %d, %v, %v; want %d, %v, %v", tt.name, n, repaired, err, tt.n, tt.repaired, tt.err)
+			continue
+		}
+		data, _ := os.ReadFile(path)
+		if tt.err != nil {
+			if string(data) != tt.content {
+				t.Errorf("%s: file was modified on error: %q", tt.name, data)
+			}
+			continue
+		}
+		var elems []json.RawMessage
+		if err := json.Unmarshal(data, &elems); err != nil {
+			t.Errorf("%s: result is not a JSON array: %v\n%s", tt.name, err, data)
+			continue
+		}
+		if tt.repaired && len(elems) != tt.n {
+			t.Errorf("%s: %d elements in the file, want %d", tt.name, len(elems), tt.n)
+		}
+		// 更新日時は元のまま（report などが更新日時で当日分を選ぶため）
+		if st, _ := os.Stat(path); !st.ModTime().Equal(old) {
+			t.Errorf("%s: mtime = %s, want %s", tt.name, st.ModTime(), old)
+		}
+	}
+}
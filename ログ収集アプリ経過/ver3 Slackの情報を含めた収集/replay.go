@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+/********** 記録の再生（-replay / -replay-speed、テスト用） **********/
+// 記録済みのセッションファイル（activity_*.json）を「その時刻に前面だったもの」として再生し、
+// osascript の代わりにメインループへ渡す。分類やセッション区切りの挙動を実機なしで再現するためのもの。
+//
+// -replay-speed は再生倍率（2 なら2倍速）。ループは仮想時計（記録上の時刻）で動き、
+// ポーリング間隔も同じ倍率で縮めるので、倍速で再生しても出来上がるセッションの時刻・長さは
+// 等倍で再生した場合と同じになる（違いはポーリング粒度ぶんの誤差のみ）。
+type replayObs struct {
+	at, end time.Time
+	app     string
+	title   string
+	idle    bool
+}
+
+type replaySource struct {
+	obs    []replayObs
+	origin time.Time // 記録上の再生開始時刻
+	wall   time.Time // 実時間での再生開始時刻
+	speed  float64
+}
+
+func newReplaySource(path string, speed float64) (*replaySource, error) {
+	if speed <= 0 {
+		return nil, fmt.Errorf("invalid -replay-speed %v", speed)
+	}
+	ss, err := readSessionsFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var obs []replayObs
+	for _, s := range ss {
+		at, err1 := time.Parse(time.RFC3339, s.Start)
+		end, err2 := time.Parse(time.RFC3339, s.End)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		obs = append(obs, replayObs{at: at, end: end, app: s.App, title: s.Title, idle: s.App == idleApp})
+	}
+	if len(obs) == 0 {
+		return nil, errors.New("no sessions to replay")
+	}
+	return &replaySource{obs: obs, origin: obs[0].at, wall: time.Now(), speed: speed}, nil
+}
+
+// 仮想時計の現在時刻
+func (r *replaySource) Now() time.Time {
+	return r.origin.Add(time.Duration(float64(time.Since(r.wall)) * r.speed))
+}
+
+// 仮想時間 d を実時間に換算する（タイマー用）
+func (r *replaySource) Scale(d time.Duration) time.Duration {
+	return time.Duration(float64(d) / r.speed)
+}
+
+// 記録の終わり
+func (r *replaySource) End() time.Time {
+	return r.obs[len(r.obs)-1].end
+}
+
+// 時刻 t に前面だったもの。記録の終わりを過ぎたら done=true
+func (r *replaySource) At(t time.Time) (ob replayObs, done bool) {
+	if !t.Before(r.End()) {
+		return replayObs{}, true
+	}
+	ob = r.obs[0]
+	for _, o := range r.obs {
+		if o.at.After(t) {
+			break
+		}
+		ob = o
+	}
+	return ob, false
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+/********** report サブコマンド **********/
+// セッションログを集計してカテゴリ別・アプリ別の時間を表示する。
+//   activitylog report [-merge-browsers] [-browsers "Safari,Google Chrome"] [file.json|glob ...]
+// -format markdown は同じ集計を Notion / Obsidian などに貼れる Markdown で出す。
+//   activitylog report -weekly [-json] [file.json|glob ...]
+//   activitylog report -focus [-focus-categories ...] [-distraction-categories ...] [file.json|glob ...]
+// -focus の分け方は SHIRUSIA_FOCUS_RULES でアプリ・タイトル単位に上書きできる（focusrules.go）。
+// ファイルを省略すると logDir の当日分（今日更新されたファイル）を読む。
+// -merge-browsers はアプリ別集計でブラウザを "Browser" 1つにまとめる（生ログは変更しない）。
+var defaultReportBrowsers = []string{
+	"safari", "google chrome", "chrome", "microsoft edge", "firefox", "arc",
+	"brave browser", "vivaldi", "opera", "chromium",
+}
+
+type reportOptions struct {
+	MergeBrowsers bool
+	Browsers      []string // 小文字のアプリ名（完全一致）
+}
+
+type reportTotals struct {
+	TotalSec int64
+	Activity map[string]int64
+	App      map[string]int64
+	Meeting  map[string]int64 // meta.meetingTopic → 秒
+	Sharing  int64            // 画面収録・画面共有中だった秒数（-detect-sharing）
+	Files    int
+	Sessions int
+}
+
+func runReport(args []string) int {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	merge := fs.Bool("merge-browsers", false, "report all browsers as a single \"Browser\" app")
+	browsers := fs.String("browsers", "", "comma-separated browser app names for -merge-browsers (default: common browsers)")
+	focus := fs.Bool("focus", false, "report focus vs distraction time, longest focus streak and context switches per day")
+	focusCats := fs.String("focus-categories", "", "comma-separated focus categories for -focus (default: work categories)")
+	distractCats := fs.String("distraction-categories", "", "comma-separated distraction categories for -focus (default: Webブラウジング, メディア視聴・再生, ゲーム)")
+	weekly := fs.Bool("weekly", false, "day-of-week x hour-of-day heatmaps per category (default input: the last 7 days)")
+	asJSON := fs.Bool("json", false, "with -weekly: print the heatmap data as JSON")
+	format := fs.String("format", "text", "output format for the default report: text or markdown")
+	fs.Parse(args)
+	if *format != "text" && *format != "markdown" && *format != "md" {
+		fmt.Fprintf(os.Stderr, "report: unknown -format %q (text, markdown)\n", *format)
+		return 2
+	}
+
+	opt := reportOptions{MergeBrowsers: *merge, Browsers: defaultReportBrowsers}
+	if list := splitList(*browsers); len(list) > 0 {
+		opt.Browsers = list
+	}
+
+	since := startOfDay(time.Now())
+	if *weekly {
+		since = since.AddDate(0, 0, -6)
+	}
+	files, err := reportInputFiles(fs.Args(), since)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "report: %v\n", err)
+		return 1
+	}
+	if len(files) == 0 {
+		fmt.Fprintln(os.Stderr, "report: no session files")
+		return 1
+	}
+	var all []session
+	for _, p := range files {
+		ss, err := readSessionsFile(p)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "report: skip %s: %v\n", p, err)
+			continue
+		}
+		all = append(all, ss...)
+	}
+	if *weekly {
+		r := aggregateWeekly(all)
+		if *asJSON {
+			if err := writeWeeklyJSON(os.Stdout, r); err != nil {
+				fmt.Fprintf(os.Stderr, "report: %v\n", err)
+				return 1
+			}
+			return 0
+		}
+		printWeekly(os.Stdout, r)
+		return 0
+	}
+	if *focus {
+		fc, dc := defaultFocusCategories, defaultDistractionCategories
+		if list := splitList(*focusCats); len(list) > 0 {
+			fc = list
+		}
+		if list := splitList(*distractCats); len(list) > 0 {
+			dc = list
+		}
+		printFocus(os.Stdout, aggregateFocus(all, categorySet(fc), categorySet(dc)))
+		return 0
+	}
+	t := aggregateSessions(all, opt)
+	t.Files = len(files)
+	if *format == "text" {
+		printReport(os.Stdout, t)
+	} else {
+		printReportMarkdown(os.Stdout, t)
+	}
+	return 0
+}
+
+// 引数のファイル/グロブを展開する。引数なしなら since 以降に更新されたファイル
+func reportInputFiles(args []string, since time.Time) ([]string, error) {
+	if len(args) == 0 {
+		return sessionFilesSince(logDir, since)
+	}
+	var out []string
+	for _, a := range args {
+		m, err := filepath.Glob(a)
+		if err != nil {
+			return nil, err
+		}
+		if m == nil {
+			return nil, fmt.Errorf("no such file: %s", a)
+		}
+		out = append(out, m...)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+func aggregateSessions(ss []session, opt reportOptions) reportTotals {
+	t := reportTotals{Activity: map[string]int64{}, App: map[string]int64{}, Meeting: map[string]int64{}}
+	for _, s := range ss {
+		t.Sessions++
+		t.TotalSec += s.DurationSec
+		t.Activity[s.Activity] += s.DurationSec
+		if s.Sharing {
+			t.Sharing += s.DurationSec
+		}
+		t.App[reportAppName(s.App, opt)] += s.DurationSec
+		if topic := s.Meta["meetingTopic"]; topic != "" {
+			t.Meeting[topic] += s.DurationSec
+		}
+	}
+	return t
+}
+
+func reportAppName(app string, opt reportOptions) string {
+	if opt.MergeBrowsers {
+		low := strings.ToLower(app)
+		for _, b := range opt.Browsers {
+			if low == b {
+				return "Browser"
+			}
+		}
+	}
+	return app
+}
+
+func printReport(w io.Writer, t reportTotals) {
+	fmt.Fprintf(w, "合計 %s（%dファイル / %dセッション）\n", fmtDur(t.TotalSec), t.Files, t.Sessions)
+	if t.Sharing > 0 {
+		fmt.Fprintf(w, "うち画面共有 %s（%.1f%%）\n", fmtDur(t.Sharing), percent(t.Sharing, t.TotalSec))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "■ カテゴリ別")
+	printRanking(w, t.Activity, t.TotalSec, 0)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "■ アプリ別")
+	printRanking(w, t.App, t.TotalSec, 15)
+	if len(t.Meeting) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "■ 会議別")
+		printRanking(w, t.Meeting, t.TotalSec, 0)
+	}
+}
+
+// printReport と同じ内容の Markdown 版（カテゴリは表、アプリと会議は箇条書き）
+func printReportMarkdown(w io.Writer, t reportTotals) {
+	fmt.Fprintln(w, "## 作業時間")
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "合計 **%s**（%dファイル / %dセッション）\n", fmtDur(t.TotalSec), t.Files, t.Sessions)
+	if t.Sharing > 0 {
+		fmt.Fprintf(w, "うち画面共有 **%s**（%.1f%%）\n", fmtDur(t.Sharing), percent(t.Sharing, t.TotalSec))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "| カテゴリ | 時間 | 割合 |")
+	fmt.Fprintln(w, "|---|---:|---:|")
+	for _, k := range sortedKeysByValue(t.Activity) {
+		fmt.Fprintf(w, "| %s | %s | %.1f%% |\n", escapeMarkdownCell(k), fmtDur(t.Activity[k]), percent(t.Activity[k], t.TotalSec))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "### よく使ったアプリ")
+	fmt.Fprintln(w)
+	printMarkdownList(w, t.App, t.TotalSec, 15)
+	if len(t.Meeting) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "### 会議")
+		fmt.Fprintln(w)
+		printMarkdownList(w, t.Meeting, t.TotalSec, 0)
+	}
+}
+
+func printMarkdownList(w io.Writer, m map[string]int64, total int64, limit int) {
+	for i, k := range sortedKeysByValue(m) {
+		if limit > 0 && i >= limit {
+			break
+		}
+		fmt.Fprintf(w, "- **%s** — %s（%.1f%%）\n", escapeMarkdownText(k), fmtDur(m[k]), percent(m[k], total))
+	}
+}
+
+// 表のセルを壊す "|" と改行をエスケープする
+func escapeMarkdownCell(s string) string {
+	s = strings.ReplaceAll(escapeMarkdownText(s), "|", `\|`)
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
+
+// 強調・リンクなどとして解釈される記号をエスケープする
+func escapeMarkdownText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`).Replace(s)
+}
+
+// 時間の長い順に表示する（limit > 0 なら上位 limit 件）
+func printRanking(w io.Writer, m map[string]int64, total int64, limit int) {
+	for i, k := range sortedKeysByValue(m) {
+		if limit > 0 && i >= limit {
+			break
+		}
+		fmt.Fprintf(w, "  %s %10s %5.1f%%\n", padRight(k, 28), fmtDur(m[k]), percent(m[k], total))
+	}
+}
+
+func sortedKeysByValue(m map[string]int64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if m[keys[i]] != m[keys[j]] {
+			return m[keys[i]] > m[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// 全角文字を幅2として数え、表示幅 width まで空白で埋める
+func padRight(s string, width int) string {
+	if w := displayWidth(s); w < width {
+		return s + strings.Repeat(" ", width-w)
+	}
+	return s
+}
+
+// padRight の右寄せ版
+func padLeft(s string, width int) string {
+	if w := displayWidth(s); w < width {
+		return strings.Repeat(" ", width-w) + s
+	}
+	return s
+}
+
+func displayWidth(s string) int {
+	w := 0
+	for _, r := range s {
+		if r >= 0x1100 {
+			w += 2
+		} else {
+			w++
+		}
+	}
+	return w
+}
+
+func percent(v, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(v) * 100 / float64(total)
+}
+
+// 秒 → "1h23m45s"
+func fmtDur(sec int64) string {
+	return (time.Duration(sec) * time.Second).String()
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/********** ログファイルの保持数（-retain） **********/
+// 常駐させると日ごとのセッションファイルが増え続けるので、起動時と日付の変更や -max-file-size で
+// ファイルを切り替えたときに、古いものから -retain 件を超えた分を削除する。
+// 消すのはこのロガーが作った名前（activity_YYYYMMDD_HHMMSS[_NN].json / .json.gz / .ndjson）のファイルだけで、
+// 名前の日時順で古いものから消す。書き込み中のファイルは消さない。
+var sessionFileRe = regexp.MustCompile(`^activity_\d{8}_\d{6}(_\d{2})?\.(json(\.gz)?|ndjson)$`)
+
+func pruneSessionFiles(dir string, keep int, current string) {
+	if keep <= 0 {
+		return
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "retain: %v\n", err)
+		return
+	}
+	var names []string
+	for _, e := range entries {
+		if e.Type().IsRegular() && sessionFileRe.MatchString(e.Name()) {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names) // 名前の日時部分が固定長なので文字列順 = 古い順
+	for i := 0; i < len(names)-keep; i++ {
+		p := filepath.Join(dir, names[i])
+		if p == current {
+			continue
+		}
+		if err := os.Remove(p); err != nil {
+			fmt.Fprintf(os.Stderr, "retain: %v\n", err)
+			continue
+		}
+		fmt.Printf("retain: removed old log %s\n", p)
+	}
+}
+
+// 日付ごとのファイルの切り替え（-rotate-daily、既定 true）。-retain とは別の設定で、false なら
+// -max-file-size と {"cmd":"rotate"} のときだけ切り替える（起動から終了まで1ファイル。0:00 でのセッションの区切りは変わらない）
+var rotateDaily = true
+
+// 日付が変わっていたら（-rotate-daily）、または maxSize（>0）バイトに達していたら新しいセッションファイルに切り替える。
+// 大きさは次のセッションを書く前に見るので、ファイルは最後の1件ぶんだけ maxSize を超えることがある。
+func rotateSessionFile(jw sessionFileSink, now time.Time, keep int, maxSize int64) (sessionFileSink, error) {
+	if lf := jw.file(); (!rotateDaily || sameDay(lf.opened, now)) && (maxSize <= 0 || lf.size < maxSize) {
+		return jw, nil
+	}
+	return switchSessionFile(jw, keep)
+}
+
+// 今のセッションファイルを閉じて新しいファイルに切り替える（日付の切り替えと {"cmd":"rotate"}）
+func switchSessionFile(jw sessionFileSink, keep int) (sessionFileSink, error) {
+	dir := filepath.Dir(jw.file().path)
+	next, err := newSessionFile(dir)
+	if err != nil {
+		return jw, err // 作れなければ今のファイルに書き続ける
+	}
+	next.file().pretty = jw.file().pretty
+	if err := jw.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "close error: %v\n", err)
+	}
+	fmt.Printf("Logging sessions to: %s\n", next.file().path)
+	pruneSessionFiles(dir, keep, next.file().path)
+	return next, nil
+}
+
+// "50MB" / "512KB" / "1GB" / "1048576" を解釈する（1KB = 1024 バイト。空なら0）
+func parseByteSize(s string) (int64, error) {
+	orig := s
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" {
+		return 0, nil
+	}
+	mult := int64(1)
+	for _, u := range []struct {
+		suffix string
+		mult   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(s, u.suffix) {
+			s, mult = strings.TrimSpace(strings.TrimSuffix(s, u.suffix)), u.mult
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q (e.g. 50MB)", orig)
+	}
+	return n * mult, nil
+}
+
+func sameDay(a, b time.Time) bool {
+	return a.Format("2006-01-02") == b.Format("2006-01-02")
+}
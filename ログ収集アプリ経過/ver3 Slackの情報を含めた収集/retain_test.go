@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRotateSessionFileDaily(t *testing.T) {
+	defer func(v bool) { rotateDaily = v }(rotateDaily)
+	dir := t.TempDir()
+	w, err := newSessionFile(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := w.file().opened.AddDate(0, 0, 1)
+
+	rotateDaily = false
+	got, err := rotateSessionFile(w, next, 0, 0)
+	if err != nil || got != w {
+		t.Fatalf("-rotate-daily=false switched files on a date change (err=%v)", err)
+	}
+
+	rotateDaily = true
+	got, err = rotateSessionFile(w, next, 0, 0)
+	if err != nil || got == w {
+		t.Fatalf("-rotate-daily did not switch files on a date change (err=%v)", err)
+	}
+	got.Close()
+	if n, _ := filepath.Glob(filepath.Join(dir, "activity_*")); len(n) != 2 {
+		t.Errorf("files = %v, want 2", n)
+	}
+}
+
+// 同じ秒に何度切り替えても、番号付きの別ファイルになる（今のファイルを上書きしない・失敗しない）
+func TestSwitchSessionFileSameSecond(t *testing.T) {
+	dir := t.TempDir()
+	w, err := newSessionFile(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if w, err = switchSessionFile(w, 0); err != nil {
+			t.Fatalf("switch %d: %v", i+1, err)
+		}
+	}
+	w.Close()
+	files, _ := sessionFiles(dir)
+	if len(files) != 4 {
+		t.Fatalf("files = %v, want 4", files)
+	}
+	for _, f := range files {
+		if !sessionFileRe.MatchString(filepath.Base(f)) {
+			t.Errorf("%s does not match sessionFileRe (-retain would never prune it)", f)
+		}
+	}
+}
+
+func TestPruneSessionFilesOnlyOwnNames(t *testing.T) {
+	dir := t.TempDir()
+	names := []string{
+		"activity_20250901_090000.json", "activity_20250902_090000.json.gz",
+		"activity_20250903_090000.ndjson", "activity_20250904_090000.json",
+		"notes.json", "activity_backup.json", "summary_20250901.json",
+	}
+	for _, n := range names {
+		if err := os.WriteFile(filepath.Join(dir, n), []byte("[]"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pruneSessionFiles(dir, 2, filepath.Join(dir, "activity_20250904_090000.json"))
+	for _, n := range names {
+		_, err := os.Stat(filepath.Join(dir, n))
+		removed := os.IsNotExist(err)
+		want := n == "activity_20250901_090000.json" || n == "activity_20250902_090000.json.gz"
+		if removed != want {
+			t.Errorf("%s: removed = %v, want %v", n, removed, want)
+		}
+	}
+}
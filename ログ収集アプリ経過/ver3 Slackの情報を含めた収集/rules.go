@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+/********** ルールファイルによる分類（rules.yaml） **********/
+// 組み込みの分類（builtinRules）はキーワードが Go に書いてあるので、調整のたびに再ビルドが要る。
+// ルールファイルがあれば、組み込みより先にその中のルールを上から順に評価し、最初に一致したものを使う。
+//
+// SHIRUSIA_RULES  ルールファイルのパス。未設定なら既定の保存先（config.go）の rules.yaml
+//   拡張子が .json なら JSON として、それ以外は YAML として読む。ファイルがなければ組み込みだけで分類する。
+//
+//   default: その他            # 省略可。書くとどのルールにも当たらないときにこれを使い、組み込みの分類はしない
+//   rules:
+//     - app: slack             # アプリ名の部分一致
+//       title: "#incident"     # タイトルの部分一致
+//       category: 障害対応
+//     - url: atlassian.net     # URL の部分一致（URL が取れるブラウザのみ）
+//       category: チケット管理
+//     - titleRegex: '\.go$'     # appRegex / titleRegex / urlRegex は正規表現（読み込み時に1回だけコンパイル）
+//       category: Go
+//   terminalApps: [Tabby, com.example.term]  # 組み込みに加えてターミナルとして扱うアプリ名か bundle ID（完全一致。terminal.go）
+//   aiApps: [Msty, com.example.assistant]    # 組み込みに加えて「AI活用」にするアプリ名か bundle ID（完全一致）
+//   aiHosts: [chat.mistral.ai]               # 組み込みに加えて「AI活用」にする URL のホスト（サブドメインも含む）
+//   careerApps: [Offers]                     # キャリア・求職。同じく design / reading / infra / finance の
+//   careerHosts: [jobs.example.com]          #   <カテゴリ>Apps（アプリ名か bundle ID・完全一致）、<カテゴリ>Hosts（URL のホスト）、
+//   careerTitles: [選考]                     #   <カテゴリ>Titles（タイトルの部分一致）で組み込みのキーワードに足せる（classify_keywords.go）
+//
+// 照合は大文字小文字を無視する。1つのルールに複数の条件を書いたときはすべてに一致したときだけ当たる。
+// 読めないファイルは警告を出して無視し、組み込みの分類で動く。起動時に1回だけ読む。
+type ruleSet struct {
+	Default       string     `yaml:"default" json:"default"`
+	Rules         []fileRule `yaml:"rules" json:"rules"`
+	TerminalApps  []string   `yaml:"terminalApps" json:"terminalApps"`
+	AIApps        []string   `yaml:"aiApps" json:"aiApps"`
+	AIHosts       []string   `yaml:"aiHosts" json:"aiHosts"`
+	DesignApps    []string   `yaml:"designApps" json:"designApps"`
+	DesignHosts   []string   `yaml:"designHosts" json:"designHosts"`
+	DesignTitles  []string   `yaml:"designTitles" json:"designTitles"`
+	ReadingApps   []string   `yaml:"readingApps" json:"readingApps"`
+	ReadingHosts  []string   `yaml:"readingHosts" json:"readingHosts"`
+	ReadingTitles []string   `yaml:"readingTitles" json:"readingTitles"`
+	InfraApps     []string   `yaml:"infraApps" json:"infraApps"`
+	InfraHosts    []string   `yaml:"infraHosts" json:"infraHosts"`
+	InfraTitles   []string   `yaml:"infraTitles" json:"infraTitles"`
+	FinanceApps   []string   `yaml:"financeApps" json:"financeApps"`
+	FinanceHosts  []string   `yaml:"financeHosts" json:"financeHosts"`
+	FinanceTitles []string   `yaml:"financeTitles" json:"financeTitles"`
+	CareerApps    []string   `yaml:"careerApps" json:"careerApps"`
+	CareerHosts   []string   `yaml:"careerHosts" json:"careerHosts"`
+	CareerTitles  []string   `yaml:"careerTitles" json:"careerTitles"`
+	source        string     // 読み込んだファイル。なければ空
+}
+
+type fileRule struct {
+	App        string `yaml:"app" json:"app"`
+	Title      string `yaml:"title" json:"title"`
+	URL        string `yaml:"url" json:"url"`
+	AppRegex   string `yaml:"appRegex" json:"appRegex"`
+	TitleRegex string `yaml:"titleRegex" json:"titleRegex"`
+	URLRegex   string `yaml:"urlRegex" json:"urlRegex"`
+	Category   string `yaml:"category" json:"category"`
+
+	appRe, titleRe, urlRe *regexp.Regexp
+	n                     int // ファイル内での番号（1始まり。matchedBy の "rules:N"）
+}
+
+var userRules = loadRules(loadDataDir("SHIRUSIA_RULES", "rules.yaml"))
+
+func loadRules(path string) *ruleSet {
+	rs, err := readRulesFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "warn: rules %s: %v (using builtin rules)\n", path, err)
+		}
+		return &ruleSet{}
+	}
+	return rs
+}
+
+func readRulesFile(path string) (*ruleSet, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rs := &ruleSet{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(b, rs)
+	} else {
+		err = yaml.Unmarshal(b, rs)
+	}
+	if err != nil {
+		return nil, err
+	}
+	rules := rs.Rules[:0]
+	for i, r := range rs.Rules {
+		r, err := compileFileRule(r)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warn: rules %s: rule %d: %v\n", path, i+1, err)
+			continue
+		}
+		r.n = i + 1
+		rules = append(rules, r)
+	}
+	rs.Rules = rules
+	rs.Default = strings.TrimSpace(rs.Default)
+	for _, list := range [][]string{
+		rs.TerminalApps, rs.AIApps, rs.AIHosts,
+		rs.DesignApps, rs.DesignHosts, rs.DesignTitles, rs.ReadingApps, rs.ReadingHosts, rs.ReadingTitles,
+		rs.InfraApps, rs.InfraHosts, rs.InfraTitles, rs.FinanceApps, rs.FinanceHosts, rs.FinanceTitles,
+		rs.CareerApps, rs.CareerHosts, rs.CareerTitles,
+	} {
+		for i, a := range list {
+			list[i] = strings.ToLower(strings.TrimSpace(a))
+		}
+	}
+	rs.source = path
+	return rs, nil
+}
+
+// 部分一致は小文字にそろえ、正規表現は大文字小文字を無視するものとしてコンパイルする
+func compileFileRule(r fileRule) (fileRule, error) {
+	out := fileRule{
+		App:      strings.ToLower(strings.TrimSpace(r.App)),
+		Title:    strings.ToLower(strings.TrimSpace(r.Title)),
+		URL:      strings.ToLower(strings.TrimSpace(r.URL)),
+		Category: strings.TrimSpace(r.Category),
+	}
+	for _, x := range []struct {
+		name, src string
+		dst       **regexp.Regexp
+	}{
+		{"appRegex", r.AppRegex, &out.appRe},
+		{"titleRegex", r.TitleRegex, &out.titleRe},
+		{"urlRegex", r.URLRegex, &out.urlRe},
+	} {
+		if x.src == "" {
+			continue
+		}
+		re, err := regexp.Compile("(?i)" + x.src)
+		if err != nil {
+			return fileRule{}, fmt.Errorf("invalid %s: %v", x.name, err)
+		}
+		*x.dst = re
+	}
+	if out.Category == "" {
+		return fileRule{}, errors.New("missing category")
+	}
+	if out.App == "" && out.Title == "" && out.URL == "" && out.appRe == nil && out.titleRe == nil && out.urlRe == nil {
+		return fileRule{}, errors.New("needs at least one of app, title, url (or their *Regex forms)")
+	}
+	return out, nil
+}
+
+// 最初に一致したルールのカテゴリと matchedBy（"rules:N"）。どれにも当たらなければ default（"rules:default"）、
+// default もなければ空
+func (rs *ruleSet) match(in activityInput) (category, by string) {
+	a := strings.ToLower(in.App)
+	t := strings.ToLower(in.Title)
+	u := strings.ToLower(in.URL)
+	for _, r := range rs.Rules {
+		if r.App != "" && !strings.Contains(a, r.App) {
+			continue
+		}
+		if r.Title != "" && !strings.Contains(t, r.Title) {
+			continue
+		}
+		if r.URL != "" && !strings.Contains(u, r.URL) {
+			continue
+		}
+		if (r.appRe != nil && !r.appRe.MatchString(in.App)) ||
+			(r.titleRe != nil && !r.titleRe.MatchString(in.Title)) ||
+			(r.urlRe != nil && !r.urlRe.MatchString(in.URL)) {
+			continue
+		}
+		return r.Category, fmt.Sprintf("rules:%d", r.n)
+	}
+	if rs.Default != "" {
+		return rs.Default, "rules:default"
+	}
+	return "", ""
+}
+
+// -print-config の rulesSource
+func (rs *ruleSet) describe() string {
+	if rs.source == "" {
+		return "builtin"
+	}
+	return rs.source
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+const sampleRules = `
+rules:
+  - app: slack
+    title: "#incident"
+    category: 障害対応
+  - app: slack
+    category: チャット
+  - titleRegex: '\.go$'
+    category: Go
+  - url: atlassian.net
+    category: チケット管理
+  - titleRegex: '('          # コンパイルできないので読み飛ばす（番号は詰めない）
+    category: 壊れたルール
+  - app: zoom                # category がないので読み飛ばす
+  - appRegex: '^visual studio code$'
+    title: README
+    category: ドキュメント
+`
+
+func TestReadRulesFilePrecedence(t *testing.T) {
+	rs, err := readRulesFile(writeTestFile(t, "rules.yaml", sampleRules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs.Rules) != 5 {
+		t.Fatalf("loaded %d rules, want 5 (2 invalid ones skipped)", len(rs.Rules))
+	}
+	tests := []struct {
+		name string
+		in   activityInput
+		cat  string
+		by   string
+	}{
+		// 上から順に評価し、最初に一致したものを使う
+		{"first match wins", activityInput{App: "Slack", Title: "#incident-42 | Acme"}, "障害対応", "rules:1"},
+		{"second rule", activityInput{App: "Slack", Title: "#random"}, "チャット", "rules:2"},
+		{"regex ignores case", activityInput{App: "Vim", Title: "MAIN.GO"}, "Go", "rules:3"},
+		{"url", activityInput{App: "Google Chrome", Title: "PROJ-1", URL: "https://acme.atlassian.net/browse/PROJ-1"}, "チケット管理", "rules:4"},
+		// 複数の条件はすべてに一致したときだけ。番号はファイル内の位置のまま
+		{"all conditions", activityInput{App: "Visual Studio Code", Title: "README.md"}, "ドキュメント", "rules:7"},
+		{"partial conditions", activityInput{App: "Visual Studio Code", Title: "notes.txt"}, "", ""},
+		{"skipped rule", activityInput{App: "zoom.us", Title: "Meeting"}, "", ""},
+	}
+	for _, tt := range tests {
+		cat, by := rs.match(tt.in)
+		if cat != tt.cat || by != tt.by {
+			t.Errorf("%s: match = %q, %q; want %q, %q", tt.name, cat, by, tt.cat, tt.by)
+		}
+	}
+}
+
+// ルールファイルは組み込みの分類より先。default を書くと組み込みの分類はしない
+func TestUserRulesBeforeBuiltin(t *testing.T) {
+	defer func(rs *ruleSet) { userRules = rs }(userRules)
+
+	rs, err := readRulesFile(writeTestFile(t, "rules.json", `{"rules":[{"app":"slack","category":"チャット"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	userRules = rs
+	if got := classifyMatch(activityInput{App: "Slack", Title: "#dev"}); got.Category != "チャット" || got.MatchedBy != "rules:1" {
+		t.Errorf("slack = %+v, want the file rule", got)
+	}
+	if got := classifyMatch(activityInput{App: "Visual Studio Code", Title: "main.go"}); got.Category != "プログラムの制作" {
+		t.Errorf("builtin fallback = %+v", got)
+	}
+
+	rs.Default = "未分類"
+	if got := classifyMatch(activityInput{App: "Visual Studio Code", Title: "main.go"}); got.Category != "未分類" || got.MatchedBy != "rules:default" {
+		t.Errorf("with default = %+v, want rules:default", got)
+	}
+}
+
+func TestLoadRulesMissingOrBroken(t *testing.T) {
+	if rs := loadRules(filepath.Join(t.TempDir(), "rules.yaml")); len(rs.Rules) != 0 || rs.describe() != "builtin" {
+		t.Errorf("missing file = %+v", rs)
+	}
+	if rs := loadRules(writeTestFile(t, "rules.yaml", "rules: [")); len(rs.Rules) != 0 || rs.describe() != "builtin" {
+		t.Errorf("broken file = %+v", rs)
+	}
+}
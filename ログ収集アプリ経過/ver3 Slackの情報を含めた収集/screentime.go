@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+/********** import-screentime サブコマンド **********/
+// macOS のスクリーンタイムが記録しているアプリの使用履歴（knowledgeC.db の /app/usage）を
+// セッション形式に変換する。ロガーを入れる前の期間の履歴をつなげるためのもの。
+//   activitylog import-screentime [-db path] [-since 2025-01-01] [-until 2025-08-25] [-o out.json]
+// できること・できないこと:
+//   - 読み取りは macOS 標準の sqlite3 コマンド（-readonly -json）で行う。DBは変更しない。
+//   - knowledgeC.db は SIP で保護されていて、実行するターミナルに「フルディスクアクセス」を
+//     与えないと開けない（システム設定 → プライバシーとセキュリティ）。開けなければその旨を表示して終わる。
+//   - 取れるのはアプリ（bundle ID）と前面にあった時間だけで、ウィンドウタイトルは残っていない。
+//     分類は bundle ID とアプリ名だけで行うので、ブラウザ内の作業は「Webブラウジング」などにまとまる。
+//   - macOS 14 以降はスクリーンタイムの記録先が Biome（非公開の形式）に移り、knowledgeC.db には
+//     新しい履歴が残っていないことがある。その場合は残っている古い期間だけを取り込む。
+// -until の既定は logDir にある最も古いセッションの開始時刻（ロガー自身の記録と重ならないように）。
+// 出力には meta.source = "screentime" を付ける。-o を省略すると logDir に
+// activity_<最初のセッションの開始時刻>.json として書くので、report などからそのまま読める。
+const (
+	screenTimeSource = "screentime"
+	coreDataEpoch    = 978307200 // 2001-01-01T00:00:00Z（Core Data の時刻の起点）の Unix 時刻
+)
+
+type screenTimeRow struct {
+	Bundle string  `json:"bundle"`
+	Start  float64 `json:"start"` // Unix 秒
+	End    float64 `json:"end"`
+}
+
+func defaultKnowledgeDB() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, "Library", "Application Support", "Knowledge", "knowledgeC.db")
+}
+
+func runImportScreenTime(args []string) int {
+	fs := flag.NewFlagSet("import-screentime", flag.ExitOnError)
+	db := fs.String("db", defaultKnowledgeDB(), "path to knowledgeC.db")
+	sinceStr := fs.String("since", "", "import usage from this date (YYYY-MM-DD, local); default: everything available")
+	untilStr := fs.String("until", "", "import usage before this date (YYYY-MM-DD, local); default: the first session already in the log directory")
+	out := fs.String("o", "", "output path (default: activity_<first start>.json in the log directory)")
+	fs.Parse(args)
+
+	var since, until time.Time
+	var err error
+	if *sinceStr != "" {
+		if since, err = time.ParseInLocation("2006-01-02", *sinceStr, time.Local); err != nil {
+			fmt.Fprintf(os.Stderr, "import-screentime: invalid -since: %v\n", err)
+			return 2
+		}
+	}
+	if *untilStr != "" {
+		if until, err = time.ParseInLocation("2006-01-02", *untilStr, time.Local); err != nil {
+			fmt.Fprintf(os.Stderr, "import-screentime: invalid -until: %v\n", err)
+			return 2
+		}
+	} else if first, ok := firstLoggedSession(logDir); ok {
+		until = first
+		fmt.Printf("importing usage before the first logged session (%s)\n", first.Format(time.RFC3339))
+	}
+
+	rows, err := queryScreenTime(*db, since, until)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "import-screentime: %v\n", err)
+		return 1
+	}
+	if len(rows) == 0 {
+		fmt.Fprintln(os.Stderr, "import-screentime: no app usage found in that period (on macOS 14 and later Screen Time may no longer write to knowledgeC.db)")
+		return 1
+	}
+	ss := screenTimeSessions(rows)
+	if *out == "" {
+		first, _ := time.Parse(time.RFC3339, ss[0].Start)
+		*out = filepath.Join(logDir, "activity_"+first.Format("20060102_150405")+".json")
+		if err := os.MkdirAll(logDir, 0755); err != nil {
+			fmt.Fprintf(os.Stderr, "import-screentime: %v\n", err)
+			return 1
+		}
+	}
+	if _, err := os.Stat(*out); err == nil {
+		fmt.Fprintf(os.Stderr, "import-screentime: %s already exists\n", *out)
+		return 1
+	}
+	if err := writeJSONFile(*out, ss); err != nil {
+		fmt.Fprintf(os.Stderr, "import-screentime: %v\n", err)
+		return 1
+	}
+	fmt.Printf("imported %d sessions (%s – %s) to %s\n", len(ss), ss[0].Start, ss[len(ss)-1].End, *out)
+	return 0
+}
+
+// sqlite3 で /app/usage を読む（since/until はゼロ値なら制限なし）
+func queryScreenTime(db string, since, until time.Time) ([]screenTimeRow, error) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		return nil, errors.New("sqlite3 not found on PATH (it ships with macOS)")
+	}
+	if _, err := os.Stat(db); err != nil {
+		if os.IsPermission(err) {
+			return nil, fmt.Errorf("cannot read %s: grant Full Disk Access to your terminal (System Settings → Privacy & Security)", db)
+		}
+		return nil, err
+	}
+	where := []string{"ZSTREAMNAME = '/app/usage'", "ZVALUESTRING IS NOT NULL", "ZENDDATE > ZSTARTDATE"}
+	if !since.IsZero() {
+		where = append(where, fmt.Sprintf("ZSTARTDATE >= %d", since.Unix()-coreDataEpoch))
+	}
+	if !until.IsZero() {
+		where = append(where, fmt.Sprintf("ZSTARTDATE < %d", until.Unix()-coreDataEpoch))
+	}
+	query := fmt.Sprintf(`SELECT ZVALUESTRING AS bundle, ZSTARTDATE + %d AS start, ZENDDATE + %d AS "end"
+FROM ZOBJECT WHERE %s ORDER BY ZSTARTDATE`, coreDataEpoch, coreDataEpoch, strings.Join(where, " AND "))
+
+	cmd := exec.Command("sqlite3", "-readonly", "-json", db, query)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	b, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if strings.Contains(msg, "authorization denied") || strings.Contains(msg, "unable to open") {
+			return nil, fmt.Errorf("cannot open %s (%s): grant Full Disk Access to your terminal (System Settings → Privacy & Security)", db, msg)
+		}
+		return nil, fmt.Errorf("sqlite3: %s", msg)
+	}
+	if len(strings.TrimSpace(string(b))) == 0 {
+		return nil, nil // 0行のときは何も出力されない
+	}
+	var rows []screenTimeRow
+	if err := json.Unmarshal(b, &rows); err != nil {
+		return nil, fmt.Errorf("unexpected sqlite3 output: %v", err)
+	}
+	return rows, nil
+}
+
+func screenTimeSessions(rows []screenTimeRow) []session {
+	names := map[string]string{}
+	ss := make([]session, 0, len(rows))
+	for _, r := range rows {
+		name, ok := names[r.Bundle]
+		if !ok {
+			name = appNameForBundle(r.Bundle)
+			names[r.Bundle] = name
+		}
+		app := normalizeAppName(name)
+		rec := &record{App: app, RawApp: name, BundleID: r.Bundle}
+		cls := classifyMatch(activityInput{App: app, BundleID: r.Bundle})
+		rec.Activity, rec.MatchedBy = cls.Category, cls.MatchedBy
+		start := time.Unix(0, int64(r.Start*float64(time.Second))).Local()
+		end := time.Unix(0, int64(r.End*float64(time.Second))).Local()
+		s := sessionFrom(rec, start, end)
+		s.setMeta("source", screenTimeSource)
+		ss = append(ss, s)
+	}
+	return ss
+}
+
+// bundle ID からアプリ名を引く（Spotlight で見つからなければ bundle ID の最後の要素）
+func appNameForBundle(id string) string {
+	out, err := runCmd("mdfind", fmt.Sprintf("kMDItemCFBundleIdentifier == '%s'", strings.ReplaceAll(id, "'", "")))
+	if err == nil {
+		for _, p := range strings.Split(out, "\n") {
+			if p = strings.TrimSpace(p); strings.HasSuffix(p, ".app") {
+				return strings.TrimSuffix(filepath.Base(p), ".app")
+			}
+		}
+	}
+	if i := strings.LastIndex(id, "."); i >= 0 {
+		return id[i+1:]
+	}
+	return id
+}
+
+// logDir にあるロガー自身の記録のうち、最も古いセッションの開始時刻
+func firstLoggedSession(dir string) (time.Time, bool) {
+	paths, _ := sessionFiles(dir)
+	for _, p := range paths {
+		ss, err := readSessionsFile(p)
+		if err != nil {
+			continue
+		}
+		for _, s := range ss {
+			if s.Meta["source"] == screenTimeSource {
+				continue
+			}
+			if t, err := time.Parse(time.RFC3339, s.Start); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
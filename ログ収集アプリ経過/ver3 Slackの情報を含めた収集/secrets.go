@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+/********** Slackトークンの読み込み（ファイル / キーチェーン / 環境変数） **********/
+// 環境変数でトークンを渡すと、環境によってはプロセス一覧などから見えてしまう。
+// 次の優先順で探し、最初に見つかった値を使う:
+//   1. SLACK_TOKEN_FILE で指定したファイル（"SLACK_BOT_TOKEN=xoxb-..." 形式の行。# 始まりはコメント）
+//   2. macOS キーチェーン（security find-generic-password -s <サービス名> -a <キー名> -w）
+//      サービス名は SLACK_KEYCHAIN_SERVICE（既定 "Shirusia"）、アカウント名はキー名そのもの
+//   3. 環境変数（従来どおり）
+func lookupSlackSecret(key string) string {
+	v, _ := lookupSlackSecretSource(key)
+	return v
+}
+
+// 値と、どこから読んだか（"file" / "keychain" / "env"、見つからなければ ""）
+func lookupSlackSecretSource(key string) (string, string) {
+	if v := secretFromFile(os.Getenv("SLACK_TOKEN_FILE"), key); v != "" {
+		return v, "file"
+	}
+	if v := secretFromKeychain(key); v != "" {
+		return v, "keychain"
+	}
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		return v, "env"
+	}
+	return "", ""
+}
+
+func secretFromFile(path, key string) string {
+	if strings.TrimSpace(path) == "" {
+		return ""
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warn: SLACK_TOKEN_FILE: %v\n", err)
+		return ""
+	}
+	defer f.Close()
+	if st, err := f.Stat(); err == nil && st.Mode().Perm()&0o077 != 0 {
+		fmt.Fprintf(os.Stderr, "warn: SLACK_TOKEN_FILE %s is readable by other users (chmod 600 recommended)\n", path)
+	}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if ok && strings.TrimSpace(k) == key {
+			return strings.Trim(strings.TrimSpace(v), `"'`)
+		}
+	}
+	return ""
+}
+
+func secretFromKeychain(key string) string {
+	if _, err := exec.LookPath("security"); err != nil {
+		return ""
+	}
+	service := strings.TrimSpace(os.Getenv("SLACK_KEYCHAIN_SERVICE"))
+	if service == "" {
+		service = "Shirusia"
+	}
+	cmd := exec.Command("security", "find-generic-password", "-s", service, "-a", key, "-w")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return "" // 未登録は正常系（次の候補へ）
+	}
+	return strings.TrimSpace(out.String())
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+/********** ロガー自身の画面 **********/
+// 統計を確認している時間が統計に混ざらないよう、ロガー自身の画面が前面のときは
+// 除外するか、専用のカテゴリ（selfActivity）として記録する。
+// 判定はアプリ名ではなく PID とバンドルIDで行うので、アプリ名を変えても効く:
+//   - 前面プロセスの PID がこのプロセス自身（将来の TUI/GUI 組み込み時）
+//   - 前面アプリのバンドルIDが、このプロセスの .app の CFBundleIdentifier か SHIRUSIA_SELF_BUNDLE_ID
+//   - -http の Web UI（埋め込みページのタイトル）をブラウザで開いている
+//
+// SHIRUSIA_SELF_WINDOWS（既定 "record"）
+//   record  : アプリ "Shirusia"、カテゴリ「記録の確認」として記録する
+//   exclude : 進行中のセッションを閉じ、自身の画面が前面の間は何も記録しない
+const (
+	selfModeRecord  = "record"
+	selfModeExclude = "exclude"
+
+	selfAppLabel = "Shirusia"
+	selfActivity = "記録の確認"
+)
+
+// web/index.html の <title> の先頭
+const selfWebUITitlePrefix = "Shirusia — "
+
+var (
+	selfWindowMode = loadSelfWindowMode()
+	selfBundleIDs  = loadSelfBundleIDs()
+)
+
+func loadSelfWindowMode() string {
+	switch m := strings.ToLower(strings.TrimSpace(os.Getenv("SHIRUSIA_SELF_WINDOWS"))); m {
+	case "":
+		return selfModeRecord
+	case selfModeRecord, selfModeExclude:
+		return m
+	default:
+		fmt.Fprintf(os.Stderr, "warn: SHIRUSIA_SELF_WINDOWS: unknown mode %q (record, exclude)\n", m)
+		return selfModeRecord
+	}
+}
+
+func loadSelfBundleIDs() []string {
+	ids := splitList(os.Getenv("SHIRUSIA_SELF_BUNDLE_ID"))
+	if id := ownBundleID(); id != "" {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+var bundleIDPlistRe = regexp.MustCompile(`<key>CFBundleIdentifier</key>\s*<string>([^<]+)</string>`)
+
+// 実行ファイルが Foo.app/Contents/MacOS/ の中にあれば、その Info.plist のバンドルID
+func ownBundleID() string {
+	exe, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	contents := filepath.Dir(filepath.Dir(exe))
+	if filepath.Base(filepath.Dir(exe)) != "MacOS" || filepath.Base(contents) != "Contents" {
+		return ""
+	}
+	b, err := os.ReadFile(filepath.Join(contents, "Info.plist"))
+	if err != nil {
+		return ""
+	}
+	if m := bundleIDPlistRe.FindSubmatch(b); m != nil {
+		return strings.TrimSpace(string(m[1]))
+	}
+	return ""
+}
+
+func isSelfWindow(w frontWindow, bundleID string) bool {
+	if w.PID != 0 && w.PID == os.Getpid() {
+		return true
+	}
+	if bundleID != "" {
+		for _, id := range selfBundleIDs {
+			if strings.EqualFold(bundleID, id) {
+				return true
+			}
+		}
+	}
+	return isBrowserApp(strings.ToLower(w.App)) && strings.HasPrefix(w.Title, selfWebUITitlePrefix)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+/********** ロガー自身の負荷計測（-self-profile） **********/
+// 一定間隔で、ロガー本体のCPU時間・メモリと osascript の起動回数を標準エラーに出す。
+// 子プロセス（osascript 等）のCPU時間は別に出すので、どちらが重いのかを切り分けられる。
+var osaSpawns atomic.Int64
+
+func runSelfProfile(every time.Duration, stop <-chan struct{}) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	var prev cpuTimes
+	var prevSpawns int64
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+			cur := readCPUTimes()
+			spawns := osaSpawns.Load()
+			var ms runtime.MemStats
+			runtime.ReadMemStats(&ms)
+			fmt.Fprintf(os.Stderr, "[self] cpu=%s (+%s) children=%s (+%s) heap=%.1fMiB sys=%.1fMiB goroutines=%d osascript=%d (+%d)\n",
+				cur.self.Round(time.Millisecond), (cur.self - prev.self).Round(time.Millisecond),
+				cur.children.Round(time.Millisecond), (cur.children - prev.children).Round(time.Millisecond),
+				float64(ms.HeapAlloc)/(1<<20), float64(ms.Sys)/(1<<20), runtime.NumGoroutine(),
+				spawns, spawns-prevSpawns)
+			prev, prevSpawns = cur, spawns
+		}
+	}
+}
+
+// 累積CPU時間（ユーザー+システム）
+type cpuTimes struct {
+	self     time.Duration
+	children time.Duration // 終了済みの子プロセス
+}
@@ -0,0 +1,8 @@
+//go:build windows || plan9
+
+package main
+
+// getrusage がない環境ではCPU時間は出さない（メモリと起動回数のみ）
+func readCPUTimes() cpuTimes {
+	return cpuTimes{}
+}
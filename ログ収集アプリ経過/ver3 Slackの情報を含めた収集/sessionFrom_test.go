@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata" // zoneinfo のない環境でも America/New_York を読めるように
+)
+
+// 夏時間の切り替えをまたいでも durationSec は実際の経過秒数で、start/end はそれぞれの時点のオフセットで書く
+func TestSessionFromDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		start, end time.Time
+		wantStart  string
+		wantEnd    string
+		wantSec    int64
+	}{
+		{
+			// 2025-03-09 02:00 EST → 03:00 EDT（壁時計では2時間、実際は1時間）
+			name:      "spring forward",
+			start:     time.Date(2025, 3, 9, 1, 30, 0, 0, ny),
+			end:       time.Date(2025, 3, 9, 3, 30, 0, 0, ny),
+			wantStart: "2025-03-09T01:30:00-05:00",
+			wantEnd:   "2025-03-09T03:30:00-04:00",
+			wantSec:   3600,
+		},
+		{
+			// 2025-11-02 02:00 EDT → 01:00 EST（壁時計では2時間、実際は3時間）
+			name:      "fall back",
+			start:     time.Date(2025, 11, 2, 0, 30, 0, 0, ny),
+			end:       time.Date(2025, 11, 2, 0, 30, 0, 0, ny).Add(3 * time.Hour),
+			wantStart: "2025-11-02T00:30:00-04:00",
+			wantEnd:   "2025-11-02T02:30:00-05:00",
+			wantSec:   10800,
+		},
+		{
+			// 01:30 が2回ある。1回目（EDT）から2回目（EST）までの1時間
+			name:      "repeated hour",
+			start:     time.Date(2025, 11, 2, 5, 30, 0, 0, time.UTC).In(ny),
+			end:       time.Date(2025, 11, 2, 6, 30, 0, 0, time.UTC).In(ny),
+			wantStart: "2025-11-02T01:30:00-04:00",
+			wantEnd:   "2025-11-02T01:30:00-05:00",
+			wantSec:   3600,
+		},
+	}
+	for _, tt := range tests {
+		s := sessionFrom(&record{App: "Visual Studio Code", Activity: "プログラムの制作"}, tt.start, tt.end)
+		if s.Start != tt.wantStart || s.End != tt.wantEnd {
+			t.Errorf("%s: start/end = %s / %s, want %s / %s", tt.name, s.Start, s.End, tt.wantStart, tt.wantEnd)
+		}
+		if s.DurationSec != tt.wantSec {
+			t.Errorf("%s: durationSec = %d, want %d", tt.name, s.DurationSec, tt.wantSec)
+		}
+		// 書いた文字列から読み直しても同じ長さになる
+		st, _ := time.Parse(time.RFC3339, s.Start)
+		en, _ := time.Parse(time.RFC3339, s.End)
+		if got := int64(en.Sub(st) / time.Second); got != tt.wantSec {
+			t.Errorf("%s: parsed start/end are %d seconds apart, want %d", tt.name, got, tt.wantSec)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+/********** 画面収録・画面共有の検出（-detect-sharing、任意） **********/
+// デモやサポートで画面を見せている時間を分けて見られるよう、収録/共有中のセッションに sharing を付ける。
+// macOS には「今キャプチャされているか」を外から聞く公開APIがないため、キャプチャ中にだけ動く
+// プロセスがあるかで判定する（ベストエフォート）:
+//   screencaptureui    : ⌘⇧5 / スクリーンショット.app の画面収録
+//   ScreensharingAgent : 画面共有.app / リモートマネージメントで画面を見せている
+//   CptHost            : Zoom の画面共有
+// SHIRUSIA_SHARING_PROCESSES="obs,CptHost" で一覧を置き換えられる（プロセス名の完全一致、大文字小文字は無視）。
+// 判定は pgrep を起動するため、callDetector と同じく sharingCheckTTL の間キャッシュする。
+const sharingCheckTTL = 10 * time.Second
+
+var defaultSharingProcesses = []string{"screencaptureui", "ScreensharingAgent", "CptHost"}
+
+type sharingDetector struct {
+	procs     []string
+	checkedAt time.Time
+	sharing   bool
+}
+
+func newSharingDetector() *sharingDetector {
+	procs := defaultSharingProcesses
+	if list := splitList(os.Getenv("SHIRUSIA_SHARING_PROCESSES")); len(list) > 0 {
+		procs = list
+	}
+	return &sharingDetector{procs: procs}
+}
+
+// 画面収録か画面共有のプロセスが動いていれば true
+func (d *sharingDetector) Sharing(now time.Time) bool {
+	if !d.checkedAt.IsZero() && now.Sub(d.checkedAt) < sharingCheckTTL {
+		return d.sharing
+	}
+	d.sharing = false
+	for _, p := range d.procs {
+		// pgrep は一致がなければ終了コード1を返す
+		out, err := runCmd("pgrep", "-i", "-x", p)
+		if err == nil && strings.TrimSpace(out) != "" {
+			d.sharing = true
+			break
+		}
+	}
+	d.checkedAt = now
+	return d.sharing
+}
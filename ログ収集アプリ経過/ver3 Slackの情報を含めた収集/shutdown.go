@@ -0,0 +1,38 @@
+package main
+
+import "time"
+
+/********** 進行中のセッションと終了処理 **********/
+// メインループは1つの select なので、ティックと終了シグナルが同時に処理されることはない
+// （同時に届いても、先に選ばれた方が終わってからもう一方を処理する）。
+// 二重に書き出す・閉じるおそれがあるのは、書き出しや終了処理の途中でパニックして後始末が走るときで、
+//   - 進行中のセッションは書き出す前に外す（書き出し中にパニックしても、後始末が同じセッションをもう一度書かない）
+//   - 出力先を閉じるのは1回だけ（閉じ括弧を2回書く、チャネルを2回閉じる等をしない）
+type openSession struct {
+	last  *record   // 進行中のセッション（なければ nil）
+	start time.Time // その開始時刻
+}
+
+// last を end で閉じて write で書き出し、next を at から始める（next が nil なら何も始めない）
+func (o *openSession) switchAt(next *record, end, at time.Time, write func(prev *record, start, end time.Time)) {
+	prev, start := o.last, o.start
+	o.last = nil
+	if prev != nil {
+		write(prev, start, end)
+	}
+	o.last, o.start = next, at
+}
+
+// 出力先をすべて閉じる処理を1回だけ行う（通常終了時とパニック時）
+type sinkCloser struct {
+	closed   bool
+	closeAll func()
+}
+
+func (c *sinkCloser) Close() {
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.closeAll()
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+)
+
+// メインループと同じ手順（進行中のセッションを確定してから出力先を閉じる）でセッションファイルに書く
+type shutdownHarness struct {
+	open   openSession
+	sinks  *sinkCloser
+	jw     sessionFileSink
+	writes int
+	closes int
+}
+
+func newShutdownHarness(t *testing.T) *shutdownHarness {
+	t.Helper()
+	jw, err := newSessionFile(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &shutdownHarness{jw: jw}
+	h.sinks = &sinkCloser{closeAll: func() {
+		h.closes++
+		if err := h.jw.Close(); err != nil {
+			t.Errorf("close: %v", err)
+		}
+	}}
+	return h
+}
+
+func (h *shutdownHarness) write(prev *record, start, end time.Time) {
+	h.writes++
+	s := sessionFrom(prev, start, end)
+	if err := h.jw.AppendSession(&s); err != nil {
+		panic(err)
+	}
+}
+
+// 終了シグナル・パニックの後始末と同じ: switchTo(nil, ...) のあと出力先を閉じる
+func (h *shutdownHarness) shutdown(at time.Time) {
+	h.open.switchAt(nil, at, at, h.write)
+	h.sinks.Close()
+}
+
+func TestShutdownWritesSessionOnce(t *testing.T) {
+	h := newShutdownHarness(t)
+	t0 := testTime(10, 0)
+	code := &record{App: "Visual Studio Code", Title: "main.go", Activity: "プログラムの制作"}
+	h.open.switchAt(code, t0, t0, h.write)
+
+	// 保留中のティック（同じアプリなので切り替えない）のあとにシグナル、さらにパニックの後始末がもう一度走る
+	if h.open.last != code {
+		t.Fatal("tick replaced the open session")
+	}
+	h.shutdown(t0.Add(time.Minute))
+	h.shutdown(t0.Add(2 * time.Minute))
+
+	if h.writes != 1 || h.closes != 1 {
+		t.Errorf("writes = %d, closes = %d; want 1 and 1", h.writes, h.closes)
+	}
+	raw, err := os.ReadFile(h.jw.file().path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ss []session
+	if err := json.Unmarshal(raw, &ss); err != nil {
+		t.Fatalf("log file is not a closed JSON array: %v\n%s", err, raw)
+	}
+	if len(ss) != 1 || ss[0].DurationSec != 60 {
+		t.Errorf("sessions = %+v, want one 60s session", ss)
+	}
+}
+
+// 書き出しの途中でパニックしても、後始末が同じセッションをもう一度書くことはない
+func TestShutdownAfterPanicInWrite(t *testing.T) {
+	h := newShutdownHarness(t)
+	t0 := testTime(10, 0)
+	h.open.switchAt(&record{App: "Slack", Activity: "コミュニケーション"}, t0, t0, h.write)
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("write did not panic")
+			}
+			h.shutdown(t0.Add(2 * time.Minute)) // main の defer と同じ後始末
+		}()
+		h.open.switchAt(nil, t0.Add(time.Minute), t0.Add(time.Minute), func(*record, time.Time, time.Time) {
+			h.writes++
+			panic("write failed")
+		})
+	}()
+
+	if h.writes != 1 || h.closes != 1 {
+		t.Errorf("writes = %d, closes = %d; want 1 and 1", h.writes, h.closes)
+	}
+	ss, err := readSessionsFile(h.jw.file().path)
+	if err != nil || len(ss) != 0 {
+		t.Errorf("sessions = %+v, %v; want none", ss, err)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+/********** Slack の会話の種類 **********/
+// メッセージの meta.conversationType に入れる。共有用のエクスポートから DM を除く、などに使う。
+//   channel : 公開チャンネル
+//   private : プライベートチャンネル
+//   dm      : 1対1の DM
+//   mpdm    : 複数人の DM（mpim）
+// イベントの channel_type を優先し、ない場合は conversations.info（要 *:read スコープ）、
+// それも失敗したらチャンネルIDの先頭文字（C/G/D）で判定する。
+const (
+	convChannel = "channel"
+	convPrivate = "private"
+	convDM      = "dm"
+	convMPDM    = "mpdm"
+)
+
+var convTypeCache sync.Map // channelID → 種類（conversations.info の結果）
+
+func conversationType(api *slack.Client, channelType, channelID string) string {
+	switch channelType {
+	case "channel":
+		return convChannel
+	case "group":
+		return convPrivate
+	case "im":
+		return convDM
+	case "mpim":
+		return convMPDM
+	}
+	if v, ok := convTypeCache.Load(channelID); ok {
+		return v.(string)
+	}
+	if api != nil && channelID != "" {
+		ch, err := api.GetConversationInfo(&slack.GetConversationInfoInput{ChannelID: channelID})
+		if err == nil {
+			t := conversationTypeOf(ch)
+			convTypeCache.Store(channelID, t)
+			return t
+		}
+		fmt.Fprintf(os.Stderr, "[slack] conversations.info %s: %v (falling back to ID prefix)\n", channelID, err)
+	}
+	return conversationTypeFromID(channelID)
+}
+
+func conversationTypeOf(ch *slack.Channel) string {
+	switch {
+	case ch.IsMpIM:
+		return convMPDM
+	case ch.IsIM:
+		return convDM
+	case ch.IsPrivate:
+		return convPrivate
+	}
+	return convChannel
+}
+
+// ID の先頭文字だけで判定する。古いワークスペースでは複数人DMも G で始まるため、
+// G はプライベートチャンネルとして扱う（区別には conversations.info が必要）
+func conversationTypeFromID(id string) string {
+	switch {
+	case strings.HasPrefix(id, "C"):
+		return convChannel
+	case strings.HasPrefix(id, "G"):
+		return convPrivate
+	case strings.HasPrefix(id, "D"):
+		return convDM
+	}
+	return ""
+}
+
+/********** Slack のチャンネル名 **********/
+// メッセージの title をチャンネルID（C0123ABC / D0456）ではなく読める名前にする。
+//   公開・プライベートチャンネル: "#general"
+//   DM: 相手の表示名（display name → real name → ユーザー名の順。users.info、要 users:read）
+//   複数人DM: conversations.info の name（"mpdm-a--b-1" の形）
+// 元のIDは従来どおり meta.channelId に残す。
+// 最初に見たチャンネルだけ conversations.info を呼び、結果はプロセスが終わるまで覚えておく。
+// レート制限（429）のときは Retry-After の間は問い合わせずにIDを返し、
+// 権限不足などで引けなかったチャンネルは slackNameRetry たってから問い合わせ直す。
+const slackNameRetry = 10 * time.Minute
+
+type channelNames struct {
+	mu      sync.Mutex
+	names   map[string]string    // channelID → 表示名
+	failed  map[string]time.Time // channelID → 引けなかった時刻
+	blocked time.Time            // レート制限中はこの時刻まで問い合わせない
+}
+
+var slackChannelNames = &channelNames{names: map[string]string{}, failed: map[string]time.Time{}}
+
+// 表示名を返す。引けなければ channelID をそのまま返す
+func (c *channelNames) Resolve(api *slack.Client, channelID string) string {
+	if api == nil || channelID == "" {
+		return channelID
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if name, ok := c.names[channelID]; ok {
+		return name
+	}
+	now := time.Now()
+	if now.Before(c.blocked) {
+		return channelID
+	}
+	if at, ok := c.failed[channelID]; ok && now.Sub(at) < slackNameRetry {
+		return channelID
+	}
+	name, err := channelDisplayName(api, channelID)
+	if err != nil {
+		var rl *slack.RateLimitedError
+		if errors.As(err, &rl) {
+			c.blocked = now.Add(rl.RetryAfter)
+		} else {
+			c.failed[channelID] = now
+		}
+		fmt.Fprintf(os.Stderr, "[slack] channel name %s: %v (keeping the ID)\n", channelID, err)
+		return channelID
+	}
+	delete(c.failed, channelID)
+	c.names[channelID] = name
+	return name
+}
+
+func channelDisplayName(api *slack.Client, channelID string) (string, error) {
+	ch, err := api.GetConversationInfo(&slack.GetConversationInfoInput{ChannelID: channelID})
+	if err != nil {
+		return "", err
+	}
+	convTypeCache.Store(channelID, conversationTypeOf(ch)) // conversationType で同じ問い合わせをしない
+	switch {
+	case ch.IsIM:
+		u, err := api.GetUserInfo(ch.User)
+		if err != nil {
+			return "", err
+		}
+		return slackUserName(u), nil
+	case ch.IsMpIM:
+		return ch.Name, nil
+	}
+	return "#" + ch.Name, nil
+}
+
+func slackUserName(u *slack.User) string {
+	for _, s := range []string{u.Profile.DisplayName, u.RealName, u.Name} {
+		if s = strings.TrimSpace(s); s != "" {
+			return s
+		}
+	}
+	return u.ID
+}
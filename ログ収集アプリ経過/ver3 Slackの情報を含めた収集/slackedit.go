@@ -0,0 +1,65 @@
+package main
+
+import (
+	"time"
+
+	"github.com/slack-go/slack/slackevents"
+)
+
+/********** Slack の編集・削除 **********/
+// 送ったメッセージだけでなく、あとから直した・取り消したことも記録する。
+//   direction "sent"    新規（サブタイプなし）
+//   direction "edited"  message_changed。text は編集後の本文、meta.editedTs は元のメッセージの ts
+//   direction "deleted" message_deleted。text は空、meta.deletedTs は消したメッセージの ts
+// editedTs / deletedTs を新規メッセージの meta.ts と突き合わせれば元のメッセージが分かる。
+// リンクの展開（unfurl）でも message_changed が届くが、本文が変わっていないものは編集として扱わない。
+// bot_message などそれ以外のサブタイプは従来どおり捨てる。
+const (
+	slackSent    = "sent"
+	slackEdited  = "edited"
+	slackDeleted = "deleted"
+)
+
+// イベントを保存する形にする。user はそのメッセージを書いた人（自分のものだけ残すための判定に使う）。
+// 記録しないイベントなら ok=false
+func messageFromEvent(ev *slackevents.MessageEvent) (m messageEntry, user string, ok bool) {
+	m = messageEntry{
+		Timestamp: time.Now().Format(time.RFC3339),
+		Source:    "Slack",
+		Meta: map[string]string{
+			"channelId": ev.Channel,
+			"ts":        ev.TimeStamp,
+		},
+	}
+	switch ev.SubType {
+	case "":
+		m.Direction = slackSent
+		m.Text = ev.Text
+		user = ev.User
+		m.Meta["threadTs"] = ev.ThreadTimeStamp
+	case "message_changed":
+		if ev.Message == nil {
+			return m, "", false
+		}
+		if ev.PreviousMessage != nil && ev.PreviousMessage.Text == ev.Message.Text {
+			return m, "", false // 本文はそのまま（リンクの展開など）
+		}
+		m.Direction = slackEdited
+		m.Text = ev.Message.Text
+		user = ev.Message.User
+		m.Meta["threadTs"] = ev.Message.ThreadTimestamp
+		m.Meta["editedTs"] = ev.Message.Timestamp
+	case "message_deleted":
+		if ev.PreviousMessage == nil {
+			return m, "", false // 誰のメッセージか分からない
+		}
+		m.Direction = slackDeleted
+		user = ev.PreviousMessage.User
+		m.Meta["threadTs"] = ev.PreviousMessage.ThreadTimestamp
+		m.Meta["deletedTs"] = ev.DeletedTimeStamp
+	default:
+		return m, "", false
+	}
+	m.Meta["userId"] = user
+	return m, user, true
+}
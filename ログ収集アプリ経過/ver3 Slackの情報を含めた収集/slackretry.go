@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"time"
+)
+
+/********** Slack の再接続 **********/
+// Socket Mode の接続が切れたり（EventTypeDisconnect）Run がエラーで返ったりすると、以前はそのまま
+// 取り込みが止まり、活動ログだけが記録され続けた。切れたら 1s, 2s, 4s... と最大30秒まで間隔を
+// 延ばしながら（±20% 揺らす）クライアントを作り直してつなぎ直し、そのたびに理由を標準エラーに出す。
+// 1分以上つながっていたあとで切れた場合は、間隔を 1s からやり直す。ctx が終われば（Ctrl+C など）すぐ止める。
+const (
+	slackRetryMin     = time.Second
+	slackRetryMax     = 30 * time.Second
+	slackRetryHealthy = time.Minute
+)
+
+// run は1回の接続を受け持ち、runCtx が終わるか接続が切れたら返る。
+// disconnected を呼ぶと runCtx を終わらせて、次の接続に移る
+func runSocketModeWithRetry(ctx context.Context, run func(runCtx context.Context, disconnected func()) error) {
+	backoff := slackRetryMin
+	for attempt := 1; ; attempt++ {
+		runCtx, cancel := context.WithCancel(ctx)
+		started := time.Now()
+		err := run(runCtx, cancel)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) >= slackRetryHealthy {
+			backoff, attempt = slackRetryMin, 1
+		}
+		wait := jitter(backoff)
+		reason := "disconnected"
+		if err != nil && err != context.Canceled {
+			reason = err.Error()
+		}
+		fmt.Fprintf(os.Stderr, "[slack] connection lost (%s); reconnecting in %s (attempt %d)\n", reason, wait.Round(100*time.Millisecond), attempt)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		backoff = min(backoff*2, slackRetryMax)
+	}
+}
+
+// d を ±20% の範囲でずらす（複数台が同時につなぎ直さないように）
+func jitter(d time.Duration) time.Duration {
+	return time.Duration(float64(d) * (0.8 + 0.4*rand.Float64()))
+}
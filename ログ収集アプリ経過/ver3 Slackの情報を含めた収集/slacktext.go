@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+/********** 長すぎるSlackメッセージの切り詰め **********/
+// ログやコードを貼り付けた巨大なメッセージでメッセージ保存先が膨らまないよう、
+// 本文を SLACK_MAX_TEXT 文字（ルーン数、既定 10000、0 で無制限）までに切り詰める。
+// 切り詰めたときは末尾に印を付け、元の文字数を meta.fullLength に入れる。
+const defaultSlackMaxText = 10000
+
+const truncatedMarker = "…[truncated]"
+
+var slackMaxText = loadSlackMaxText()
+
+func loadSlackMaxText() int {
+	raw := strings.TrimSpace(os.Getenv("SLACK_MAX_TEXT"))
+	if raw == "" {
+		return defaultSlackMaxText
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		fmt.Fprintf(os.Stderr, "warn: SLACK_MAX_TEXT: invalid value %q (using %d)\n", raw, defaultSlackMaxText)
+		return defaultSlackMaxText
+	}
+	return n
+}
+
+// 本文を max ルーンまでに切り詰める（文字の途中では切らない）。切り詰めたら元のルーン数を返す
+func truncateText(s string, max int) (string, int, bool) {
+	if max <= 0 {
+		return s, 0, false
+	}
+	n := utf8.RuneCountInString(s)
+	if n <= max {
+		return s, n, false
+	}
+	i, count := 0, 0
+	for i = range s {
+		if count == max {
+			break
+		}
+		count++
+	}
+	return s[:i] + truncatedMarker, n, true
+}
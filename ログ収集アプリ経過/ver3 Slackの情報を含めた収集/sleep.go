@@ -0,0 +1,48 @@
+package main
+
+import (
+	"regexp"
+	"time"
+)
+
+/********** 画面ロック・スリープ **********/
+// ロック中・スリープ中は入力がないだけなので、-idle の閾値を過ぎるまで前のセッションが伸びていた。
+// どちらも見つけた時点でアイドル期間にし、前のセッションの endReason を lock / sleep にする（-end-reason）。
+// アイドル期間は入力の再開（resume）で終わる。-idle 0 でも、スリープ（と -end-reason のときのロック）の間はアイドルとして分ける。
+//
+// ロック: ioreg -n Root -d1 の IOConsoleUsers に出る CGSSessionScreenIsLocked（macOS のみ。lock_darwin.go）。
+//   ロックを見つけたポーリングの時刻からアイドルにする。他の OS ではロックを判定しない。
+//   -idle か -end-reason のときだけ調べ、外部コマンドを毎ティック起動しないよう lockCheckTTL の間は結果を使い回す。
+// スリープ: ポーリングの間が、いちばん長いポーリング間隔の3倍＋30秒（/healthz と同じ基準）より空いたら、
+//   その間はスリープしていた（プロセスが止まっていた）とみなし、前回のポーリングの時刻からアイドルにする。
+//   壁時計で比べるので OS を問わない。
+var screenLockedRe = regexp.MustCompile(`"CGSSessionScreenIsLocked"\s*=\s*Yes`)
+
+const lockCheckTTL = 10 * time.Second
+
+type lockDetector struct {
+	probe     func() bool // screenLocked（テストでは差し替える）
+	checkedAt time.Time
+	locked    bool
+}
+
+// 画面がロック中なら true
+func (d *lockDetector) Locked(now time.Time) bool {
+	if !d.checkedAt.IsZero() && now.Sub(d.checkedAt) < lockCheckTTL {
+		return d.locked
+	}
+	d.locked = d.probe()
+	d.checkedAt = now
+	return d.locked
+}
+
+// ioreg の出力が画面ロック中を示しているか
+func screenLockedIn(ioregOut string) bool {
+	return screenLockedRe.MatchString(ioregOut)
+}
+
+// 前回のポーリング prev から now までに gap より長く止まっていたか。
+// 単調時計はスリープ中に進まない OS があるので、Round(0) で外して壁時計で比べる
+func sleptBetween(prev, now time.Time, gap time.Duration) bool {
+	return now.Round(0).Sub(prev.Round(0)) > gap
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScreenLockedIn(t *testing.T) {
+	locked := `+-o Root  <class IORegistryEntry, id 0x100000100, retain 30>
+    {
+      "IOConsoleUsers" = ({"kCGSSessionOnConsoleKey"=Yes,"CGSSessionScreenIsLocked"=Yes,"kCGSSessionUserNameKey"="miori"})
+    }`
+	unlocked := `+-o Root  <class IORegistryEntry, id 0x100000100, retain 30>
+    {
+      "IOConsoleUsers" = ({"kCGSSessionOnConsoleKey"=Yes,"kCGSSessionUserNameKey"="miori"})
+    }`
+	if !screenLockedIn(locked) {
+		t.Error("locked console not detected")
+	}
+	if screenLockedIn(unlocked) {
+		t.Error("unlocked console reported as locked")
+	}
+}
+
+func TestSleptBetween(t *testing.T) {
+	prev := time.Date(2025, 9, 1, 10, 0, 0, 0, time.Local)
+	gap := 45 * time.Second
+	if sleptBetween(prev, prev.Add(5*time.Second), gap) {
+		t.Error("a normal poll interval was taken as sleep")
+	}
+	if !sleptBetween(prev, prev.Add(40*time.Minute), gap) {
+		t.Error("a 40 minute gap was not taken as sleep")
+	}
+	// 単調時計の読みが止まっていても（スリープ中に進まない OS）、壁時計で判定する
+	now := time.Now()
+	if !sleptBetween(now, now.Round(0).Add(time.Hour), gap) {
+		t.Error("wall clock gap ignored")
+	}
+}
+
+func TestEndReasonLockAndSleep(t *testing.T) {
+	prev := &record{App: "Visual Studio Code"}
+	for note, want := range map[string]string{"lock": endLock, "sleep": endSleep} {
+		if got := endReasonFor(prev, idleRecord(time.Now()), note); got != want {
+			t.Errorf("endReasonFor(%s) = %q, want %q", note, got, want)
+		}
+	}
+}
+
+// ロックの判定は lockCheckTTL の間は前回の結果を返す（毎ティック ioreg を起動しない）
+func TestLockDetectorCaches(t *testing.T) {
+	calls, locked := 0, false
+	d := &lockDetector{probe: func() bool { calls++; return locked }}
+	at := time.Date(2025, 9, 1, 10, 0, 0, 0, time.Local)
+	for i := 0; i < 5; i++ {
+		if d.Locked(at.Add(time.Duration(i) * time.Second)) {
+			t.Fatal("reported locked before locking")
+		}
+	}
+	if calls != 1 {
+		t.Errorf("probe called %d times within the TTL, want 1", calls)
+	}
+	locked = true
+	if !d.Locked(at.Add(lockCheckTTL)) {
+		t.Error("lock not seen after the TTL")
+	}
+	if calls != 2 {
+		t.Errorf("probe called %d times, want 2", calls)
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+/********** 現在の操作スペース（-space、macOS） **********/
+// プロジェクトごとにスペース（仮想デスクトップ）を分けている人向けに、セッション開始時の
+// スペース番号（1始まり）を session.space に入れる。取れなければ何も入れない。
+//   1. yabai があれば `yabai -m query --spaces --space` の index（正確）
+//   2. なければ com.apple.spaces の設定（SpacesDisplayConfiguration）からメインディスプレイの
+//      現在のスペースの位置を求める。この設定は macOS が随時書き出すものなので、
+//      切り替え直後は古い値のことがある。plutil -convert json は <data> や <date> を含む設定で
+//      失敗するので、defaults export の XML をそのまま読む。読めなかったときは最初の1回だけ警告する
+// 毎ティック外部コマンドを起動するので既定はオフ。
+func currentSpace() int {
+	if n := spaceFromYabai(); n > 0 {
+		return n
+	}
+	return spaceFromDefaults()
+}
+
+func spaceFromYabai() int {
+	if _, err := exec.LookPath("yabai"); err != nil {
+		return 0
+	}
+	out, err := runCmd("yabai", "-m", "query", "--spaces", "--space")
+	if err != nil {
+		return 0
+	}
+	var sp struct {
+		Index int `json:"index"`
+	}
+	if json.Unmarshal([]byte(out), &sp) != nil {
+		return 0
+	}
+	return sp.Index
+}
+
+type spacesConfig struct {
+	Display struct {
+		Management struct {
+			Monitors []struct {
+				Current struct {
+					ID int `json:"ManagedSpaceID"`
+				} `json:"Current Space"`
+				Spaces []struct {
+					ID int `json:"ManagedSpaceID"`
+				} `json:"Spaces"`
+			} `json:"Monitors"`
+		} `json:"Management Data"`
+	} `json:"SpacesDisplayConfiguration"`
+}
+
+// com.apple.spaces が読めなかったことを警告済みか（毎ティック出さない）
+var spaceWarned bool
+
+func spaceFromDefaults() int {
+	out, err := runCmd("defaults", "export", "com.apple.spaces", "-")
+	if err == nil {
+		var n int
+		if n, err = parseSpacesConfig(out); err == nil {
+			return n
+		}
+	}
+	if !spaceWarned {
+		spaceWarned = true
+		fmt.Fprintf(os.Stderr, "warn: -space: cannot read com.apple.spaces: %v (install yabai for exact space numbers)\n", err)
+	}
+	return 0
+}
+
+// defaults export の XML plist から、最初に現在のスペースを持つディスプレイ（メインディスプレイ）での位置を求める
+func parseSpacesConfig(raw string) (int, error) {
+	v, err := decodePlist(strings.NewReader(raw))
+	if err != nil {
+		return 0, fmt.Errorf("plist: %w", err)
+	}
+	// 汎用の値を JSON 経由で spacesConfig に詰め替える
+	b, err := json.Marshal(v)
+	if err != nil {
+		return 0, err
+	}
+	var cfg spacesConfig
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return 0, err
+	}
+	for _, m := range cfg.Display.Management.Monitors {
+		if m.Current.ID == 0 {
+			continue
+		}
+		for i, sp := range m.Spaces {
+			if sp.ID == m.Current.ID {
+				return i + 1, nil
+			}
+		}
+	}
+	return 0, nil
+}
+
+// XML plist を map[string]any / []any / string / int64 / float64 / bool に読む。
+// <data>（base64）と <date> は文字列のまま返す
+func decodePlist(r io.Reader) (any, error) {
+	d := xml.NewDecoder(r)
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		if se, ok := tok.(xml.StartElement); ok && se.Name.Local != "plist" {
+			return decodePlistValue(d, se)
+		}
+	}
+}
+
+func decodePlistValue(d *xml.Decoder, se xml.StartElement) (any, error) {
+	switch se.Name.Local {
+	case "dict", "array":
+		m, a := map[string]any{}, []any{}
+		var key string
+		for {
+			tok, err := d.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch t := tok.(type) {
+			case xml.StartElement:
+				if t.Name.Local == "key" {
+					if err := d.DecodeElement(&key, &t); err != nil {
+						return nil, err
+					}
+					continue
+				}
+				v, err := decodePlistValue(d, t)
+				if err != nil {
+					return nil, err
+				}
+				if se.Name.Local == "dict" {
+					m[key] = v
+				} else {
+					a = append(a, v)
+				}
+			case xml.EndElement:
+				if se.Name.Local == "dict" {
+					return m, nil
+				}
+				return a, nil
+			}
+		}
+	case "true", "false":
+		return se.Name.Local == "true", d.Skip()
+	}
+	var text string
+	if err := d.DecodeElement(&text, &se); err != nil {
+		return nil, err
+	}
+	text = strings.TrimSpace(text)
+	switch se.Name.Local {
+	case "integer":
+		return strconv.ParseInt(text, 10, 64)
+	case "real":
+		return strconv.ParseFloat(text, 64)
+	}
+	return text, nil // string, date, data
+}
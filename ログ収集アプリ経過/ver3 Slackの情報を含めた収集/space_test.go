@@ -0,0 +1,83 @@
+package main
+
+import "testing"
+
+// plutil -convert json が失敗する <data> と <date> を含む、defaults export の出力
+const spacesPlist = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>SpacesDisplayConfiguration</key>
+	<dict>
+		<key>Management Data</key>
+		<dict>
+			<key>Monitors</key>
+			<array>
+				<dict>
+					<key>Collapsed Space</key>
+					<dict>
+						<key>ManagedSpaceID</key>
+						<integer>1</integer>
+					</dict>
+					<key>Display Identifier</key>
+					<string>Main</string>
+					<key>Current Space</key>
+					<dict>
+						<key>ManagedSpaceID</key>
+						<integer>42</integer>
+						<key>uuid</key>
+						<string>6D1C7C0B-0000-0000-0000-000000000000</string>
+					</dict>
+					<key>Spaces</key>
+					<array>
+						<dict>
+							<key>ManagedSpaceID</key>
+							<integer>3</integer>
+						</dict>
+						<dict>
+							<key>ManagedSpaceID</key>
+							<integer>42</integer>
+							<key>fs_wid</key>
+							<true/>
+						</dict>
+					</array>
+				</dict>
+			</array>
+		</dict>
+		<key>Space Properties</key>
+		<array>
+			<dict>
+				<key>name</key>
+				<string>6D1C7C0B</string>
+				<key>windows</key>
+				<data>AAECAw==</data>
+			</dict>
+		</array>
+	</dict>
+	<key>lastModified</key>
+	<date>2025-09-01T01:00:00Z</date>
+	<key>ratio</key>
+	<real>1.5</real>
+</dict>
+</plist>
+`
+
+func TestParseSpacesConfig(t *testing.T) {
+	n, err := parseSpacesConfig(spacesPlist)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("space = %d, want 2", n)
+	}
+}
+
+func TestParseSpacesConfigErrors(t *testing.T) {
+	if _, err := parseSpacesConfig("<plist><dict><key>a</key>"); err == nil {
+		t.Error("truncated plist: want error")
+	}
+	n, err := parseSpacesConfig(`<plist version="1.0"><dict><key>other</key><string>x</string></dict></plist>`)
+	if err != nil || n != 0 {
+		t.Errorf("plist without spaces = %d, %v; want 0, nil", n, err)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "modernc.org/sqlite" // cgo のいらない SQLite ドライバ（database/sql の "sqlite"）
+)
+
+/********** SQLite への保存（SHIRUSIA_OUTPUT=sqlite） **********/
+// 何日分ものセッションを横断して調べるときに activity_*.json を1つずつ開かなくて済むよう、
+// セッションと Slack のメッセージを logDir/activity.db にまとめて書く。
+//   sqlite3 ~/Library/Application\ Support/Shirusia/log/activity.db \
+//     "SELECT activity, SUM(duration_sec) FROM sessions WHERE start >= '2025-09-01' GROUP BY activity"
+// database/sql と modernc.org/sqlite（純 Go。cgo も sqlite3 コマンドもいらない）で、用意した文（prepared
+// statement）に値を渡して書く。1件ごとに自動コミットし、synchronous=FULL なので、
+// AppendSession が nil を返したセッションはディスクに残っている。
+// 接続は1本だけにして、メインループと Slack の受信からの書き込みを順に処理する。
+// ほかのプロセス（sqlite3 で調べている最中など）がロックしていれば5秒まで待つ。
+//
+// sessions には主な列のほかに、セッション全体を JSON で入れる（meta などは json_extract で引ける）。
+// このモードではセッションファイルを作らないので、-pretty・-max-file-size・{"cmd":"rotate"} は効かない。
+// summarize と suggest は DB から読むが、report などほかのサブコマンドはファイルしか読まない。
+const sqliteSchema = `
+PRAGMA journal_mode=WAL;
+CREATE TABLE IF NOT EXISTS sessions (
+	id           INTEGER PRIMARY KEY,
+	start        TEXT NOT NULL,
+	"end"        TEXT NOT NULL,
+	app          TEXT NOT NULL,
+	title        TEXT NOT NULL,
+	activity     TEXT NOT NULL,
+	duration_sec INTEGER NOT NULL,
+	json         TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS sessions_start ON sessions(start);
+CREATE TABLE IF NOT EXISTS messages (
+	id        INTEGER PRIMARY KEY,
+	timestamp TEXT NOT NULL,
+	source    TEXT NOT NULL,
+	direction TEXT NOT NULL,
+	title     TEXT NOT NULL,
+	text      TEXT NOT NULL,
+	meta      TEXT NOT NULL
+);
+`
+
+type sqliteSink struct {
+	path       string
+	db         *sql.DB
+	insertSess *sql.Stmt
+	insertMsg  *sql.Stmt
+}
+
+// messageDB が nil でなければ Slack のメッセージもファイルではなくこの DB に書く（saveMessage）
+var messageDB *sqliteSink
+
+// 接続ごとに設定する PRAGMA（接続が作り直されても同じになるよう DSN で渡す）
+const sqlitePragmas = "?_pragma=busy_timeout(5000)&_pragma=synchronous(FULL)"
+
+// dir/activity.db を開き（なければ作り）、テーブルと書き込み用の文を用意する
+func openSQLiteSink(dir string) (*sqliteSink, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, "activity.db")
+	db, err := sql.Open("sqlite", path+sqlitePragmas)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	sink := &sqliteSink{path: path, db: db}
+	if err := sink.prepare(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return sink, nil
+}
+
+func (s *sqliteSink) prepare() (err error) {
+	if _, err = s.db.Exec(sqliteSchema); err != nil {
+		return err
+	}
+	s.insertSess, err = s.db.Prepare(`INSERT INTO sessions (start, "end", app, title, activity, duration_sec, json) VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	s.insertMsg, err = s.db.Prepare(`INSERT INTO messages (timestamp, source, direction, title, text, meta) VALUES (?, ?, ?, ?, ?, ?)`)
+	return err
+}
+
+func (s *sqliteSink) AppendSession(se *session) error {
+	b, err := json.Marshal(se)
+	if err != nil {
+		return err
+	}
+	_, err = s.insertSess.Exec(se.Start, se.End, se.App, se.Title, se.Activity, se.DurationSec, string(b))
+	return err
+}
+
+func (s *sqliteSink) AppendMessage(m *messageEntry) error {
+	meta := "{}"
+	if len(m.Meta) > 0 {
+		b, err := json.Marshal(m.Meta)
+		if err != nil {
+			return err
+		}
+		meta = string(b)
+	}
+	_, err := s.insertMsg.Exec(m.Timestamp, m.Source, m.Direction, m.Title, m.Text, meta)
+	return err
+}
+
+func (s *sqliteSink) Close() error {
+	s.insertSess.Close()
+	s.insertMsg.Close()
+	return s.db.Close()
+}
+
+// [from, to) に重なるセッションを開始順に読む（summarize・suggest 用）
+func readSessionsDB(path string, from, to time.Time) ([]session, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", path+sqlitePragmas+"&_pragma=query_only(1)")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	rows, err := db.Query(`SELECT json FROM sessions WHERE "end" >= ? AND start < ? ORDER BY start`,
+		from.Format(time.RFC3339), to.Format(time.RFC3339))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []session
+	for rows.Next() {
+		var raw string
+		if err := rows.Scan(&raw); err != nil {
+			return nil, err
+		}
+		var s session
+		if err := json.Unmarshal([]byte(raw), &s); err == nil {
+			out = append(out, s)
+		}
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSQLiteSinkRoundTrip(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "Application Support", "log") // 空白を含むパス
+	db, err := openSQLiteSink(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := []session{
+		{Start: "2025-09-01T09:00:00+09:00", End: "2025-09-01T09:30:00+09:00", App: "Visual Studio Code",
+			Title: "it's main.go'); DROP TABLE sessions;--", Activity: "プログラムの制作", DurationSec: 1800,
+			Meta: map[string]string{"rawApp": "Code"}},
+		{Start: "2025-09-01T10:00:00+09:00", End: "2025-09-01T10:05:00+09:00", App: "Slack", Activity: "コミュニケーション", DurationSec: 300},
+		{Start: "2025-09-02T09:00:00+09:00", End: "2025-09-02T09:10:00+09:00", App: "Slack", Activity: "コミュニケーション", DurationSec: 600},
+	}
+	for i := range in {
+		if err := db.AppendSession(&in[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.AppendMessage(&messageEntry{Timestamp: "2025-09-01T09:10:00+09:00", Source: "slack", Direction: "out", Text: "O'Reilly の本"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	jst := time.FixedZone("JST", 9*60*60)
+	got, err := readSessionsDB(filepath.Join(dir, "activity.db"),
+		time.Date(2025, 9, 1, 0, 0, 0, 0, jst), time.Date(2025, 9, 2, 0, 0, 0, 0, jst))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d sessions for 2025-09-01, want 2: %+v", len(got), got)
+	}
+	if got[0].Title != in[0].Title || got[0].Meta["rawApp"] != "Code" || got[1].App != "Slack" {
+		t.Errorf("sessions = %+v", got)
+	}
+
+	raw, err := sql.Open("sqlite", filepath.Join(dir, "activity.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer raw.Close()
+	var text string
+	if err := raw.QueryRow(`SELECT text FROM messages`).Scan(&text); err != nil || text != "O'Reilly の本" {
+		t.Errorf("message text = %q, %v", text, err)
+	}
+}
+
+func TestReadSessionsDBMissing(t *testing.T) {
+	if _, err := readSessionsDB(filepath.Join(t.TempDir(), "activity.db"), time.Now().Add(-time.Hour), time.Now()); err == nil {
+		t.Error("missing database: want an error")
+	}
+}
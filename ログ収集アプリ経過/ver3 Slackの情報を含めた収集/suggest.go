@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/********** suggest サブコマンド **********/
+// 最近のログから「その他」に落ちた時間の長いアプリと、ブラウザで「その他」「Webブラウジング」のままだった
+// 時間の長いサイト（URL のホスト）を集計し、分類ルール（rules.yaml）の雛形を出力する。
+// ログは SHIRUSIA_OUTPUT に合わせて、セッションファイル（.json / .ndjson）か activity.db から読む。
+// サイトは URL を記録しているセッション（ブラウザのタブ）のみ。
+//   activitylog suggest [-days 7] [-top 10]
+func runSuggest(args []string) int {
+	fs := flag.NewFlagSet("suggest", flag.ExitOnError)
+	days := fs.Int("days", 7, "scan sessions from the last N days")
+	top := fs.Int("top", 10, "number of suggestions to print for apps and for sites")
+	fs.Parse(args)
+
+	now := time.Now()
+	ss, err := readLoggedSessions(logDir, now.AddDate(0, 0, -*days), now)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "suggest: %v\n", err)
+		return 1
+	}
+	apps, hosts := unclassifiedTotals(ss)
+	if len(apps) == 0 && len(hosts) == 0 {
+		fmt.Printf("# 直近%d日（%dセッション）に分類されていない時間はありませんでした\n", *days, len(ss))
+		return 0
+	}
+
+	fmt.Printf("# 直近%d日（%dセッション）で分類されていない時間の長いアプリ・サイト\n", *days, len(ss))
+	fmt.Println("# category を埋めてルールファイル（rules.yaml）の rules: に貼り付けてください")
+	for _, a := range topKeys(apps, *top) {
+		fmt.Printf("- app: %s  # %s\n", strconv.Quote(a), time.Duration(apps[a])*time.Second)
+		fmt.Println("  category: \"TODO\"")
+	}
+	for _, h := range topKeys(hosts, *top) {
+		fmt.Printf("- url: %s  # %s\n", strconv.Quote(h), time.Duration(hosts[h])*time.Second)
+		fmt.Println("  category: \"TODO\"")
+	}
+	return 0
+}
+
+// 「その他」のアプリ別の秒数と、「その他」「Webブラウジング」のページのホスト別の秒数（"www." は外す）
+func unclassifiedTotals(ss []session) (apps, hosts map[string]int64) {
+	apps, hosts = map[string]int64{}, map[string]int64{}
+	for _, s := range ss {
+		if s.Activity != defaultActivity && s.Activity != "Webブラウジング" {
+			continue
+		}
+		if s.Activity == defaultActivity && s.App != "" {
+			apps[s.App] += s.DurationSec
+		}
+		if s.URL == "" {
+			continue
+		}
+		u, err := url.Parse(s.URL)
+		if err != nil || u.Hostname() == "" {
+			continue
+		}
+		hosts[strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")] += s.DurationSec
+	}
+	return apps, hosts
+}
+
+// 秒数の多い順に最大 n 個（同じなら名前順）
+func topKeys(totals map[string]int64, n int) []string {
+	keys := make([]string, 0, len(totals))
+	for k := range totals {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if totals[keys[i]] != totals[keys[j]] {
+			return totals[keys[i]] > totals[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+	return keys
+}
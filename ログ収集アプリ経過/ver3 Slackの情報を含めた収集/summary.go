@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+/********** 実行中の定期サマリ（-summary-interval） **********/
+// 終了やレポートを待たずに途中経過を見られるよう、一定間隔で当日のカテゴリ別合計を標準出力に出す。
+// 集計は HTTP の /today と同じく liveState（当日の確定済みセッション＋進行中のセッション）から行う。
+func runSummaries(every time.Duration, live *liveState, clock func() time.Time, stop <-chan struct{}) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+			printLiveSummary(live, clock())
+		}
+	}
+}
+
+func printLiveSummary(live *liveState, now time.Time) {
+	totals := map[string]int64{}
+	var total int64
+	for _, s := range live.Today(now) {
+		totals[s.Activity] += s.DurationSec
+		total += s.DurationSec
+	}
+	fmt.Fprintf(os.Stdout, "[summary %s] 今日の合計 %s\n", now.Format("15:04"), fmtDur(total))
+	printRanking(os.Stdout, totals, total, 0)
+}
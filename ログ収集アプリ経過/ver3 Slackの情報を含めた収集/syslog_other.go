@@ -0,0 +1,16 @@
+//go:build windows || plan9
+
+package main
+
+import "errors"
+
+// log/syslog が使えない環境向けのスタブ
+type syslogSink struct{}
+
+func newSyslogSink(facility, tag string) (*syslogSink, error) {
+	return nil, errors.New("syslog is not supported on this platform")
+}
+
+func (s *syslogSink) AppendSession(sess *session) error { return nil }
+
+func (s *syslogSink) Close() error { return nil }
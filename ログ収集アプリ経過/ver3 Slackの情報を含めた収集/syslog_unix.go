@@ -0,0 +1,54 @@
+//go:build !windows && !plan9
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/syslog"
+	"strings"
+)
+
+/********** syslog 出力（-syslog） **********/
+// 確定したセッションを1行のJSONとしてシステムログへ送る。
+// 既存のログ基盤（リモートsyslogコレクタなど）にそのまま流し込むためのもの。
+type syslogSink struct {
+	w *syslog.Writer
+}
+
+var syslogFacilities = map[string]syslog.Priority{
+	"user":   syslog.LOG_USER,
+	"daemon": syslog.LOG_DAEMON,
+	"local0": syslog.LOG_LOCAL0,
+	"local1": syslog.LOG_LOCAL1,
+	"local2": syslog.LOG_LOCAL2,
+	"local3": syslog.LOG_LOCAL3,
+	"local4": syslog.LOG_LOCAL4,
+	"local5": syslog.LOG_LOCAL5,
+	"local6": syslog.LOG_LOCAL6,
+	"local7": syslog.LOG_LOCAL7,
+}
+
+func newSyslogSink(facility, tag string) (*syslogSink, error) {
+	fac, ok := syslogFacilities[strings.ToLower(facility)]
+	if !ok {
+		return nil, fmt.Errorf("unknown syslog facility %q", facility)
+	}
+	w, err := syslog.New(fac|syslog.LOG_INFO, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &syslogSink{w: w}, nil
+}
+
+func (s *syslogSink) AppendSession(sess *session) error {
+	b, err := json.Marshal(sess)
+	if err != nil {
+		return err
+	}
+	return s.w.Info("session " + string(b))
+}
+
+func (s *syslogSink) Close() error {
+	return s.w.Close()
+}
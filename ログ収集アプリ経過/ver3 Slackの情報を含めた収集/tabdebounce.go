@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+/********** ブラウザのタブ切り替えのデバウンス（-tab-dwell） **********/
+// ブラウザではタブを次々に切り替えるので、タイトルが変わるたびに区切ると数秒のセッションが大量にできる。
+// 同じブラウザ内でタイトルだけが変わった場合は、そのタブに -tab-dwell 以上とどまったときに初めて
+// 新しいセッションとする（開始時刻はそのタブを最初に見た時刻）。通り過ぎただけのタブは記録せず、
+// その時間は直前のセッションに含める。アプリが変わったときはこれまでどおりすぐ区切る。
+// 既定は 0（無効）。有効にするとブラウザのセッションの区切り方が変わるので、-tab-dwell 5s のように指定したときだけ使う。
+type tabDebouncer struct {
+	dwell   time.Duration
+	pending *record   // 確定待ちのタブ
+	since   time.Time // pending を最初に見た時刻
+}
+
+// cur を今すぐ新しいセッションにせず保留するなら hold=true。
+// 保留していたタブが dwell を超えたら、そのレコードと開始時刻を返す（hold=false）。
+// prev → cur が同じブラウザ内のタブ切り替えでなければ保留を捨てて (cur, now, false) を返す。
+func (d *tabDebouncer) Observe(prev, cur *record, now time.Time) (next *record, at time.Time, hold bool) {
+	if d.dwell <= 0 || !isTabSwitch(prev, cur) {
+		d.pending = nil
+		return cur, now, false
+	}
+	if d.pending == nil || d.pending.Title != cur.Title {
+		d.pending, d.since = cur, now
+	}
+	if now.Sub(d.since) < d.dwell {
+		return nil, time.Time{}, true
+	}
+	next, at = d.pending, d.since
+	d.pending = nil
+	return next, at, false
+}
+
+// 直前のセッションに戻った（またはセッションが続いている）ので保留を捨てる
+func (d *tabDebouncer) Reset() {
+	d.pending = nil
+}
+
+// 同じブラウザで、通話・画面共有の状態やタイトルトリガーも変わらずにタイトル（タブ）だけが変わったか
+func isTabSwitch(prev, cur *record) bool {
+	if prev == nil || prev.Idle || prev.App != cur.App || prev.OnCall != cur.OnCall || prev.Sharing != cur.Sharing || prev.Trigger != cur.Trigger {
+		return false
+	}
+	return isBrowserApp(strings.ToLower(cur.App))
+}
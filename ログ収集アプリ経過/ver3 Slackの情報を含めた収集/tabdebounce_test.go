@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func chromeTab(title string) *record {
+	return &record{App: "Google Chrome", Title: title, Activity: "Webブラウジング"}
+}
+
+// タブを次々に切り替えて最後のタブにとどまる。通り過ぎたタブはセッションにならず、
+// とどまったタブはそのタブを最初に見た時刻から始まる
+func TestTabDebouncerRapidSwitching(t *testing.T) {
+	d := &tabDebouncer{dwell: 5 * time.Second}
+	start := testTime(10, 0)
+	last := chromeTab("Inbox")
+	polls := []struct {
+		sec   int
+		title string
+	}{
+		{1, "News"}, {2, "Weather"}, {3, "Docs"}, {4, "Docs"}, {6, "Docs"}, {8, "Docs"},
+	}
+	var started []string
+	var startedAt []time.Time
+	for _, p := range polls {
+		cur := chromeTab(p.title)
+		next, at, hold := d.Observe(last, cur, start.Add(time.Duration(p.sec)*time.Second))
+		if hold {
+			continue
+		}
+		started = append(started, next.Title)
+		startedAt = append(startedAt, at)
+		last = next
+	}
+	if len(started) != 1 || started[0] != "Docs" {
+		t.Fatalf("sessions started = %v, want [Docs]", started)
+	}
+	if want := start.Add(3 * time.Second); !startedAt[0].Equal(want) {
+		t.Errorf("Docs started at %s, want %s (first seen)", startedAt[0].Format("15:04:05"), want.Format("15:04:05"))
+	}
+}
+
+func TestTabDebouncerAppSwitchIsImmediate(t *testing.T) {
+	d := &tabDebouncer{dwell: 5 * time.Second}
+	now := testTime(10, 0)
+	slack := &record{App: "Slack", Title: "#general", Activity: "コミュニケーション"}
+	if next, at, hold := d.Observe(chromeTab("Inbox"), slack, now); hold || next != slack || !at.Equal(now) {
+		t.Errorf("app switch held or delayed: next=%v at=%s hold=%v", next, at, hold)
+	}
+}
+
+// 既定（0）では従来どおりタブを変えるたびに区切る
+func TestTabDebouncerDisabled(t *testing.T) {
+	d := &tabDebouncer{}
+	now := testTime(10, 0)
+	cur := chromeTab("News")
+	if next, _, hold := d.Observe(chromeTab("Inbox"), cur, now); hold || next != cur {
+		t.Error("tab switch held with -tab-dwell 0")
+	}
+}
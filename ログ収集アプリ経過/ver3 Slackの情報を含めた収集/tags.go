@@ -0,0 +1,48 @@
+package main
+
+import "strings"
+
+/********** タグ（-tags） **********/
+// 主カテゴリは1つだが、1つの作業には複数の側面がある（例: コーディング中にGitHubを見ている）。
+// -tags を付けると、一致したすべての分類ルールのカテゴリと、下の観点タグを session.tags に入れる。
+// 既存の activity はそのままなので、tags を読まない利用側には影響しない。
+type facetTag struct {
+	Tag  string
+	Keys []string // アプリ名・タイトル・URL（小文字）のいずれかに含まれれば付与
+}
+
+var facetTags = []facetTag{
+	{"github", []string{"github"}},
+	{"gitlab", []string{"gitlab"}},
+	{"stackoverflow", []string{"stackoverflow", "stack overflow"}},
+	{"google-docs", []string{"docs.google.com", "google docs", "google ドキュメント"}},
+	{"jira", []string{"jira", "atlassian.net"}},
+	{"notion", []string{"notion"}},
+	{"youtube", []string{"youtube", "youtu.be"}},
+	{"figma", []string{"figma"}},
+}
+
+func classifyTags(in activityInput) []string {
+	a := strings.ToLower(in.App)
+	t := strings.ToLower(in.Title)
+	var tags []string
+	seen := map[string]bool{}
+	add := func(tag string) {
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	for _, r := range builtinRules {
+		if r.Match(in, a, t) {
+			add(r.Category)
+		}
+	}
+	hay := a + "\n" + t + "\n" + strings.ToLower(in.URL)
+	for _, f := range facetTags {
+		if hasAny(hay, f.Keys) {
+			add(f.Tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+/********** ターミナル作業 **********/
+// ターミナル（Terminal / iTerm2 / Warp / Alacritty など）は、タイトルにソースファイル名やインフラ系コマンドが
+// 出ていなければ「ターミナル作業」にする。タイトルに git のブランチやビルドのコマンドが出ていれば
+// （シェルのプロンプトや実行中のコマンドをタイトルに出す設定のとき）「プログラムの制作」にする。
+//   "~/src/app (main)" "~/src/app [feature/x]" "git:(main)"  プロンプトのブランチ表示
+//   "make test" "git rebase -i" "cargo build" "npm run dev" "go test ./..."  実行中のコマンド
+// 組み込みにないターミナルは、ルールファイル（rules.go）の terminalApps にアプリ名か bundle ID を書く。
+const terminalActivity = "ターミナル作業"
+
+var terminalApps = []string{"terminal", "iterm", "warp", "alacritty", "kitty", "wezterm", "ghostty", "hyper"}
+
+var terminalTools = keywordCategory{
+	Bundles: []string{
+		"com.apple.terminal",
+		"com.googlecode.iterm2",
+		"dev.warp.warp",
+		"org.alacritty",
+		"io.alacritty",
+		"net.kovidgoyal.kitty",
+		"com.github.wez.wezterm",
+		"com.mitchellh.ghostty",
+		"co.zeit.hyper",
+	},
+	Apps:  terminalApps,
+	Extra: userRules.TerminalApps,
+}
+
+// ビルド・バージョン管理のコマンド（タイトルの単語と完全一致で比べる）
+var terminalDevCommands = []string{
+	"git", "lazygit", "tig", "make", "gmake", "cmake", "ninja", "bazel",
+	"cargo", "npm", "npx", "yarn", "pnpm", "gradle", "gradlew", "mvn",
+}
+
+var terminalDevTitles = textMatcher{
+	res: []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\bgit:\([\w./-]+\)`),                       // oh-my-zsh のプロンプト
+		regexp.MustCompile(`[~/][\w./-]*\s+[(\[][\w./-]+[)\]]`),            // "~/src/app (main)"
+		regexp.MustCompile(`(?i)\bgo\s+(?:build|test|run|vet|generate)\b`), // "go" だけでは当てない
+	},
+}
+
+func isTerminal(in activityInput) bool {
+	return terminalTools.match(in)
+}
+
+// タイトルにブランチの表示やビルド・バージョン管理のコマンドが出ているか
+func hasTerminalDevContext(title string) bool {
+	if terminalDevTitles.match(title) {
+		return true
+	}
+	for _, w := range strings.FieldsFunc(strings.ToLower(title), isTitleSeparator) {
+		if containsString(terminalDevCommands, w) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+func TestHasTerminalDevContext(t *testing.T) {
+	tests := []struct {
+		title string
+		want  bool
+	}{
+		// プロンプトのブランチ表示
+		{"~/src/app (main) — zsh", true},
+		{"~/src/app [feature/login] — bash", true},
+		{"➜  app git:(fix/idle-timer) ✗", true},
+		// 実行中のコマンド
+		{"make test — 120×40", true},
+		{"git rebase -i HEAD~3", true},
+		{"cargo build --release", true},
+		{"npm run dev — node", true},
+		{"go test ./...", true},
+		{"app — go build — 80×24", true},
+		{"./gradlew assemble", true},
+		// ただのシェル・開発に関係ないコマンド
+		{"~ — -zsh — 80×24", false},
+		{"~/go — zsh", false},
+		{"ssh deploy@bastion", false},
+		{"vim notes.txt", false},
+		{"htop", false},
+		{"gitlab-notes — zsh", false}, // 単語の一部は当てない
+		{"Let's go shopping", false},  // "go" だけでは当てない
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := hasTerminalDevContext(tt.title); got != tt.want {
+			t.Errorf("hasTerminalDevContext(%q) = %v, want %v", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestClassifyTerminal(t *testing.T) {
+	checkClassify(t, []classifyCase{
+		{"branch prompt", activityInput{App: "iTerm2", Title: "~/src/app (main) — zsh"}, "プログラムの制作"},
+		{"build command", activityInput{App: "Terminal", Title: "make test — 120×40"}, "プログラムの制作"},
+		{"bundle id", activityInput{App: "ターミナル", BundleID: "com.apple.Terminal", Title: "cargo build"}, "プログラムの制作"},
+		{"plain shell", activityInput{App: "Ghostty", Title: "~ — -zsh"}, terminalActivity},
+		{"ssh session", activityInput{App: "Warp", Title: "ssh deploy@bastion"}, terminalActivity},
+	})
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+/********** 時間帯によるカテゴリの上書き **********/
+// 同じアプリでも時間帯で用途が違う人向け（例: 朝のブラウザは仕事、夜は余暇）。
+// 通常のルールで分類した結果を、ローカル時刻の時間帯ごとに別のカテゴリへ読み替える。
+// ルール自体を変えるのではなく、分類結果の上に重ねる上書きの層なので、
+// 時間帯の外では通常の分類のまま。タイトルトリガー（SHIRUSIA_TITLE_TRIGGERS）はこれより優先する。
+//
+// SHIRUSIA_TIME_OVERRIDES="<HH:MM-HH:MM> <元のカテゴリ>=><新しいカテゴリ>;..."
+//   例: SHIRUSIA_TIME_OVERRIDES="19:00-05:00 Webブラウジング=>余暇;12:00-13:00 メディア視聴・再生=>休憩"
+//   同じ時刻・カテゴリに複数一致したら先に書いたものを使う。
+type timeOverride struct {
+	window   clockWindow
+	from, to string
+}
+
+var timeOverrides = loadTimeOverrides()
+
+func loadTimeOverrides() []timeOverride {
+	var out []timeOverride
+	for _, part := range strings.Split(os.Getenv("SHIRUSIA_TIME_OVERRIDES"), ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		o, err := parseTimeOverride(part)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warn: SHIRUSIA_TIME_OVERRIDES: %v\n", err)
+			continue
+		}
+		out = append(out, o)
+	}
+	return out
+}
+
+func parseTimeOverride(s string) (timeOverride, error) {
+	win, remap, ok := strings.Cut(s, " ")
+	from, to, ok2 := strings.Cut(remap, "=>")
+	from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+	if !ok || !ok2 || from == "" || to == "" {
+		return timeOverride{}, fmt.Errorf("invalid entry %q (want \"HH:MM-HH:MM from=>to\")", s)
+	}
+	ws, err := parseClockWindows(win)
+	if err != nil || len(ws) != 1 {
+		return timeOverride{}, fmt.Errorf("invalid window in %q (want HH:MM-HH:MM)", s)
+	}
+	return timeOverride{window: ws[0], from: from, to: to}, nil
+}
+
+// 時刻 t（ローカル）に合わせて分類結果を読み替える
+func remapByTime(activity string, t time.Time) string {
+	for _, o := range timeOverrides {
+		if o.from == activity && o.window.contains(t) {
+			return o.to
+		}
+	}
+	return activity
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+/********** セッションの区切り方（-track） **********/
+//   title    : アプリ / タイトル / カテゴリ のどれかが変わったら区切る（従来の挙動・既定）
+//   app      : アプリかカテゴリが変わったら区切る（同じアプリ内のタイトル変化は1セッション）
+//   category : カテゴリが変わったときだけ区切る。カテゴリ内でアプリを行き来しても1セッションとし、
+//              その間に前面になったアプリは meta.apps に出現順で残す
+type trackMode string
+
+const (
+	trackTitle    trackMode = "title"
+	trackApp      trackMode = "app"
+	trackCategory trackMode = "category"
+)
+
+func parseTrackMode(s string) (trackMode, error) {
+	switch m := trackMode(strings.ToLower(strings.TrimSpace(s))); m {
+	case trackTitle, trackApp, trackCategory:
+		return m, nil
+	case "":
+		return trackTitle, nil
+	}
+	return "", fmt.Errorf("unknown -track mode %q (title, app, category)", s)
+}
+
+// mode に応じて、cur が新しいセッションの始まりかどうか
+func sessionChanged(mode trackMode, prev, cur *record) bool {
+	if prev == nil {
+		return true
+	}
+	if prev.OnCall != cur.OnCall || prev.Sharing != cur.Sharing || prev.Trigger != cur.Trigger {
+		return true // 通話・画面共有の開始/終了、タイトルトリガーの一致/解除はどのモードでも区切る
+	}
+	// メディアアプリ内の曲送りはどのモードでも区切らない（曲名は meta.tracks へ）
+	if prev.App == cur.App && isMediaApp(cur.App) {
+		return false
+	}
+	switch mode {
+	case trackCategory:
+		return prev.Activity != cur.Activity
+	case trackApp:
+		return prev.App != cur.App || prev.Activity != cur.Activity
+	}
+	return changed(prev, cur)
+}
+
+// 1回の取得（ティック）を進行中のセッションに対してどう扱うか
+type tickStep int
+
+const (
+	tickSkip   tickStep = iota // 切り替え途中などでアプリ名が空。記録せず、進行中のセッションにそのまま含める
+	tickSame                   // 進行中のセッションの続き
+	tickSwitch                 // 新しいセッションの候補（flicker・grace を通してから区切る）
+)
+
+// last は進行中のセッション（なければ nil）。アイドル中の取得は必ず新しいセッションの候補になる
+func nextTickStep(mode trackMode, last, cur *record) tickStep {
+	switch {
+	case isEmptyRecord(cur):
+		return tickSkip
+	case last != nil && !last.Idle && !sessionChanged(mode, last, cur):
+		return tickSame
+	}
+	return tickSwitch
+}
+
+// 同じセッションの中で前面になったアプリを記録する（重複なし・出現順）
+func (r *record) noteApp(app string) {
+	app = clean(app)
+	if app == "" {
+		return
+	}
+	for _, a := range r.Apps {
+		if a == app {
+			return
+		}
+	}
+	r.Apps = append(r.Apps, app)
+}
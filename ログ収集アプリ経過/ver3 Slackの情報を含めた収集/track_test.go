@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTrackCategoryKeepsSessionAcrossApps(t *testing.T) {
+	vscode := &record{App: "Visual Studio Code", Title: "main.go", Activity: "プログラムの制作"}
+	vscode.noteApp(vscode.App)
+	iterm := &record{App: "iTerm2", Title: "go test ./...", Activity: "プログラムの制作"}
+	slack := &record{App: "Slack", Title: "#general", Activity: "コミュニケーション"}
+
+	if sessionChanged(trackCategory, vscode, iterm) {
+		t.Error("category mode: app switch within the same category started a new session")
+	}
+	if !sessionChanged(trackCategory, vscode, slack) {
+		t.Error("category mode: category change did not start a new session")
+	}
+	if !sessionChanged(trackTitle, vscode, iterm) || !sessionChanged(trackApp, vscode, iterm) {
+		t.Error("title/app mode: app switch did not start a new session")
+	}
+
+	// メインループと同じく、続いているセッションに前面になったアプリを足す
+	vscode.noteApp(iterm.App)
+	vscode.noteApp(vscode.App)
+	start := time.Date(2025, 9, 1, 10, 0, 0, 0, time.Local)
+	s := sessionFrom(vscode, start, start.Add(10*time.Minute))
+	if got := s.Meta["apps"]; got != "Visual Studio Code, iTerm2" {
+		t.Errorf("meta.apps = %q, want %q", got, "Visual Studio Code, iTerm2")
+	}
+}
+
+func TestTrackAppKeepsSessionAcrossTitles(t *testing.T) {
+	a := &record{App: "Visual Studio Code", Title: "main.go", Activity: "プログラムの制作"}
+	b := &record{App: "Visual Studio Code", Title: "util.go", Activity: "プログラムの制作"}
+	if sessionChanged(trackApp, a, b) {
+		t.Error("app mode: title change started a new session")
+	}
+	if !sessionChanged(trackTitle, a, b) {
+		t.Error("title mode: title change did not start a new session")
+	}
+}
+
+func TestTrackModesSplitOnCallChange(t *testing.T) {
+	a := &record{App: "zoom.us", Activity: "会議・通話"}
+	b := &record{App: "zoom.us", Activity: "会議・通話", OnCall: true}
+	for _, m := range []trackMode{trackTitle, trackApp, trackCategory} {
+		if !sessionChanged(m, a, b) {
+			t.Errorf("%s mode: onCall change did not start a new session", m)
+		}
+	}
+}
+
+func TestParseTrackMode(t *testing.T) {
+	for in, want := range map[string]trackMode{"": trackTitle, "Category": trackCategory, " app ": trackApp} {
+		if got, err := parseTrackMode(in); err != nil || got != want {
+			t.Errorf("parseTrackMode(%q) = %q, %v", in, got, err)
+		}
+	}
+	if _, err := parseTrackMode("window"); err == nil {
+		t.Error("parseTrackMode(window) returned no error")
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+/********** タイトルによる区切り・カテゴリ指定 **********/
+// 外部ツールがウィンドウタイトルだけでロガーに合図できるようにする
+// （例: ポモドーロタイマーが休憩中にタイトルへ "BREAK" と出す）。
+//
+// SHIRUSIA_TITLE_TRIGGERS="<正規表現>[=><カテゴリ>];..."
+//   タイトルが一致したら、アプリに関係なくその時点でセッションを区切る（一致しなくなったときも区切る）。
+//   カテゴリを書いた場合は、一致している間の分類をそのカテゴリで上書きする。
+//   例: SHIRUSIA_TITLE_TRIGGERS="\bBREAK\b=>休憩;^🍅 FOCUS"
+type titleTrigger struct {
+	re       *regexp.Regexp
+	category string // 空なら分類はそのまま
+}
+
+var titleTriggers = loadTitleTriggers()
+
+func loadTitleTriggers() []titleTrigger {
+	var out []titleTrigger
+	for _, part := range strings.Split(os.Getenv("SHIRUSIA_TITLE_TRIGGERS"), ";") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		pat, category, _ := strings.Cut(part, "=>")
+		re, err := regexp.Compile(strings.TrimSpace(pat))
+		if err != nil || strings.TrimSpace(pat) == "" {
+			fmt.Fprintf(os.Stderr, "warn: SHIRUSIA_TITLE_TRIGGERS: invalid entry %q\n", part)
+			continue
+		}
+		out = append(out, titleTrigger{re: re, category: strings.TrimSpace(category)})
+	}
+	return out
+}
+
+// 最初に一致したトリガー（なければ nil）
+func matchTitleTrigger(title string) *titleTrigger {
+	for i := range titleTriggers {
+		if titleTriggers[i].re.MatchString(title) {
+			return &titleTriggers[i]
+		}
+	}
+	return nil
+}
+
+// トリガーの一致状態を record に反映する
+func (r *record) applyTrigger(t *titleTrigger) {
+	if t == nil {
+		return
+	}
+	r.Trigger = t.re.String()
+	if t.category != "" {
+		r.Activity = t.category
+		r.MatchedBy = "trigger"
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+/********** 保存するURLの整形 **********/
+// ブラウザのURLにはトークン・セッションID・検索語（?q=...）が入りやすいので、取得した時点で削る（sanitizeURL）。
+// 分類もこの整形後のURLで行う（ホストとパスしか見ないので結果は変わらない）。
+//   SHIRUSIA_URL_MODE=path|host|full  （既定 path）
+//     path  scheme://host/path だけを残す。クエリとフラグメントは捨てる（SHIRUSIA_URL_KEEP_PARAMS のキーだけ残す）
+//     host  scheme://host だけを残す
+//     full  クエリとフラグメントを残し、機微なパラメータ（SHIRUSIA_URL_STRIP_PARAMS）だけを除く
+//   SHIRUSIA_URL_KEEP_PARAMS="v,list"  path で残すクエリのキー（既定は defaultKeepParams を置き換え。"-" で何も残さない）
+//     機微なパラメータに当たるキーは一覧にあっても残さない
+//   SHIRUSIA_URL_STRIP_PARAMS="token,password,..."  機微なパラメータのキー（既定は defaultSensitiveParams を置き換え）
+//   SHIRUSIA_URL_STRIP_QUERY="1"  どのモードでもクエリを丸ごと落とす
+// どのモードでもユーザー名・パスワード（user:pass@host）は落とす。
+// javascript: / data: / blob: / vbscript: は中身がスクリプトや本文なので保存しない（空にする）。
+// 解析できないもの・スキームのないものも空にする。file:// はパスを残す（host では "file://" だけ）。
+// about:blank などはそのまま、mailto: などほかの不透明なURLはスキームだけを残す。
+const (
+	urlModePath = "path"
+	urlModeHost = "host"
+	urlModeFull = "full"
+)
+
+var defaultKeepParams = []string{"v", "list", "page"}
+
+var droppedURLSchemes = map[string]bool{"javascript": true, "data": true, "blob": true, "vbscript": true}
+
+// 機微なパラメータのキーは大文字小文字を無視し、"access_token" のように区切り付きで含む場合も除去する。
+var defaultSensitiveParams = []string{
+	"token", "access_token", "id_token", "refresh_token", "auth", "code",
+	"password", "passwd", "pwd", "secret", "key", "apikey", "api_key",
+	"sig", "signature", "session", "sessionid", "sid",
+}
+
+var (
+	sensitiveParams = loadSensitiveParams()
+	stripWholeQuery = strings.TrimSpace(os.Getenv("SHIRUSIA_URL_STRIP_QUERY")) == "1"
+	urlMode         = loadURLMode()
+	keepParams      = loadKeepParams()
+)
+
+func loadURLMode() string {
+	switch v := strings.ToLower(strings.TrimSpace(os.Getenv("SHIRUSIA_URL_MODE"))); v {
+	case "", urlModePath:
+		return urlModePath
+	case urlModeHost, urlModeFull:
+		return v
+	default:
+		fmt.Fprintf(os.Stderr, "warn: SHIRUSIA_URL_MODE: unknown value %q (path, host, full); using path\n", v)
+		return urlModePath
+	}
+}
+
+func loadKeepParams() []string {
+	raw := strings.TrimSpace(os.Getenv("SHIRUSIA_URL_KEEP_PARAMS"))
+	if raw == "-" {
+		return nil
+	}
+	if v := splitList(raw); len(v) > 0 {
+		return v
+	}
+	return defaultKeepParams
+}
+
+// 保存してよい形にしたURL。保存しないものは空
+func sanitizeURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" {
+		return ""
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if droppedURLSchemes[scheme] {
+		return ""
+	}
+	if u.Opaque != "" {
+		if scheme == "about" {
+			return "about:" + u.Opaque
+		}
+		return scheme + ":"
+	}
+	u.Scheme = scheme
+	u.User = nil
+	switch urlMode {
+	case urlModeFull:
+		return stripSensitiveQuery(u.String())
+	case urlModeHost:
+		if u.Host == "" {
+			return scheme + "://"
+		}
+		return (&url.URL{Scheme: scheme, Host: u.Host}).String()
+	}
+	u.RawQuery = keptQuery(u.Query())
+	u.ForceQuery = false
+	u.Fragment, u.RawFragment = "", ""
+	return u.String()
+}
+
+// path モードで残すクエリ（SHIRUSIA_URL_KEEP_PARAMS にあって機微でないキーだけ）
+func keptQuery(q url.Values) string {
+	if stripWholeQuery {
+		return ""
+	}
+	out := url.Values{}
+	for k, v := range q {
+		if containsString(keepParams, strings.ToLower(k)) && !isSensitiveParam(k) {
+			out[k] = v
+		}
+	}
+	return out.Encode()
+}
+
+func loadSensitiveParams() []string {
+	if v := splitList(os.Getenv("SHIRUSIA_URL_STRIP_PARAMS")); len(v) > 0 {
+		return v
+	}
+	return defaultSensitiveParams
+}
+
+// 機微なパラメータを除いたURLを返す（full モード）。解析できないURLは保存しないよう空にする
+func stripSensitiveQuery(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	u.User = nil // user:pass@host も落とす
+	if stripWholeQuery {
+		u.RawQuery = ""
+		return u.String()
+	}
+	q := u.Query()
+	for k := range q {
+		if isSensitiveParam(k) {
+			q.Del(k)
+		}
+	}
+	u.RawQuery = q.Encode()
+	// OAuthのimplicitフローなどはフラグメントにトークンが入る（#access_token=...）
+	if strings.Contains(u.Fragment, "=") {
+		if fq, err := url.ParseQuery(u.Fragment); err == nil {
+			for k := range fq {
+				if isSensitiveParam(k) {
+					fq.Del(k)
+				}
+			}
+			u.Fragment = fq.Encode()
+			u.RawFragment = ""
+		}
+	}
+	return u.String()
+}
+
+func isSensitiveParam(key string) bool {
+	k := strings.ToLower(key)
+	for _, p := range sensitiveParams {
+		if k == p || strings.HasSuffix(k, "_"+p) || strings.HasSuffix(k, "-"+p) ||
+			strings.HasPrefix(k, p+"_") || strings.HasPrefix(k, p+"-") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+/********** bundle ID のベンダーによる補助分類 **********/
+// どのルールにも当たらなかった（「その他」になる）アプリを、bundle ID の接頭辞で分類する。
+// 同じベンダーのアプリは用途が近いことが多い（JetBrains の IDE、iWork など）ので、
+// アプリごとにルールを書かなくても「その他」を減らせる。通常のルールより優先はしない。
+//
+// SHIRUSIA_VENDOR_RULES="com.jetbrains.*=プログラムの制作,com.example.*=ドキュメント編集"
+//   既定の表より先に評価する。接頭辞は "com.jetbrains" / "com.jetbrains." / "com.jetbrains.*" のどれでもよい。
+// SHIRUSIA_VENDOR_FALLBACK=0 で無効。
+type vendorRule struct {
+	prefix   string // 小文字、末尾の "." や ".*" を除いたもの
+	category string
+}
+
+var defaultVendorRules = []vendorRule{
+	{"com.jetbrains", "プログラムの制作"},
+	{"com.google.android.studio", "プログラムの制作"},
+	{"com.sublimetext", "プログラムの制作"},
+	{"com.panic.nova", "プログラムの制作"},
+	{"dev.zed", "プログラムの制作"},
+	{"com.apple.iwork.pages", "ドキュメント編集"},
+	{"com.apple.iwork.numbers", "表計算・データ整理"},
+	{"com.apple.iwork.keynote", "プレゼン資料作成"},
+	{"com.apple.iwork", "ドキュメント編集"},
+	{"com.adobe", "デザイン作業"},
+	{"com.microsoft.teams", "コミュニケーション"},
+}
+
+var vendorRules = loadVendorRules()
+
+func loadVendorRules() []vendorRule {
+	if strings.TrimSpace(os.Getenv("SHIRUSIA_VENDOR_FALLBACK")) == "0" {
+		return nil
+	}
+	var out []vendorRule
+	for _, part := range strings.Split(os.Getenv("SHIRUSIA_VENDOR_RULES"), ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		prefix, category, ok := strings.Cut(part, "=")
+		prefix = normalizeVendorPrefix(prefix)
+		category = strings.TrimSpace(category)
+		if !ok || prefix == "" || category == "" {
+			fmt.Fprintf(os.Stderr, "warn: SHIRUSIA_VENDOR_RULES: invalid entry %q\n", part)
+			continue
+		}
+		out = append(out, vendorRule{prefix: prefix, category: category})
+	}
+	return append(out, defaultVendorRules...)
+}
+
+func normalizeVendorPrefix(p string) string {
+	p = strings.ToLower(strings.TrimSpace(p))
+	p = strings.TrimSuffix(p, "*")
+	return strings.TrimSuffix(p, ".")
+}
+
+// bundle ID の接頭辞で決まるカテゴリと、一致した接頭辞（当たらなければ ""）。
+// "com.adobe" は "com.adobefoo" には当たらない
+func vendorMatch(bundleID string) (category, prefix string) {
+	b := strings.ToLower(strings.TrimSpace(bundleID))
+	if b == "" {
+		return "", ""
+	}
+	for _, r := range vendorRules {
+		if b == r.prefix || strings.HasPrefix(b, r.prefix+".") {
+			return r.category, r.prefix
+		}
+	}
+	return "", ""
+}
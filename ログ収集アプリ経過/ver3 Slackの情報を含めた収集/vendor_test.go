@@ -0,0 +1,64 @@
+package main
+
+import "testing"
+
+func TestVendorMatch(t *testing.T) {
+	tests := []struct {
+		bundleID, category, prefix string
+	}{
+		{"com.jetbrains.rider", "プログラムの制作", "com.jetbrains"},
+		{"COM.JetBrains.Toolbox", "プログラムの制作", "com.jetbrains"},
+		{"com.apple.iWork.Numbers", "表計算・データ整理", "com.apple.iwork.numbers"},
+		// より長い接頭辞が先に当たる
+		{"com.apple.iWork.Pages", "ドキュメント編集", "com.apple.iwork.pages"},
+		{"com.apple.iWork.Unknown", "ドキュメント編集", "com.apple.iwork"},
+		{"com.adobe", "デザイン作業", "com.adobe"},
+		// 区切りの "." までが接頭辞
+		{"com.adobefoo.app", "", ""},
+		{"com.example.app", "", ""},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		c, p := vendorMatch(tt.bundleID)
+		if c != tt.category || p != tt.prefix {
+			t.Errorf("vendorMatch(%q) = %q, %q; want %q, %q", tt.bundleID, c, p, tt.category, tt.prefix)
+		}
+	}
+}
+
+func TestLoadVendorRules(t *testing.T) {
+	defer func(rs []vendorRule) { vendorRules = rs }(vendorRules)
+
+	t.Setenv("SHIRUSIA_VENDOR_RULES", "com.example.*=ドキュメント編集, com.jetbrains.=調査・ドキュメント閲覧,broken,=x")
+	vendorRules = loadVendorRules()
+	if c, p := vendorMatch("com.example.writer"); c != "ドキュメント編集" || p != "com.example" {
+		t.Errorf("com.example.writer = %q, %q", c, p)
+	}
+	// 環境変数の表が既定より先
+	if c, _ := vendorMatch("com.jetbrains.rider"); c != "調査・ドキュメント閲覧" {
+		t.Errorf("override did not take precedence: %q", c)
+	}
+	if len(vendorRules) != len(defaultVendorRules)+2 {
+		t.Errorf("got %d rules, want the 2 valid entries plus the defaults", len(vendorRules))
+	}
+
+	t.Setenv("SHIRUSIA_VENDOR_FALLBACK", "0")
+	if rs := loadVendorRules(); rs != nil {
+		t.Errorf("SHIRUSIA_VENDOR_FALLBACK=0: got %d rules", len(rs))
+	}
+}
+
+// どのルールにも当たらないアプリだけがベンダーで分類される
+func TestClassifyVendorFallback(t *testing.T) {
+	got := classifyMatch(activityInput{App: "Toolbox", BundleID: "com.jetbrains.toolbox", Title: "Projects"})
+	if got.Category != "プログラムの制作" || got.MatchedBy != "vendor:com.jetbrains" {
+		t.Errorf("toolbox = %+v", got)
+	}
+	got = classifyMatch(activityInput{App: "Adobe Photoshop 2025", BundleID: "com.adobe.Photoshop", Title: "banner.psd"})
+	if got.MatchedBy == "vendor:com.adobe" {
+		t.Errorf("photoshop fell through to the vendor table: %+v", got)
+	}
+	if got := classifyMatch(activityInput{App: "Mystery", BundleID: "com.example.mystery"}); got.Category != defaultActivity {
+		t.Errorf("unknown vendor = %+v", got)
+	}
+}
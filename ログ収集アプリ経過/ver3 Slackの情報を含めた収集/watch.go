@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+)
+
+/********** watch サブコマンド **********/
+// ロガーを通常どおり動かしながら、確定したセッションを1行1JSON（JSONL）で標準出力に流す。
+// jq や fluent-bit などへパイプでつなぐための入口。
+//   activitylog watch [ロガーのフラグ ...] | jq -c 'select(.durationSec > 60)'
+// 標準出力をパイプ専用にするため、起動メッセージや start/end の表示などの人向けの出力は捨てる
+// （エラーや警告は従来どおり標準エラーへ）。1件ごとに直接 write するのでバッファに溜まらない。
+// パイプの読み手が先に終わったら（SIGPIPE）、Ctrl+C と同じく進行中のセッションを閉じて終了する。
+type jsonlWriter struct {
+	w io.Writer
+}
+
+func (j *jsonlWriter) AppendSession(s *session) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	_, err = j.w.Write(append(b, '\n'))
+	return err
+}
+
+// 今の標準出力をセッション用に確保し、以降の fmt.Print* の出力先を捨てる
+func startWatchOutput() *jsonlWriter {
+	out := &jsonlWriter{w: os.Stdout}
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		os.Stdout = os.Stderr // 捨てられなければ少なくともパイプには混ぜない
+	} else {
+		os.Stdout = devNull
+	}
+	return out
+}
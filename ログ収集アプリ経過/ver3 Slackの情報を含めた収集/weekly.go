@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+/********** report -weekly（曜日×時間帯のヒートマップ） **********/
+// 1週間分（引数なしなら直近7日）のセッションを曜日×時刻（ローカル時刻、1時間単位）に
+// 振り分けて、カテゴリごとのヒートマップを出す。「コーディングは火曜の午前が多い」のような傾向を見る用。
+// 時間をまたぐセッションは各時間帯に按分する。アイドル・休憩・離席は含めない。
+// -json で同じ内容を JSON で出す。
+var weekdayLabels = [7]string{"月", "火", "水", "木", "金", "土", "日"}
+
+const weeklyTopCategories = 6 // テキスト表示するカテゴリ数（時間の多い順）
+
+// [曜日(月=0)][時] → 秒
+type weekGrid [7][24]int64
+
+type weeklyCategory struct {
+	Category  string    `json:"category"`
+	TotalSec  int64     `json:"totalSec"`
+	ByWeekday [7]int64  `json:"byWeekday"` // 月〜日
+	ByHour    [24]int64 `json:"byHour"`
+	Grid      weekGrid  `json:"grid"` // [曜日][時]
+}
+
+type weeklyReport struct {
+	From       string           `json:"from"`
+	To         string           `json:"to"`
+	Categories []weeklyCategory `json:"categories"` // 時間の多い順
+}
+
+func aggregateWeekly(ss []session) weeklyReport {
+	grids := map[string]*weekGrid{}
+	var from, to time.Time
+	for _, s := range ss {
+		if s.App == idleApp || s.App == awayApp {
+			continue
+		}
+		start, err1 := time.Parse(time.RFC3339, s.Start)
+		end, err2 := time.Parse(time.RFC3339, s.End)
+		if err1 != nil || err2 != nil || !end.After(start) {
+			continue
+		}
+		start, end = start.Local(), end.Local()
+		if from.IsZero() || start.Before(from) {
+			from = start
+		}
+		if end.After(to) {
+			to = end
+		}
+		g := grids[s.Activity]
+		if g == nil {
+			g = &weekGrid{}
+			grids[s.Activity] = g
+		}
+		// 1時間ごとの境界で切りながら加算する
+		for t := start; t.Before(end); {
+			next := t.Truncate(time.Hour).Add(time.Hour)
+			if next.After(end) {
+				next = end
+			}
+			g[mondayIndex(t.Weekday())][t.Hour()] += int64(next.Sub(t) / time.Second)
+			t = next
+		}
+	}
+
+	totals := map[string]int64{}
+	for c, g := range grids {
+		for d := range g {
+			for h := range g[d] {
+				totals[c] += g[d][h]
+			}
+		}
+	}
+	r := weeklyReport{Categories: []weeklyCategory{}}
+	if !from.IsZero() {
+		r.From, r.To = from.Format("2006-01-02"), to.Format("2006-01-02")
+	}
+	for _, c := range sortedKeysByValue(totals) {
+		wc := weeklyCategory{Category: c, TotalSec: totals[c], Grid: *grids[c]}
+		for d := range wc.Grid {
+			for h, sec := range wc.Grid[d] {
+				wc.ByWeekday[d] += sec
+				wc.ByHour[h] += sec
+			}
+		}
+		r.Categories = append(r.Categories, wc)
+	}
+	return r
+}
+
+// time.Weekday（日=0）→ 月=0 の添字
+func mondayIndex(d time.Weekday) int {
+	return (int(d) + 6) % 7
+}
+
+func printWeekly(w io.Writer, r weeklyReport) {
+	if len(r.Categories) == 0 {
+		fmt.Fprintln(w, "no sessions")
+		return
+	}
+	fmt.Fprintf(w, "%s 〜 %s（濃いほど長い。カテゴリごとに最大のマスを基準に表示）\n", r.From, r.To)
+	for i, c := range r.Categories {
+		if i >= weeklyTopCategories {
+			fmt.Fprintf(w, "\n（ほか %d カテゴリは -json で確認できます）\n", len(r.Categories)-i)
+			break
+		}
+		fmt.Fprintf(w, "\n■ %s（合計 %s）\n", c.Category, fmtDur(c.TotalSec))
+		printHeatmap(w, c)
+	}
+}
+
+var heatShades = []rune{'·', '░', '▒', '▓', '█'}
+
+func printHeatmap(w io.Writer, c weeklyCategory) {
+	var max int64
+	for d := range c.Grid {
+		for _, sec := range c.Grid[d] {
+			if sec > max {
+				max = sec
+			}
+		}
+	}
+	var hdr strings.Builder
+	for h := 0; h < 24; h += 3 {
+		fmt.Fprintf(&hdr, "%-3d", h)
+	}
+	fmt.Fprintf(w, "      %s\n", strings.TrimRight(hdr.String(), " "))
+	for d := range c.Grid {
+		var row strings.Builder
+		for _, sec := range c.Grid[d] {
+			row.WriteRune(heatShade(sec, max))
+		}
+		fmt.Fprintf(w, "  %s  %s %10s\n", weekdayLabels[d], row.String(), fmtDur(c.ByWeekday[d]))
+	}
+}
+
+func heatShade(sec, max int64) rune {
+	if sec <= 0 || max <= 0 {
+		return heatShades[0]
+	}
+	// 1秒でもあれば一番薄い色以上にする
+	i := 1 + int(float64(sec)/float64(max)*float64(len(heatShades)-2)+0.5)
+	if i >= len(heatShades) {
+		i = len(heatShades) - 1
+	}
+	return heatShades[i]
+}
+
+func writeWeeklyJSON(w io.Writer, r weeklyReport) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
@@ -0,0 +1,34 @@
+package main
+
+/********** 前面ウィンドウの取得元（OSごと） **********/
+// メインループは前面のアプリ・タイトル・URLを WindowSource から受け取るだけにして、OS ごとの取り方は
+// ビルドタグで分けたファイルに置く。newWindowSource が実行中の OS のものを返す。
+//   macOS  darwinWindowSource（osascript。windowsource_darwin.go）
+//   Linux  x11WindowSource（xprop。windowsource_linux.go）
+//   Windows windowsWindowSource（Win32 API。windowsource_windows.go）
+// いずれでもない OS では newWindowSource がエラーを返す（-replay なら取得元なしで動く）。
+// PID やシークレットウィンドウの判定まで返せるものは frontWindowReader も満たす（readFrontWindow）。
+type WindowSource interface {
+	FrontmostAppAndTitle() (app, title, url string, err error)
+}
+
+// App / Title / URL に加えて PID や Private も返せる取得元
+type frontWindowReader interface {
+	FrontWindow() (frontWindow, error)
+}
+
+type frontWindow struct {
+	App     string
+	Title   string
+	PID     int    // 前面プロセスのPID（取れなければ0）
+	Private bool   // シークレットウィンドウ（Chromium系のみ判定できる）
+	URL     string // 表示中のページのURL（macOS の Safari・Chromium 系・Firefox。取れなければ空）
+}
+
+func readFrontWindow(src WindowSource) (frontWindow, error) {
+	if r, ok := src.(frontWindowReader); ok {
+		return r.FrontWindow()
+	}
+	app, title, url, err := src.FrontmostAppAndTitle()
+	return frontWindow{App: app, Title: title, URL: url}, err
+}
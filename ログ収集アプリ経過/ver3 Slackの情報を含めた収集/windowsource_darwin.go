@@ -0,0 +1,162 @@
+//go:build darwin
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/********** macOS: osascript で前面ウィンドウを取る **********/
+// System Events で前面アプリを、Safari / Chromium 系はタブのタイトルとURLまで取る。Firefox は firefox.go。
+// 補助アクセス（アクセシビリティ）と各ブラウザの Automation の許可が要る。
+type darwinWindowSource struct{}
+
+func newWindowSource() (WindowSource, error) {
+	if err := checkOSAAvailable(); err != nil {
+		return nil, err
+	}
+	return darwinWindowSource{}, nil
+}
+
+func (darwinWindowSource) FrontWindow() (frontWindow, error) {
+	return frontmostAppAndTitleWithBrowserTabs()
+}
+
+func (darwinWindowSource) FrontmostAppAndTitle() (app, title, url string, err error) {
+	w, err := frontmostAppAndTitleWithBrowserTabs()
+	return w.App, w.Title, w.URL, err
+}
+
+// 前面アプリ名・PID・タブ（またはウィンドウ）のタイトルを1回の osascript で取る。
+// 2回に分けると毎ポーリングのプロセス起動が倍になり、その間に前面アプリが変わると別のアプリのタイトルを拾ってしまう。
+// ブラウザの用語（current tab / active tab / mode）はそのブラウザの辞書がないとコンパイルできないので、
+// 前面アプリ名を埋め込んだスクリプトを run script で実行時にコンパイルする（入っていないブラウザがあっても動く）。
+// Chromium系はアプリ名に chrome / edge / brave / vivaldi / opera / arc を含むもの（contains は大文字小文字を区別しない）。
+// ウィンドウのタイトルは、キーボードフォーカスを持つウィンドウ（AXFocusedWindow）の AXTitle を使う。
+// "front window" は同じアプリの複数のウィンドウ（別のモニタや、スペースを切り替えた直後）で
+// 後ろのウィンドウを返すことがある。AXFocusedWindow が取れないアプリだけ従来どおり front window の名前を使う。
+// 出力は1行ずつ: アプリ名 / PID / 取得元（"tab" / "focused" / "window"）/ ウィンドウのモード / タイトル / URL
+const frontWindowScript = `
+	set n to ""
+	set pid to ""
+	set t to ""
+	tell application "System Events"
+		set p to first process whose frontmost is true
+		set n to name of p
+		set pid to (unix id of p) as text
+		set src to "window"
+		try
+			set fw to value of attribute "AXFocusedWindow" of p
+			if fw is not missing value then
+				set src to "focused"
+				set wn to value of attribute "AXTitle" of fw
+				if wn is not missing value then set t to wn
+			end if
+		end try
+		if src is "window" then
+			try
+				set wn to name of front window of p
+				if wn is not missing value then set t to wn
+			end try
+		end if
+	end tell
+	set m to ""
+	set u to ""
+	set tabScript to ""
+	if n is "Safari" then
+		set tabScript to "tell application \"Safari\"
+			if (count of windows) = 0 then return {\"\", \"\", \"\"}
+			set x to current tab of front window
+			set v to \"\"
+			try
+				set uu to URL of x
+				if uu is not missing value then set v to uu
+			end try
+			set tt to name of x
+			if tt is missing value then set tt to \"\"
+			return {\"normal\", tt, v}
+		end tell"
+	else if n contains "chrome" or n contains "edge" or n contains "brave" or n contains "vivaldi" or n contains "opera" or n contains "arc" then
+		set tabScript to "tell application \"" & n & "\"
+			if (count of windows) = 0 then return {\"\", \"\", \"\"}
+			set mm to \"normal\"
+			try
+				set mm to (mode of front window) as text
+			end try
+			set x to active tab of front window
+			set v to \"\"
+			try
+				set uu to URL of x
+				if uu is not missing value then set v to uu
+			end try
+			set tt to title of x
+			if tt is missing value then set tt to \"\"
+			return {mm, tt, v}
+		end tell"
+	end if
+	if tabScript is not "" then
+		try
+			set {m, t2, u} to (run script tabScript)
+			set t to t2
+			set src to "tab"
+		end try
+	end if
+	return n & linefeed & pid & linefeed & src & linefeed & m & linefeed & t & linefeed & u
+`
+
+func frontmostAppAndTitleWithBrowserTabs() (w frontWindow, err error) {
+	out, err := runOSA(frontWindowScript)
+	if err != nil {
+		return w, fmt.Errorf("get frontmost app failed: %w", err)
+	}
+	parts := strings.SplitN(strings.TrimRight(out, "\n"), "\n", 6)
+	for len(parts) < 6 {
+		parts = append(parts, "")
+	}
+	w.App = strings.TrimSpace(parts[0])
+	w.PID, _ = strconv.Atoi(strings.TrimSpace(parts[1]))
+	w.Title = strings.TrimSpace(parts[4])
+
+	// Safari / Chromium系：現在タブのタイトルとURL。Chromium系はシークレットウィンドウかどうかも分かる
+	if strings.TrimSpace(parts[2]) == "tab" {
+		w.Private = strings.TrimSpace(parts[3]) == "incognito"
+		if !w.Private {
+			w.URL = strings.TrimSpace(parts[5])
+		}
+		return w, nil
+	}
+
+	// Firefox：アドレスバーのURLとウィンドウタイトル（GUIスクリプティング。2回目の osascript）
+	if isFirefox(strings.ToLower(w.App)) {
+		if title, u, e := firefoxFrontTab(w.App); e == nil {
+			w.Title, w.URL = title, u
+			return w, nil
+		}
+	}
+	// フォーカスのあるウィンドウのタイトルが空なら空のまま（front window を見ると後ろのウィンドウを拾いうる）
+	if w.Title != "" || strings.TrimSpace(parts[2]) == "focused" {
+		return w, nil
+	}
+
+	// AXFocusedWindow もウィンドウ名も取れないときだけ、AXTitle も見る従来の取り方でもう一度
+	titleScript := fmt.Sprintf(`
+		tell application "System Events"
+			tell process "%s"
+				try
+					return name of front window
+				on error
+					try
+						return value of attribute "AXTitle" of front window
+					on error
+						return ""
+					end try
+				end try
+			end tell
+		end tell
+	`, escapeOSA(w.App))
+	title, _ := runOSA(titleScript)
+	w.Title = strings.TrimSpace(title)
+	return w, nil
+}
@@ -0,0 +1,121 @@
+//go:build linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+/********** Linux: X11 の前面ウィンドウ（xprop） **********/
+// ルートウィンドウの _NET_ACTIVE_WINDOW から前面ウィンドウを引き、その WM_CLASS（クラス名をアプリ名にする）、
+// _NET_WM_NAME（なければ WM_NAME）、_NET_WM_PID を読む。EWMH 対応のウィンドウマネージャが必要。
+// Wayland のネイティブなウィンドウは X11 から見えないので取れない（XWayland のアプリだけ取れる）。
+// ブラウザのURLは取れないので空（分類はタイトルで行う）。xprop は x11-utils などのパッケージに入っている。
+type x11WindowSource struct{}
+
+func newWindowSource() (WindowSource, error) {
+	if os.Getenv("DISPLAY") == "" {
+		return nil, errors.New("DISPLAY is not set: the Linux backend reads the active window from X11 (use -replay to run without it)")
+	}
+	if _, err := exec.LookPath("xprop"); err != nil {
+		return nil, errors.New("xprop not found on PATH: install x11-utils (or xorg-xprop) to read the active window")
+	}
+	return x11WindowSource{}, nil
+}
+
+func (x11WindowSource) FrontmostAppAndTitle() (app, title, url string, err error) {
+	w, err := x11WindowSource{}.FrontWindow()
+	return w.App, w.Title, w.URL, err
+}
+
+func (x11WindowSource) FrontWindow() (w frontWindow, err error) {
+	out, err := runCmd("xprop", "-root", "-notype", "_NET_ACTIVE_WINDOW")
+	if err != nil {
+		return w, fmt.Errorf("get active window failed: %w", err)
+	}
+	id := x11ActiveWindowID(out)
+	if id == "" {
+		return w, nil // デスクトップなど、前面のウィンドウがない
+	}
+	out, err = runCmd("xprop", "-id", id, "-notype", "WM_CLASS", "_NET_WM_NAME", "WM_NAME", "_NET_WM_PID")
+	if err != nil {
+		return w, fmt.Errorf("read window %s failed: %w", id, err)
+	}
+	props := parseXprop(out)
+	if class := props["WM_CLASS"]; len(class) > 0 {
+		w.App = class[len(class)-1] // "instance", "Class" の Class
+	}
+	if name := props["_NET_WM_NAME"]; len(name) > 0 {
+		w.Title = name[0]
+	} else if name := props["WM_NAME"]; len(name) > 0 {
+		w.Title = name[0]
+	}
+	if pid := props["_NET_WM_PID"]; len(pid) > 0 {
+		w.PID, _ = strconv.Atoi(pid[0])
+	}
+	return w, nil
+}
+
+// "_NET_ACTIVE_WINDOW: window id # 0x3a00007" → "0x3a00007"（0x0 や取れないときは空）
+func x11ActiveWindowID(out string) string {
+	_, id, ok := strings.Cut(out, "#")
+	if !ok {
+		return ""
+	}
+	id = strings.TrimSpace(strings.Split(strings.TrimSpace(id), ",")[0])
+	if !strings.HasPrefix(id, "0x") || strings.Trim(id[2:], "0") == "" {
+		return ""
+	}
+	return id
+}
+
+// xprop -notype の出力（`WM_CLASS = "code", "Code"` や `_NET_WM_PID = 12345` の行）を
+// プロパティ名 → 値の並び にする。文字列は引用符とエスケープを外す。
+// 見つからないプロパティ（`WM_NAME:  not found.`）は入れない
+func parseXprop(out string) map[string][]string {
+	props := map[string][]string{}
+	for _, line := range strings.Split(out, "\n") {
+		name, val, ok := strings.Cut(line, " = ")
+		if !ok {
+			continue
+		}
+		props[strings.TrimSpace(name)] = splitXpropValues(strings.TrimSpace(val))
+	}
+	return props
+}
+
+func splitXpropValues(s string) []string {
+	var vals []string
+	for s != "" {
+		if s[0] == '"' {
+			// 閉じ引用符（エスケープされていないもの）まで
+			end := 1
+			for end < len(s) && s[end] != '"' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) {
+				end = len(s) - 1
+			}
+			v, err := strconv.Unquote(s[:end+1])
+			if err != nil {
+				v = strings.Trim(s[:end+1], `"`)
+			}
+			vals = append(vals, v)
+			s = s[end+1:]
+		} else {
+			v, rest, _ := strings.Cut(s, ",")
+			vals = append(vals, strings.TrimSpace(v))
+			s = rest
+		}
+		s = strings.TrimLeft(s, ", ")
+	}
+	return vals
+}
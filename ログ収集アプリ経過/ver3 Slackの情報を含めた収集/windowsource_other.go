@@ -0,0 +1,13 @@
+//go:build !darwin && !linux && !windows
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// 前面ウィンドウの取り方がない OS（-replay だけ使える）
+func newWindowSource() (WindowSource, error) {
+	return nil, fmt.Errorf("reading the frontmost window is not supported on %s (use -replay to run without it)", runtime.GOOS)
+}
@@ -0,0 +1,122 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+/********** Windows: Win32 API で前面ウィンドウを取る **********/
+// GetForegroundWindow で前面ウィンドウを、GetWindowThreadProcessId でそのプロセスを引き、
+// 実行ファイル名（QueryFullProcessImageName）をアプリ名に、GetWindowTextW をタイトルにする。
+// ブラウザのタブはウィンドウタイトル（"ページ名 - Google Chrome" など）で分類する。URL は取れないので空。
+// 管理者権限で動いているプロセスなど、開けないプロセスはアプリ名が空になる（そのポーリングは記録しない）。
+type windowsWindowSource struct{}
+
+var (
+	user32                   = windows.NewLazySystemDLL("user32.dll")
+	procGetWindowTextW       = user32.NewProc("GetWindowTextW")
+	procGetWindowTextLengthW = user32.NewProc("GetWindowTextLengthW")
+)
+
+// 実行ファイル名 → 分類に使うアプリ名（macOS と同じ名前にそろえ、builtinRules やルールファイルがそのまま当たるようにする）。
+// 表にないものは ".exe" を外した名前をそのまま使う（"Notepad.exe" → "Notepad"）
+var windowsAppNames = map[string]string{
+	"chrome.exe":          "Google Chrome",
+	"msedge.exe":          "Microsoft Edge",
+	"firefox.exe":         "Firefox",
+	"brave.exe":           "Brave Browser",
+	"opera.exe":           "Opera",
+	"vivaldi.exe":         "Vivaldi",
+	"arc.exe":             "Arc",
+	"code.exe":            "Visual Studio Code",
+	"idea64.exe":          "IntelliJ IDEA",
+	"goland64.exe":        "GoLand",
+	"winword.exe":         "Microsoft Word",
+	"excel.exe":           "Microsoft Excel",
+	"powerpnt.exe":        "Microsoft PowerPoint",
+	"outlook.exe":         "Microsoft Outlook",
+	"olk.exe":             "Microsoft Outlook",
+	"ms-teams.exe":        "Microsoft Teams",
+	"teams.exe":           "Microsoft Teams",
+	"zoom.exe":            "zoom.us",
+	"slack.exe":           "Slack",
+	"discord.exe":         "Discord",
+	"figma.exe":           "Figma",
+	"notion.exe":          "Notion",
+	"obsidian.exe":        "Obsidian",
+	"acrobat.exe":         "Adobe Acrobat",
+	"acrord32.exe":        "Adobe Acrobat Reader",
+	"explorer.exe":        "File Explorer",
+	"windowsterminal.exe": "Windows Terminal",
+	"spotify.exe":         "Spotify",
+}
+
+func newWindowSource() (WindowSource, error) {
+	if err := procGetWindowTextW.Find(); err != nil {
+		return nil, fmt.Errorf("user32.dll GetWindowTextW not available: %w", err)
+	}
+	return windowsWindowSource{}, nil
+}
+
+func (windowsWindowSource) FrontmostAppAndTitle() (app, title, url string, err error) {
+	w, err := windowsWindowSource{}.FrontWindow()
+	return w.App, w.Title, w.URL, err
+}
+
+func (windowsWindowSource) FrontWindow() (w frontWindow, err error) {
+	hwnd := windows.GetForegroundWindow()
+	if hwnd == 0 {
+		return w, nil // ロック画面や切り替えの途中など、前面のウィンドウがない
+	}
+	var pid uint32
+	if _, err := windows.GetWindowThreadProcessId(hwnd, &pid); err != nil {
+		return w, fmt.Errorf("GetWindowThreadProcessId failed: %w", err)
+	}
+	w.PID = int(pid)
+	w.Title = windowText(hwnd)
+	if exe, err := processImageName(pid); err == nil {
+		w.App = windowsAppName(exe)
+	}
+	return w, nil
+}
+
+// GetWindowTextW。取れなければ空
+func windowText(hwnd windows.HWND) string {
+	n, _, _ := procGetWindowTextLengthW.Call(uintptr(hwnd))
+	if n == 0 {
+		return ""
+	}
+	buf := make([]uint16, n+1)
+	got, _, _ := procGetWindowTextW.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+	return windows.UTF16ToString(buf[:got])
+}
+
+// プロセスの実行ファイルのフルパス
+func processImageName(pid uint32) (string, error) {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return "", err
+	}
+	defer windows.CloseHandle(h)
+	buf := make([]uint16, windows.MAX_LONG_PATH)
+	size := uint32(len(buf))
+	if err := windows.QueryFullProcessImageName(h, 0, &buf[0], &size); err != nil {
+		return "", err
+	}
+	return windows.UTF16ToString(buf[:size]), nil
+}
+
+// "C:\...\chrome.exe" → "Google Chrome"
+func windowsAppName(exePath string) string {
+	base := strings.ToLower(filepath.Base(exePath))
+	if name, ok := windowsAppNames[base]; ok {
+		return name
+	}
+	return strings.TrimSuffix(filepath.Base(exePath), filepath.Ext(exePath))
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/********** Zoom の会議名・参加者数 **********/
+// Zoom（zoom.us）の会議ウィンドウが前面のときは「会議・通話」とし、会議名を meta.meetingTopic に、
+// 取れれば参加者数を meta.participants に入れる。report で会議ごとの時間を出すためのもの。
+// 会議名は zoom.us のウィンドウ名から取る（"Zoom" / "Zoom Meeting" のような汎用の名前は除く）。
+// 待機室・会議外・取得できない場合は会議名なしで記録する。
+const meetingActivity = "会議・通話"
+
+const zoomInfoTTL = 30 * time.Second // 毎ティック osascript を呼ばないようにキャッシュする
+
+var (
+	zoomParticipantsRe = regexp.MustCompile(`^(?:Participants|参加者)\s*[（(]\s*(\d+)\s*[)）]`)
+
+	// 会議ウィンドウのタイトル（会議中と判定する）
+	zoomMeetingTitles = []string{"zoom meeting", "zoom ミーティング", "zoom webinar", "zoom ウェビナー"}
+
+	// 会議名ではないウィンドウ名
+	zoomGenericWindows = []string{
+		"zoom", "zoom.us", "zoom workplace", "zoom meeting", "zoom ミーティング", "zoom webinar", "zoom ウェビナー",
+		"zoom cloud meetings", "settings", "設定", "chat", "チャット", "participants", "参加者",
+		"share screen", "画面の共有", "floating video panel", "meeting controls",
+	}
+
+	// 待機室・開始待ち（会議名は記録しない）
+	zoomWaitingHints = []string{"waiting room", "待機室", "wait for the host", "ホストがこのミーティングを開始するまで"}
+)
+
+func isZoomApp(app string) bool {
+	a := strings.ToLower(app)
+	return a == "zoom.us" || a == "zoom" || strings.HasPrefix(a, "zoom ")
+}
+
+// 前面のタイトルから会議中かどうか（a, t は小文字）
+func isZoomMeeting(a, t string) bool {
+	return isZoomApp(a) && hasAny(t, zoomMeetingTitles)
+}
+
+type zoomInfo struct {
+	topic        string
+	participants int
+}
+
+var zoomCache struct {
+	mu   sync.Mutex
+	at   time.Time
+	info zoomInfo
+}
+
+// zoom.us の全ウィンドウ名から会議名と参加者数を読む（zoomInfoTTL の間は前回の結果を返す）
+func zoomMeetingInfo(now time.Time) zoomInfo {
+	zoomCache.mu.Lock()
+	defer zoomCache.mu.Unlock()
+	if !zoomCache.at.IsZero() && now.Sub(zoomCache.at) < zoomInfoTTL {
+		return zoomCache.info
+	}
+	out, err := runOSA(`
+		tell application "System Events"
+			if not (exists process "zoom.us") then return ""
+			set AppleScript's text item delimiters to linefeed
+			return (name of every window of process "zoom.us") as text
+		end tell
+	`)
+	info := zoomInfo{}
+	if err == nil {
+		info = parseZoomWindows(strings.Split(out, "\n"))
+	}
+	zoomCache.at = now
+	zoomCache.info = info
+	return info
+}
+
+func parseZoomWindows(names []string) zoomInfo {
+	var info zoomInfo
+	for _, n := range names {
+		n = strings.TrimSpace(n)
+		low := strings.ToLower(n)
+		if n == "" || low == "missing value" {
+			continue
+		}
+		if hasAny(low, zoomWaitingHints) {
+			return zoomInfo{} // 待機室: まだ会議に入っていない
+		}
+		if m := zoomParticipantsRe.FindStringSubmatch(n); m != nil {
+			info.participants, _ = strconv.Atoi(m[1])
+			continue
+		}
+		if info.topic == "" && !isGenericZoomWindow(low) {
+			info.topic = clean(n)
+		}
+	}
+	return info
+}
+
+func isGenericZoomWindow(low string) bool {
+	for _, g := range zoomGenericWindows {
+		if low == g {
+			return true
+		}
+	}
+	return false
+}
+
+// 同じセッション中の観測を反映する（会議名は最初に取れたもの、参加者数は最大値）
+func (r *record) noteMeeting(info zoomInfo) {
+	if r.Meeting == "" {
+		r.Meeting = info.topic
+	}
+	if info.participants > r.Attendees {
+		r.Attendees = info.participants
+	}
+}